@@ -68,8 +68,11 @@ type ContainerServer interface {
 	DeleteContainer(name string) (op *Operation, err error)
 
 	ExecContainer(containerName string, exec api.ContainerExecPost, args *ContainerExecArgs) (*Operation, error)
+	ConsoleContainer(containerName string, console api.ContainerConsolePost, args *ContainerConsoleArgs) (*Operation, error)
+	GetContainerConsoleLog(containerName string) (content io.ReadCloser, err error)
 
 	GetContainerFile(containerName string, path string) (content io.ReadCloser, resp *ContainerFileResponse, err error)
+	GetContainerFileNoFollow(containerName string, path string) (content io.ReadCloser, resp *ContainerFileResponse, err error)
 	CreateContainerFile(containerName string, path string, args ContainerFileArgs) (err error)
 	DeleteContainerFile(containerName string, path string) (err error)
 
@@ -80,8 +83,18 @@ type ContainerServer interface {
 	CopyContainerSnapshot(source ContainerServer, snapshot api.ContainerSnapshot, args *ContainerSnapshotCopyArgs) (op *RemoteOperation, err error)
 	RenameContainerSnapshot(containerName string, name string, container api.ContainerSnapshotPost) (op *Operation, err error)
 	MigrateContainerSnapshot(containerName string, name string, container api.ContainerSnapshotPost) (op *Operation, err error)
+	UpdateContainerSnapshot(containerName string, name string, snapshot api.ContainerSnapshotPut, ETag string) (op *Operation, err error)
 	DeleteContainerSnapshot(containerName string, name string) (op *Operation, err error)
 
+	GetContainerBackupNames(containerName string) (names []string, err error)
+	GetContainerBackups(containerName string) (backups []api.ContainerBackup, err error)
+	GetContainerBackup(containerName string, name string) (backup *api.ContainerBackup, ETag string, err error)
+	CreateContainerBackup(containerName string, backup api.ContainerBackupsPost) (op *Operation, err error)
+	RenameContainerBackup(containerName string, name string, backup api.ContainerBackupPost) (op *Operation, err error)
+	DeleteContainerBackup(containerName string, name string) (op *Operation, err error)
+	GetContainerBackupFile(containerName string, name string, req *BackupFileRequest) (resp *BackupFileResponse, err error)
+	CreateContainerFromBackup(args ContainerBackupArgs) (op *Operation, err error)
+
 	GetContainerState(name string) (state *api.ContainerState, ETag string, err error)
 	UpdateContainerState(name string, state api.ContainerStatePut, ETag string) (op *Operation, err error)
 
@@ -179,6 +192,9 @@ type ProgressData struct {
 
 	// Total number of bytes (for files)
 	TotalBytes int64
+
+	// Transfer speed in bytes per second (for files)
+	Speed int64
 }
 
 // The ImageCreateArgs struct is used for direct image upload
@@ -259,6 +275,10 @@ type ContainerCopyArgs struct {
 	// If set, only the container will copied, its snapshots won't
 	ContainerOnly bool
 
+	// If set, an existing target container is updated with the snapshots
+	// it's missing and the current filesystem of the source
+	Refresh bool
+
 	// The transfer mode, can be "pull" (default), "push" or "relay"
 	Mode string
 }
@@ -276,6 +296,30 @@ type ContainerSnapshotCopyArgs struct {
 	Live bool
 }
 
+// The ContainerBackupArgs struct is used when creating a container from a backup
+type ContainerBackupArgs struct {
+	// The backup file
+	BackupFile io.Reader
+}
+
+// The BackupFileRequest struct is used for a backup download request
+type BackupFileRequest struct {
+	// Writer for the backup file
+	BackupFile io.WriteSeeker
+
+	// Progress handler (called whenever some progress is made)
+	ProgressHandler func(progress ProgressData)
+
+	// A canceler that can be used to interrupt some part of the backup download request
+	Canceler *cancel.Canceler
+}
+
+// The BackupFileResponse struct is used as the response for backup downloads
+type BackupFileResponse struct {
+	// Size of backup file
+	Size int64
+}
+
 // The ContainerExecArgs struct is used to pass additional options during container exec
 type ContainerExecArgs struct {
 	// Standard input
@@ -294,6 +338,18 @@ type ContainerExecArgs struct {
 	DataDone chan bool
 }
 
+// The ContainerConsoleArgs struct is used to pass additional options during a container console session
+type ContainerConsoleArgs struct {
+	// Bidirectional fd to pass to the container
+	Terminal io.ReadWriteCloser
+
+	// Control message handler (window resize)
+	Control func(conn *websocket.Conn)
+
+	// Closing this channel detaches from the container's console
+	ConsoleDisconnect chan bool
+}
+
 // The ContainerFileArgs struct is used to pass the various options for a container file upload
 type ContainerFileArgs struct {
 	// File content
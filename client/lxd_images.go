@@ -122,8 +122,10 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
-	defer close(doneCh)
+
+	// Split images are sent as a multipart response, which can't be resumed
+	resumable := newResumableBody(r.http, req.Canceler, request, response, doneCh)
+	defer resumable.Close()
 
 	if response.StatusCode != http.StatusOK {
 		_, _, err := r.parseResponse(response)
@@ -138,14 +140,14 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 	}
 
 	// Handle the data
-	body := response.Body
+	var body io.ReadCloser = resumable
 	if req.ProgressHandler != nil {
 		body = &ioprogress.ProgressReader{
-			ReadCloser: response.Body,
+			ReadCloser: resumable,
 			Tracker: &ioprogress.ProgressTracker{
 				Length: response.ContentLength,
 				Handler: func(percent int64, speed int64) {
-					req.ProgressHandler(ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, shared.GetByteSizeString(speed, 2))})
+					req.ProgressHandler(downloadProgressData("", percent, speed, resumable.offset, response.ContentLength))
 				},
 			},
 		}
@@ -1,6 +1,7 @@
 package lxd
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,6 +11,8 @@ import (
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/simplestreams"
 )
 
 // Image handling functions
@@ -93,6 +96,69 @@ func (r *ProtocolSimpleStreams) GetImageFile(fingerprint string, req ImageFileRe
 		resp.MetaSize = size
 	}
 
+	// Apply a delta on top of a local rootfs, only writing to the target
+	// once the result has been validated.
+	applyDelta := func(srcPath string, delta simplestreams.SimpleStreamsFile, sha256sum string, target io.WriteSeeker) (int64, error) {
+		// Create temporary file for the delta
+		deltaFile, err := ioutil.TempFile("", "lxc_image_")
+		if err != nil {
+			return -1, err
+		}
+		defer deltaFile.Close()
+		defer os.Remove(deltaFile.Name())
+
+		// Download the delta
+		_, err = download(delta.Path, "rootfs delta", delta.Sha256, deltaFile)
+		if err != nil {
+			return -1, err
+		}
+
+		// Create temporary file for the patched rootfs
+		patchedFile, err := ioutil.TempFile("", "lxc_image_")
+		if err != nil {
+			return -1, err
+		}
+		patchedFile.Close()
+		defer os.Remove(patchedFile.Name())
+
+		// Apply it
+		_, err = shared.RunCommand("xdelta3", "-f", "-d", "-s", srcPath, deltaFile.Name(), patchedFile.Name())
+		if err != nil {
+			return -1, err
+		}
+
+		// Validate the result
+		patched, err := os.Open(patchedFile.Name())
+		if err != nil {
+			return -1, err
+		}
+		defer patched.Close()
+
+		hash := sha256.New()
+		_, err = io.Copy(hash, patched)
+		if err != nil {
+			return -1, err
+		}
+
+		result := fmt.Sprintf("%x", hash.Sum(nil))
+		if result != sha256sum {
+			return -1, fmt.Errorf("Hash mismatch for the patched rootfs: %s != %s", result, sha256sum)
+		}
+
+		// Copy to the target
+		_, err = patched.Seek(0, 0)
+		if err != nil {
+			return -1, err
+		}
+
+		_, err = target.Seek(0, 0)
+		if err != nil {
+			return -1, err
+		}
+
+		return io.Copy(target, patched)
+	}
+
 	// Download the rootfs
 	rootfs, ok := files["root"]
 	if ok && req.RootfsFile != nil {
@@ -112,44 +178,18 @@ func (r *ProtocolSimpleStreams) GetImageFile(fingerprint string, req ImageFileRe
 					continue
 				}
 
-				// Create temporary file for the delta
-				deltaFile, err := ioutil.TempFile("", "lxc_image_")
-				if err != nil {
-					return nil, err
-				}
-				defer deltaFile.Close()
-				defer os.Remove(deltaFile.Name())
-
-				// Download the delta
-				_, err = download(file.Path, "rootfs delta", file.Sha256, deltaFile)
+				size, err := applyDelta(srcPath, file, rootfs.Sha256, req.RootfsFile)
 				if err != nil {
-					return nil, err
-				}
-
-				// Create temporary file for the delta
-				patchedFile, err := ioutil.TempFile("", "lxc_image_")
-				if err != nil {
-					return nil, err
-				}
-				defer patchedFile.Close()
-				defer os.Remove(patchedFile.Name())
-
-				// Apply it
-				_, err = shared.RunCommand("xdelta3", "-f", "-d", "-s", srcPath, deltaFile.Name(), patchedFile.Name())
-				if err != nil {
-					return nil, err
-				}
-
-				// Copy to the target
-				size, err := io.Copy(req.RootfsFile, patchedFile)
-				if err != nil {
-					return nil, err
+					// Try the next delta or fallback to the whole file
+					logger.Debugf("Failed to apply the delta from %s: %v", srcFingerprint, err)
+					continue
 				}
 
 				parts := strings.Split(rootfs.Path, "/")
 				resp.RootfsName = parts[len(parts)-1]
 				resp.RootfsSize = size
 				downloaded = true
+				break
 			}
 		}
 
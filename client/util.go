@@ -7,6 +7,8 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/cancel"
@@ -103,31 +105,30 @@ func downloadFileSha256(httpClient *http.Client, useragent string, progress func
 	if err != nil {
 		return -1, err
 	}
-	defer r.Body.Close()
-	defer close(doneCh)
+
+	resumable := newResumableBody(httpClient, canceler, req, r, doneCh)
+	defer resumable.Close()
 
 	if r.StatusCode != http.StatusOK {
 		return -1, fmt.Errorf("Unable to fetch %s: %s", url, r.Status)
 	}
 
 	// Handle the data
-	body := r.Body
+	var body io.ReadCloser = resumable
 	if progress != nil {
 		body = &ioprogress.ProgressReader{
-			ReadCloser: r.Body,
+			ReadCloser: resumable,
 			Tracker: &ioprogress.ProgressTracker{
 				Length: r.ContentLength,
 				Handler: func(percent int64, speed int64) {
-					if filename != "" {
-						progress(ProgressData{Text: fmt.Sprintf("%s: %d%% (%s/s)", filename, percent, shared.GetByteSizeString(speed, 2))})
-					} else {
-						progress(ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, shared.GetByteSizeString(speed, 2))})
-					}
+					progress(downloadProgressData(filename, percent, speed, resumable.offset, r.ContentLength))
 				},
 			},
 		}
 	}
 
+	// The hash covers the whole file, including the parts written before any
+	// interruption of the transfer.
 	sha256 := sha256.New()
 	size, err := io.Copy(io.MultiWriter(target, sha256), body)
 	if err != nil {
@@ -142,6 +143,136 @@ func downloadFileSha256(httpClient *http.Client, useragent string, progress func
 	return size, nil
 }
 
+// downloadProgressData builds the progress information for a file download.
+func downloadProgressData(filename string, percent int64, speed int64, transferred int64, total int64) ProgressData {
+	text := fmt.Sprintf("%d%% (%s/s)", percent, shared.GetByteSizeString(speed, 2))
+	if filename != "" {
+		text = fmt.Sprintf("%s: %s", filename, text)
+	}
+
+	data := ProgressData{
+		Text:             text,
+		TransferredBytes: transferred,
+		TotalBytes:       total,
+		Speed:            speed,
+	}
+
+	if total > 0 {
+		data.Percentage = int(percent)
+	}
+
+	return data
+}
+
+// The number of times an interrupted download is resumed before giving up
+const downloadResumeAttempts = 3
+
+// resumableBody wraps the body of a download and transparently resumes the
+// transfer with a range request should the connection be interrupted.
+type resumableBody struct {
+	httpClient *http.Client
+	canceler   *cancel.Canceler
+
+	req    *http.Request
+	resp   *http.Response
+	doneCh chan bool
+
+	// Number of bytes read so far
+	offset int64
+}
+
+func newResumableBody(httpClient *http.Client, canceler *cancel.Canceler, req *http.Request, resp *http.Response, doneCh chan bool) *resumableBody {
+	return &resumableBody{
+		httpClient: httpClient,
+		canceler:   canceler,
+		req:        req,
+		resp:       resp,
+		doneCh:     doneCh,
+	}
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	n, err := b.resp.Body.Read(p)
+	b.offset += int64(n)
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+
+	for i := 0; i < downloadResumeAttempts; i++ {
+		retry, resumeErr := b.resume()
+		if resumeErr == nil {
+			return n, nil
+		}
+
+		if !retry {
+			break
+		}
+
+		time.Sleep(time.Duration(i+1) * time.Second)
+	}
+
+	return n, err
+}
+
+func (b *resumableBody) Close() error {
+	close(b.doneCh)
+	return b.resp.Body.Close()
+}
+
+// resume requests the rest of the file, starting at the current offset. It
+// returns whether it's worth trying again on failure.
+func (b *resumableBody) resume() (bool, error) {
+	// Don't resume canceled downloads
+	select {
+	case <-b.req.Cancel:
+		return false, fmt.Errorf("The download was canceled")
+	default:
+	}
+
+	if b.resp.Header.Get("Accept-Ranges") != "bytes" {
+		return false, fmt.Errorf("The server doesn't support resuming downloads")
+	}
+
+	req, err := http.NewRequest("GET", b.req.URL.String(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	for key, values := range b.req.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+
+	// Only get the rest of the file if it didn't change in the meantime
+	if b.resp.Header.Get("ETag") != "" {
+		req.Header.Set("If-Range", b.resp.Header.Get("ETag"))
+	} else if b.resp.Header.Get("Last-Modified") != "" {
+		req.Header.Set("If-Range", b.resp.Header.Get("Last-Modified"))
+	}
+
+	resp, doneCh, err := cancel.CancelableDownload(b.canceler, b.httpClient, req)
+	if err != nil {
+		close(doneCh)
+		return true, err
+	}
+
+	if resp.StatusCode != http.StatusPartialContent || !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", b.offset)) {
+		resp.Body.Close()
+		close(doneCh)
+		return false, fmt.Errorf("Unable to resume the download: %s", resp.Status)
+	}
+
+	// Switch over to the new response
+	b.resp.Body.Close()
+	close(b.doneCh)
+
+	b.req = req
+	b.resp = resp
+	b.doneCh = doneCh
+
+	return true, nil
+}
+
 type nullReadWriteCloser int
 
 func (nullReadWriteCloser) Close() error                { return nil }
@@ -0,0 +1,98 @@
+package lxd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Serve the given data, cutting the connection halfway through the first
+// response. Subsequent requests are served normally, honoring ranges if
+// resumable is set.
+func newInterruptedServer(t *testing.T, data []byte, resumable bool) (*httptest.Server, *[]string) {
+	ranges := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+
+		if resumable {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("ETag", `"image"`)
+		}
+
+		if len(ranges) > 1 {
+			if !resumable {
+				w.Write(data)
+				return
+			}
+
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+			return
+		}
+
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(data[:len(data)/2])
+		w.(http.Flusher).Flush()
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		conn.Close()
+	}))
+
+	return server, &ranges
+}
+
+// An interrupted download is resumed with a range request and the resulting
+// file matches the original one.
+func TestDownloadFileSha256Resume(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	hash := fmt.Sprintf("%x", sha256.Sum256(data))
+
+	server, ranges := newInterruptedServer(t, data, true)
+	defer server.Close()
+
+	target, err := ioutil.TempFile("", "lxd_client_test_")
+	require.NoError(t, err)
+	defer os.Remove(target.Name())
+	defer target.Close()
+
+	size, err := downloadFileSha256(&http.Client{}, "", nil, nil, "", server.URL, hash, target)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), size)
+
+	require.Len(t, *ranges, 2)
+	assert.Equal(t, "", (*ranges)[0])
+	assert.Equal(t, fmt.Sprintf("bytes=%d-", len(data)/2), (*ranges)[1])
+
+	content, err := ioutil.ReadFile(target.Name())
+	require.NoError(t, err)
+	assert.Equal(t, hash, fmt.Sprintf("%x", sha256.Sum256(content)))
+}
+
+// Downloads from servers which don't support ranges fail when interrupted.
+func TestDownloadFileSha256NoResume(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	hash := fmt.Sprintf("%x", sha256.Sum256(data))
+
+	server, ranges := newInterruptedServer(t, data, false)
+	defer server.Close()
+
+	target, err := ioutil.TempFile("", "lxd_client_test_")
+	require.NoError(t, err)
+	defer os.Remove(target.Name())
+	defer target.Close()
+
+	_, err = downloadFileSha256(&http.Client{}, "", nil, nil, "", server.URL, hash, target)
+	assert.Error(t, err)
+	assert.Len(t, *ranges, 1)
+}
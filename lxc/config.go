@@ -24,7 +24,10 @@ import (
 )
 
 type configCmd struct {
-	expanded bool
+	expanded   bool
+	role       string
+	containers string
+	profiles   string
 }
 
 func (c *configCmd) showByDefault() bool {
@@ -33,6 +36,9 @@ func (c *configCmd) showByDefault() bool {
 
 func (c *configCmd) flags() {
 	gnuflag.BoolVar(&c.expanded, "expanded", false, i18n.G("Show the expanded configuration"))
+	gnuflag.StringVar(&c.role, "role", "", i18n.G("Role of the trusted certificate (admin, operator or read-only)"))
+	gnuflag.StringVar(&c.containers, "containers", "", i18n.G("Comma separated list of containers the trusted certificate is restricted to"))
+	gnuflag.StringVar(&c.profiles, "profiles", "", i18n.G("Comma separated list of profiles the trusted certificate is restricted to"))
 }
 
 func (c *configCmd) configEditHelp() string {
@@ -107,8 +113,8 @@ lxc config device remove [<remote>:]<container> <name>...
 lxc config trust list [<remote>:]
     List all trusted certs.
 
-lxc config trust add [<remote>:] <certfile.crt>
-    Add certfile.crt to trusted hosts.
+lxc config trust add [<remote>:] <certfile.crt> [--role=admin|operator|read-only] [--containers=<list>] [--profiles=<list>]
+    Add certfile.crt to trusted hosts, optionally restricting what it may do.
 
 lxc config trust remove [<remote>:] [hostname|fingerprint]
     Remove the cert from trusted hosts.
@@ -128,7 +134,10 @@ lxc config set core.https_address [::]:8443
     Will have LXD listen on IPv4 and IPv6 port 8443.
 
 lxc config set core.trust_password blah
-    Will set the server's trust password to blah.`)
+    Will set the server's trust password to blah.
+
+lxc config trust add ci.crt --role=operator --containers=ci1,ci2
+    Will let the holder of ci.crt run commands in and manage the state of ci1 and ci2 only.`)
 }
 
 func (c *configCmd) doSet(conf *config.Config, args []string, unset bool) error {
@@ -321,10 +330,10 @@ func (c *configCmd) run(conf *config.Config, args []string) error {
 			}
 
 			data := [][]string{}
-			for _, cert := range trust {
-				fp := cert.Fingerprint[0:12]
+			for _, trusted := range trust {
+				fp := trusted.Fingerprint[0:12]
 
-				certBlock, _ := pem.Decode([]byte(cert.Certificate))
+				certBlock, _ := pem.Decode([]byte(trusted.Certificate))
 				if certBlock == nil {
 					return fmt.Errorf(i18n.G("Invalid certificate"))
 				}
@@ -337,7 +346,12 @@ func (c *configCmd) run(conf *config.Config, args []string) error {
 				const layout = "Jan 2, 2006 at 3:04pm (MST)"
 				issue := cert.NotBefore.Format(layout)
 				expiry := cert.NotAfter.Format(layout)
-				data = append(data, []string{fp, cert.Subject.CommonName, issue, expiry})
+				role := trusted.Role
+				if role == "" {
+					role = "admin"
+				}
+
+				data = append(data, []string{fp, cert.Subject.CommonName, role, issue, expiry})
 			}
 
 			table := tablewriter.NewWriter(os.Stdout)
@@ -347,6 +361,7 @@ func (c *configCmd) run(conf *config.Config, args []string) error {
 			table.SetHeader([]string{
 				i18n.G("FINGERPRINT"),
 				i18n.G("COMMON NAME"),
+				i18n.G("ROLE"),
 				i18n.G("ISSUE DATE"),
 				i18n.G("EXPIRY DATE")})
 			sort.Sort(SortImage(data))
@@ -384,6 +399,21 @@ func (c *configCmd) run(conf *config.Config, args []string) error {
 			cert.Certificate = base64.StdEncoding.EncodeToString(x509Cert.Raw)
 			cert.Name = name
 			cert.Type = "client"
+			cert.Role = c.role
+
+			if c.containers != "" {
+				cert.Containers = strings.Split(c.containers, ",")
+			}
+
+			if c.profiles != "" {
+				cert.Profiles = strings.Split(c.profiles, ",")
+			}
+
+			if cert.Role != "" || cert.Containers != nil || cert.Profiles != nil {
+				if !d.HasExtension("certificate_roles") {
+					return fmt.Errorf(i18n.G("The server doesn't support restricted certificates"))
+				}
+			}
 
 			return d.CreateCertificate(cert)
 		case "remove":
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/config"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/gnuflag"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type consoleCmd struct {
+	showLog bool
+}
+
+func (c *consoleCmd) showByDefault() bool {
+	return true
+}
+
+func (c *consoleCmd) usage() string {
+	return i18n.G(
+		`Usage: lxc console [<remote>:]<container> [--show-log]
+
+Attach to container consoles.
+
+This command allows you to interact with the boot console of a container
+as well as retrieve past log entries from it.`)
+}
+
+func (c *consoleCmd) flags() {
+	gnuflag.BoolVar(&c.showLog, "show-log", false, i18n.G("Retrieve the container's console log"))
+}
+
+func (c *consoleCmd) sendTermSize(control *websocket.Conn) error {
+	width, height, err := termios.GetSize(int(syscall.Stdout))
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("Window size is now: %dx%d", width, height)
+
+	w, err := control.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+
+	msg := api.ContainerConsoleControl{}
+	msg.Command = "window-resize"
+	msg.Args = make(map[string]string)
+	msg.Args["width"] = strconv.Itoa(width)
+	msg.Args["height"] = strconv.Itoa(height)
+
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+
+	w.Close()
+	return err
+}
+
+// consoleTerminal is the local terminal attached to the console, it detaches
+// when <ctrl>+a q is typed.
+type consoleTerminal struct {
+	io.Reader
+	io.Writer
+
+	disconnect  chan bool
+	foundEscape bool
+}
+
+// The terminal is in raw mode, so every read returns a single keystroke.
+func (t *consoleTerminal) Read(p []byte) (int, error) {
+	n, err := t.Reader.Read(p)
+	if n == 0 {
+		return n, err
+	}
+
+	if p[0] == '\x01' && !t.foundEscape {
+		t.foundEscape = true
+		return 0, err
+	}
+
+	if p[0] == 'q' && t.foundEscape {
+		select {
+		case t.disconnect <- true:
+		default:
+		}
+
+		return 0, err
+	}
+
+	t.foundEscape = false
+	return n, err
+}
+
+func (t *consoleTerminal) Close() error {
+	return nil
+}
+
+func (c *consoleCmd) run(conf *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errArgs
+	}
+
+	remote, name, err := conf.ParseRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	d, err := conf.GetContainerServer(remote)
+	if err != nil {
+		return err
+	}
+
+	if c.showLog {
+		log, err := d.GetContainerConsoleLog(name)
+		if err != nil {
+			return err
+		}
+		defer log.Close()
+
+		_, err = io.Copy(os.Stdout, log)
+		return err
+	}
+
+	return c.console(d, name)
+}
+
+func (c *consoleCmd) console(d lxd.ContainerServer, name string) error {
+	// Configure the terminal
+	cfd := int(syscall.Stdin)
+
+	oldttystate, err := termios.MakeRaw(cfd)
+	if err != nil {
+		return err
+	}
+	defer termios.Restore(cfd, oldttystate)
+
+	width, height, err := termios.GetSize(int(syscall.Stdout))
+	if err != nil {
+		return err
+	}
+
+	req := api.ContainerConsolePost{
+		Width:  width,
+		Height: height,
+	}
+
+	disconnect := make(chan bool, 1)
+	consoleArgs := lxd.ContainerConsoleArgs{
+		Terminal:          &consoleTerminal{Reader: os.Stdin, Writer: os.Stdout, disconnect: disconnect},
+		Control:           c.controlSocketHandler,
+		ConsoleDisconnect: disconnect,
+	}
+
+	fmt.Printf(i18n.G("To detach from the console, press: <ctrl>+a q") + "\n\r")
+
+	// Attach to the container console
+	op, err := d.ConsoleContainer(name, req, &consoleArgs)
+	if err != nil {
+		return err
+	}
+
+	// Wait for the operation to complete
+	return op.Wait()
+}
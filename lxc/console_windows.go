@@ -0,0 +1,12 @@
+// +build windows
+
+package main
+
+import (
+	"github.com/gorilla/websocket"
+)
+
+func (c *consoleCmd) controlSocketHandler(control *websocket.Conn) {
+	// Windows doesn't send window resize signals, nothing to forward
+	<-make(chan bool)
+}
@@ -12,7 +12,9 @@ import (
 )
 
 type copyCmd struct {
-	ephem bool
+	ephem         bool
+	refresh       bool
+	containerOnly bool
 }
 
 func (c *copyCmd) showByDefault() bool {
@@ -21,14 +23,22 @@ func (c *copyCmd) showByDefault() bool {
 
 func (c *copyCmd) usage() string {
 	return i18n.G(
-		`Usage: lxc copy [<remote>:]<source>[/<snapshot>] [[<remote>:]<destination>] [--ephemeral|e]
+		`Usage: lxc copy [<remote>:]<source>[/<snapshot>] [[<remote>:]<destination>] [--ephemeral|e] [--refresh] [--container-only]
 
-Copy containers within or in between LXD instances.`)
+Copy containers within or in between LXD instances.
+
+With --refresh, an existing destination container is updated instead: only
+the snapshots it's missing are transferred, followed by the changes to the
+container's filesystem.
+
+With --container-only, the snapshots of the source container aren't copied.`)
 }
 
 func (c *copyCmd) flags() {
 	gnuflag.BoolVar(&c.ephem, "ephemeral", false, i18n.G("Ephemeral container"))
 	gnuflag.BoolVar(&c.ephem, "e", false, i18n.G("Ephemeral container"))
+	gnuflag.BoolVar(&c.refresh, "refresh", false, i18n.G("Update an existing destination container"))
+	gnuflag.BoolVar(&c.containerOnly, "container-only", false, i18n.G("Copy the container without its snapshots"))
 }
 
 func (c *copyCmd) copyContainer(conf *config.Config, sourceResource string, destResource string, keepVolatile bool, ephemeral int) error {
@@ -75,6 +85,10 @@ func (c *copyCmd) copyContainer(conf *config.Config, sourceResource string, dest
 
 	var op *lxd.RemoteOperation
 	if shared.IsSnapshot(sourceName) {
+		if c.refresh {
+			return fmt.Errorf(i18n.G("--refresh can only be used with containers"))
+		}
+
 		// Prepare the container creation request
 		args := lxd.ContainerSnapshotCopyArgs{
 			Name: destName,
@@ -115,7 +129,9 @@ func (c *copyCmd) copyContainer(conf *config.Config, sourceResource string, dest
 	} else {
 		// Prepare the container creation request
 		args := lxd.ContainerCopyArgs{
-			Name: destName,
+			Name:          destName,
+			Refresh:       c.refresh,
+			ContainerOnly: c.containerOnly,
 		}
 
 		// Copy of a container into a new container
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/config"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/gnuflag"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type exportCmd struct {
+	containerOnly    bool
+	optimizedStorage bool
+}
+
+func (c *exportCmd) showByDefault() bool {
+	return true
+}
+
+func (c *exportCmd) usage() string {
+	return i18n.G(
+		`Usage: lxc export [<remote>:]<container> [target] [--container-only] [--optimized-storage]
+
+Export containers as backup tarballs.
+
+The tarball holds the container, its snapshots and their configuration,
+and can be restored with "lxc import". It defaults to <container>.tar.gz.
+
+*Examples*
+lxc export u1 backup0.tar.gz
+    Download a backup tarball of the u1 container.`)
+}
+
+func (c *exportCmd) flags() {
+	gnuflag.BoolVar(&c.containerOnly, "container-only", false, i18n.G("Whether or not to only backup the container (without snapshots)"))
+	gnuflag.BoolVar(&c.optimizedStorage, "optimized-storage", false, i18n.G("Use storage driver optimized format (can only be restored on a similar pool)"))
+}
+
+func (c *exportCmd) run(conf *config.Config, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errArgs
+	}
+
+	remote, name, err := conf.ParseRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	if shared.IsSnapshot(name) {
+		return fmt.Errorf(i18n.G("Snapshots can't be exported on their own"))
+	}
+
+	d, err := conf.GetContainerServer(remote)
+	if err != nil {
+		return err
+	}
+
+	req := api.ContainerBackupsPost{
+		ContainerOnly:    c.containerOnly,
+		OptimizedStorage: c.optimizedStorage,
+	}
+
+	op, err := d.CreateContainerBackup(name, req)
+	if err != nil {
+		return fmt.Errorf(i18n.G("Create backup: %v"), err)
+	}
+
+	// Watch the background operation
+	progress := ProgressRenderer{Format: i18n.G("Backing up container: %s")}
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = op.Wait()
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+	progress.Done("")
+
+	// Get name of backup
+	backupName := path.Base(op.Resources["backups"][0])
+
+	defer func() {
+		// Delete backup after we're done
+		op, err := d.DeleteContainerBackup(name, backupName)
+		if err == nil {
+			op.Wait()
+		}
+	}()
+
+	var target *os.File
+	if len(args) > 1 {
+		target, err = os.Create(shared.HostPath(args[1]))
+	} else {
+		target, err = os.Create(fmt.Sprintf("%s.tar.gz", name))
+	}
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	// Prepare the download request
+	progress = ProgressRenderer{Format: i18n.G("Exporting the backup: %s")}
+	backupFileRequest := lxd.BackupFileRequest{
+		BackupFile:      io.WriteSeeker(target),
+		ProgressHandler: progress.UpdateProgress,
+	}
+
+	// Export tarball
+	_, err = d.GetContainerBackupFile(name, backupName, &backupFileRequest)
+	if err != nil {
+		os.Remove(target.Name())
+		progress.Done("")
+		return fmt.Errorf(i18n.G("Fetch container backup file: %v"), err)
+	}
+
+	progress.Done(i18n.G("Backup exported successfully!"))
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -23,6 +24,8 @@ type fileCmd struct {
 	uid  int
 	gid  int
 	mode string
+
+	recursive bool
 }
 
 func (c *fileCmd) showByDefault() bool {
@@ -35,10 +38,10 @@ func (c *fileCmd) usage() string {
 
 Manage files in containers.
 
-lxc file pull [<remote>:]<container>/<path> [[<remote>:]<container>/<path>...] <target path>
+lxc file pull [-r|--recursive] [<remote>:]<container>/<path> [[<remote>:]<container>/<path>...] <target path>
     Pull files from containers.
 
-lxc file push [--uid=UID] [--gid=GID] [--mode=MODE] <source path> [<source path>...] [<remote>:]<container>/<path>
+lxc file push [-r|--recursive] [--uid=UID] [--gid=GID] [--mode=MODE] <source path> [<source path>...] [<remote>:]<container>/<path>
     Push files into containers.
 
 lxc file edit [<remote>:]<container>/<path>
@@ -49,13 +52,157 @@ lxc file push /etc/hosts foo/etc/hosts
    To push /etc/hosts into the container "foo".
 
 lxc file pull foo/etc/hosts .
-   To pull /etc/hosts from the container and write it to the current directory.`)
+   To pull /etc/hosts from the container and write it to the current directory.
+
+lxc file push -r /srv/www foo/srv
+   To push the /srv/www directory tree into /srv/www in the container "foo",
+   keeping the ownership and permissions of its content.`)
 }
 
 func (c *fileCmd) flags() {
 	gnuflag.IntVar(&c.uid, "uid", -1, i18n.G("Set the file's uid on push"))
 	gnuflag.IntVar(&c.gid, "gid", -1, i18n.G("Set the file's gid on push"))
 	gnuflag.StringVar(&c.mode, "mode", "", i18n.G("Set the file's perms on push"))
+	gnuflag.BoolVar(&c.recursive, "recursive", false, i18n.G("Recursively push or pull files"))
+	gnuflag.BoolVar(&c.recursive, "r", false, i18n.G("Recursively push or pull files"))
+}
+
+func (c *fileCmd) recursivePushFile(d lxd.ContainerServer, container string, source string, target string) error {
+	source = filepath.Clean(source)
+	sourceDir, _ := filepath.Split(source)
+	sourceLen := len(sourceDir)
+
+	sendFile := func(p string, fInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf(i18n.G("Failed to walk path for %s: %s"), p, err)
+		}
+
+		// Detect unsupported files
+		if !fInfo.Mode().IsRegular() && !fInfo.Mode().IsDir() && fInfo.Mode()&os.ModeSymlink != os.ModeSymlink {
+			return fmt.Errorf(i18n.G("'%s' isn't a supported file type."), p)
+		}
+
+		// Prepare for file transfer
+		targetPath := path.Join(target, filepath.ToSlash(p[sourceLen:]))
+		mode, uid, gid := c.getOwnerMode(fInfo)
+		if c.uid >= 0 {
+			uid = c.uid
+		}
+
+		if c.gid >= 0 {
+			gid = c.gid
+		}
+
+		args := lxd.ContainerFileArgs{
+			UID:  int64(uid),
+			GID:  int64(gid),
+			Mode: int(mode.Perm()),
+		}
+
+		if fInfo.IsDir() {
+			// Directory handling
+			args.Type = "directory"
+		} else if fInfo.Mode()&os.ModeSymlink == os.ModeSymlink {
+			// Symlink handling
+			symlinkTarget, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+
+			args.Type = "symlink"
+			args.Content = bytes.NewReader([]byte(symlinkTarget))
+		} else {
+			// File handling
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			args.Type = "file"
+			args.Content = f
+		}
+
+		return d.CreateContainerFile(container, targetPath, args)
+	}
+
+	return filepath.Walk(source, sendFile)
+}
+
+func (c *fileCmd) recursivePullFile(d lxd.ContainerServer, container string, p string, targetDir string, followSymlinks bool) error {
+	// Only the path given by the user is followed, symlinks found while
+	// walking the tree are copied as symlinks
+	var buf io.ReadCloser
+	var resp *lxd.ContainerFileResponse
+	var err error
+	if followSymlinks {
+		buf, resp, err = d.GetContainerFile(container, p)
+	} else {
+		buf, resp, err = d.GetContainerFileNoFollow(container, p)
+	}
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(targetDir, filepath.Base(p))
+
+	switch resp.Type {
+	case "directory":
+		// Keep the directory writable until its content is pulled
+		err := os.Mkdir(target, 0700)
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+
+		for _, ent := range resp.Entries {
+			err := c.recursivePullFile(d, container, path.Join(p, ent), target, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = os.Chmod(target, os.FileMode(resp.Mode))
+		if err != nil {
+			return err
+		}
+	case "file":
+		defer buf.Close()
+
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(resp.Mode))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(f, buf)
+		if err != nil {
+			return err
+		}
+	case "symlink":
+		defer buf.Close()
+
+		linkTarget, err := ioutil.ReadAll(buf)
+		if err != nil {
+			return err
+		}
+
+		err = os.Symlink(string(linkTarget), target)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf(i18n.G("Unknown file type '%s'"), resp.Type)
+	}
+
+	// Ownership can only be restored when running as root
+	if os.Geteuid() == 0 {
+		err = os.Lchown(target, int(resp.UID), int(resp.GID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (c *fileCmd) push(conf *config.Config, send_file_perms bool, args []string) error {
@@ -81,6 +228,21 @@ func (c *fileCmd) push(conf *config.Config, send_file_perms bool, args []string)
 		return err
 	}
 
+	if c.recursive {
+		if c.mode != "" {
+			return fmt.Errorf(i18n.G("The --mode flag can't be used together with --recursive"))
+		}
+
+		for _, fname := range args[:len(args)-1] {
+			err := c.recursivePushFile(d, container, shared.HostPath(fname), targetPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
 	mode := os.FileMode(0755)
 	if c.mode != "" {
 		if len(c.mode) == 3 {
@@ -129,6 +291,15 @@ func (c *fileCmd) push(conf *config.Config, send_file_perms bool, args []string)
 			if err != nil {
 				return err
 			}
+
+			fInfo, err := file.Stat()
+			if err != nil {
+				return err
+			}
+
+			if fInfo.IsDir() {
+				return fmt.Errorf(i18n.G("'%s' is a directory, use --recursive to push it"), f)
+			}
 		}
 
 		defer file.Close()
@@ -229,11 +400,32 @@ func (c *fileCmd) pull(conf *config.Config, args []string) error {
 			return err
 		}
 
-		buf, _, err := d.GetContainerFile(container, pathSpec[1])
+		if c.recursive {
+			if !targetIsDir {
+				err := os.MkdirAll(target, 0755)
+				if err != nil {
+					return err
+				}
+				targetIsDir = true
+			}
+
+			err := c.recursivePullFile(d, container, pathSpec[1], target, true)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		buf, resp, err := d.GetContainerFile(container, pathSpec[1])
 		if err != nil {
 			return err
 		}
 
+		if resp.Type == "directory" {
+			return fmt.Errorf(i18n.G("'%s' is a directory, use --recursive to pull it"), pathSpec[1])
+		}
+
 		var targetPath string
 		if targetIsDir {
 			targetPath = path.Join(target, path.Base(pathSpec[1]))
@@ -13,9 +13,15 @@ func (c *fileCmd) getOwner(f *os.File) (os.FileMode, int, int, error) {
 		return os.FileMode(0), -1, -1, err
 	}
 
+	mode, uid, gid := c.getOwnerMode(fInfo)
+
+	return mode, uid, gid, nil
+}
+
+func (c *fileCmd) getOwnerMode(fInfo os.FileInfo) (os.FileMode, int, int) {
 	mode := fInfo.Mode()
 	uid := int(fInfo.Sys().(*syscall.Stat_t).Uid)
 	gid := int(fInfo.Sys().(*syscall.Stat_t).Gid)
 
-	return mode, uid, gid, nil
+	return mode, uid, gid
 }
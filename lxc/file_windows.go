@@ -9,3 +9,7 @@ import (
 func (c *fileCmd) getOwner(f *os.File) (os.FileMode, int, int, error) {
 	return os.FileMode(0), -1, -1, nil
 }
+
+func (c *fileCmd) getOwnerMode(fInfo os.FileInfo) (os.FileMode, int, int) {
+	return fInfo.Mode(), -1, -1
+}
@@ -104,6 +104,12 @@ lxc image export [<remote>:]<image> [target]
     the appropriate extension will be appended to the provided file name
     based on the algorithm used to compress the image.
 
+lxc image refresh [<remote>:]<image> [[<remote>:]<image>...]
+    Refresh one or more images from their source.
+
+    A newer version of the image, if available, replaces the local copy
+    and takes over its aliases.
+
 lxc image info [<remote>:]<image>
     Print everything LXD knows about a given image.
 
@@ -372,6 +378,55 @@ func (c *imageCmd) run(conf *config.Config, args []string) error {
 
 		return nil
 
+	case "refresh":
+		/* refresh [<remote>:]<image> [<image>...] */
+		if len(args) < 2 {
+			return errArgs
+		}
+
+		for _, arg := range args[1:] {
+			remote, inName, err := conf.ParseRemote(arg)
+			if err != nil {
+				return err
+			}
+
+			d, err := conf.GetContainerServer(remote)
+			if err != nil {
+				return err
+			}
+
+			image := c.dereferenceAlias(d, inName)
+			op, err := d.RefreshImage(image)
+			if err != nil {
+				return err
+			}
+
+			// Register progress handler
+			progress := ProgressRenderer{Format: i18n.G("Refreshing the image: %s")}
+			_, err = op.AddHandler(progress.UpdateOp)
+			if err != nil {
+				progress.Done("")
+				return err
+			}
+
+			// Wait for the refresh to complete
+			err = op.Wait()
+			if err != nil {
+				progress.Done("")
+				return err
+			}
+
+			// Check whether the image was updated
+			refreshed, ok := op.Metadata["refreshed"].(bool)
+			if ok && refreshed {
+				progress.Done(fmt.Sprintf(i18n.G("Image refreshed with fingerprint: %s"), op.Metadata["fingerprint"]))
+			} else {
+				progress.Done(i18n.G("Image already up to date."))
+			}
+		}
+
+		return nil
+
 	case "info":
 		if len(args) < 2 {
 			return errArgs
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/config"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/ioprogress"
+)
+
+type importCmd struct {
+}
+
+func (c *importCmd) showByDefault() bool {
+	return true
+}
+
+func (c *importCmd) usage() string {
+	return i18n.G(
+		`Usage: lxc import [<remote>:] <backup file>
+
+Import backups of containers including their snapshots.
+
+The container is recreated under the name it had when exported.
+
+*Examples*
+lxc import backup0.tar.gz
+    Create a new container using backup0.tar.gz as the source.`)
+}
+
+func (c *importCmd) flags() {}
+
+func (c *importCmd) run(conf *config.Config, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errArgs
+	}
+
+	remote := conf.DefaultRemote
+	backupFile := args[0]
+	if len(args) > 1 {
+		var err error
+		remote, _, err = conf.ParseRemote(args[0])
+		if err != nil {
+			return err
+		}
+
+		backupFile = args[1]
+	}
+
+	d, err := conf.GetContainerServer(remote)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(shared.HostPath(backupFile))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fstat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	progress := ProgressRenderer{Format: i18n.G("Importing container: %s")}
+
+	createArgs := lxd.ContainerBackupArgs{}
+	createArgs.BackupFile = &ioprogress.ProgressReader{
+		ReadCloser: file,
+		Tracker: &ioprogress.ProgressTracker{
+			Length: fstat.Size(),
+			Handler: func(percent int64, speed int64) {
+				progress.UpdateProgress(lxd.ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, shared.GetByteSizeString(speed, 2))})
+			},
+		},
+	}
+
+	op, err := d.CreateContainerFromBackup(createArgs)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = op.Wait()
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+	return nil
+}
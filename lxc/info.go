@@ -15,7 +15,8 @@ import (
 )
 
 type infoCmd struct {
-	showLog bool
+	showLog   bool
+	resources bool
 }
 
 func (c *infoCmd) showByDefault() bool {
@@ -24,19 +25,20 @@ func (c *infoCmd) showByDefault() bool {
 
 func (c *infoCmd) usage() string {
 	return i18n.G(
-		`Usage: lxc info [<remote>:][<container>] [--show-log]
+		`Usage: lxc info [<remote>:][<container>] [--show-log] [--resources]
 
 Show container or server information.
 
 lxc info [<remote>:]<container> [--show-log]
     For container information.
 
-lxc info [<remote>:]
+lxc info [<remote>:] [--resources]
     For LXD server information.`)
 }
 
 func (c *infoCmd) flags() {
 	gnuflag.BoolVar(&c.showLog, "show-log", false, i18n.G("Show the container's last 100 log lines?"))
+	gnuflag.BoolVar(&c.resources, "resources", false, i18n.G("Show the resources available to the server"))
 }
 
 func (c *infoCmd) run(conf *config.Config, args []string) error {
@@ -62,12 +64,30 @@ func (c *infoCmd) run(conf *config.Config, args []string) error {
 
 	if cName == "" {
 		return c.remoteInfo(d)
+	} else if c.resources {
+		return fmt.Errorf(i18n.G("--resources can only be used with a remote"))
 	} else {
 		return c.containerInfo(d, conf.Remotes[remote], cName, c.showLog)
 	}
 }
 
 func (c *infoCmd) remoteInfo(d lxd.ContainerServer) error {
+	if c.resources {
+		resources, err := d.GetServerResources()
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(&resources)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s", data)
+
+		return nil
+	}
+
 	serverStatus, _, err := d.GetServer()
 	if err != nil {
 		return err
@@ -215,11 +235,19 @@ func (c *infoCmd) containerInfo(d lxd.ContainerServer, remote config.Remote, nam
 			fmt.Printf(" ("+i18n.G("taken at %s")+")", snap.CreationDate.UTC().Format(layout))
 		}
 
+		if shared.TimeIsSet(snap.ExpiresAt) {
+			fmt.Printf(" ("+i18n.G("expires at %s")+")", snap.ExpiresAt.UTC().Format(layout))
+		}
+
 		if snap.Stateful {
 			fmt.Printf(" (" + i18n.G("stateful") + ")")
 		} else {
 			fmt.Printf(" (" + i18n.G("stateless") + ")")
 		}
+
+		if snap.Description != "" {
+			fmt.Printf(" - %s", snap.Description)
+		}
 		fmt.Printf("\n")
 
 		first_snapshot = false
@@ -201,13 +201,16 @@ type command interface {
 
 var commands = map[string]command{
 	"config":  &configCmd{},
+	"console": &consoleCmd{},
 	"copy":    &copyCmd{},
 	"delete":  &deleteCmd{},
 	"exec":    &execCmd{},
+	"export":  &exportCmd{},
 	"file":    &fileCmd{},
 	"finger":  &fingerCmd{},
 	"help":    &helpCmd{},
 	"image":   &imageCmd{},
+	"import":  &importCmd{},
 	"info":    &infoCmd{},
 	"init":    &initCmd{},
 	"launch":  &launchCmd{},
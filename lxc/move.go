@@ -6,10 +6,12 @@ import (
 	"github.com/lxc/lxd/lxc/config"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/gnuflag"
 	"github.com/lxc/lxd/shared/i18n"
 )
 
 type moveCmd struct {
+	containerOnly bool
 }
 
 func (c *moveCmd) showByDefault() bool {
@@ -18,12 +20,13 @@ func (c *moveCmd) showByDefault() bool {
 
 func (c *moveCmd) usage() string {
 	return i18n.G(
-		`Usage: lxc move [<remote>:]<container>[/<snapshot>] [<remote>:][<container>[/<snapshot>]]
+		`Usage: lxc move [<remote>:]<container>[/<snapshot>] [<remote>:][<container>[/<snapshot>]] [--container-only]
 
 Move containers within or in between LXD instances.
 
-lxc move [<remote>:]<source container> [<remote>:][<destination container>]
+lxc move [<remote>:]<source container> [<remote>:][<destination container>] [--container-only]
     Move a container between two hosts, renaming it if destination name differs.
+    With --container-only, its snapshots are left behind and deleted along with it.
 
 lxc move <old name> <new name>
     Rename a local container.
@@ -32,7 +35,9 @@ lxc move <container>/<old snapshot name> <container>/<new snapshot name>
     Rename a snapshot.`)
 }
 
-func (c *moveCmd) flags() {}
+func (c *moveCmd) flags() {
+	gnuflag.BoolVar(&c.containerOnly, "container-only", false, i18n.G("Move the container without its snapshots"))
+}
 
 func (c *moveCmd) run(conf *config.Config, args []string) error {
 	if len(args) != 2 {
@@ -83,6 +88,7 @@ func (c *moveCmd) run(conf *config.Config, args []string) error {
 	}
 
 	cpy := copyCmd{}
+	cpy.containerOnly = c.containerOnly
 
 	// A move is just a copy followed by a delete; however, we want to
 	// keep the volatile entries around since we are moving the container.
@@ -14,9 +14,10 @@ import (
 )
 
 type publishCmd struct {
-	pAliases   aliasList // aliasList defined in lxc/image.go
-	makePublic bool
-	Force      bool
+	pAliases             aliasList // aliasList defined in lxc/image.go
+	makePublic           bool
+	Force                bool
+	compressionAlgorithm string
 }
 
 func (c *publishCmd) showByDefault() bool {
@@ -25,9 +26,12 @@ func (c *publishCmd) showByDefault() bool {
 
 func (c *publishCmd) usage() string {
 	return i18n.G(
-		`Usage: lxc publish [<remote>:]<container>[/<snapshot>] [<remote>:] [--alias=ALIAS...] [prop-key=prop-value...]
+		`Usage: lxc publish [<remote>:]<container>[/<snapshot>] [<remote>:] [--alias=ALIAS...] [--compression=ALGORITHM] [prop-key=prop-value...]
 
-Publish containers as images.`)
+Publish containers as images.
+
+The --compression flag overrides the server's images.compression_algorithm
+for this image and can be set to bzip2, gzip, lzma, xz or none.`)
 }
 
 func (c *publishCmd) flags() {
@@ -35,6 +39,7 @@ func (c *publishCmd) flags() {
 	gnuflag.Var(&c.pAliases, "alias", i18n.G("New alias to define at target"))
 	gnuflag.BoolVar(&c.Force, "force", false, i18n.G("Stop the container if currently running"))
 	gnuflag.BoolVar(&c.Force, "f", false, i18n.G("Stop the container if currently running"))
+	gnuflag.StringVar(&c.compressionAlgorithm, "compression", "", i18n.G("Compression algorithm to use (bzip2, gzip, lzma, xz or none)"))
 }
 
 func (c *publishCmd) run(conf *config.Config, args []string) error {
@@ -191,6 +196,7 @@ func (c *publishCmd) run(conf *config.Config, args []string) error {
 		},
 	}
 	req.Properties = properties
+	req.CompressionAlgorithm = c.compressionAlgorithm
 
 	if shared.IsSnapshot(cName) {
 		req.Source.Type = "snapshot"
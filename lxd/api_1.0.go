@@ -24,8 +24,14 @@ var api10 = []Command{
 	containerFileCmd,
 	containerLogsCmd,
 	containerLogCmd,
+	containerMetadataCmd,
+	containerMetadataTemplatesCmd,
 	containerSnapshotsCmd,
 	containerSnapshotCmd,
+	containerBackupsCmd,
+	containerBackupCmd,
+	containerBackupExportCmd,
+	containerConsoleCmd,
 	containerExecCmd,
 	aliasCmd,
 	aliasesCmd,
@@ -34,6 +40,7 @@ var api10 = []Command{
 	imagesCmd,
 	imagesExportCmd,
 	imagesSecretCmd,
+	imagesRefreshCmd,
 	operationsCmd,
 	operationCmd,
 	operationWait,
@@ -41,10 +48,17 @@ var api10 = []Command{
 	networksCmd,
 	networkCmd,
 	api10Cmd,
+	api10ResourcesCmd,
 	certificatesCmd,
 	certificateFingerprintCmd,
 	profilesCmd,
 	profileCmd,
+	storagePoolsCmd,
+	storagePoolCmd,
+	storagePoolResourcesCmd,
+	storagePoolVolumesCmd,
+	storagePoolVolumesTypeCmd,
+	storagePoolVolumeTypeCmd,
 }
 
 func api10Get(d *Daemon, r *http.Request) Response {
@@ -62,6 +76,34 @@ func api10Get(d *Daemon, r *http.Request) Response {
 			"id_map",
 			"id_map_base",
 			"resource_limits",
+			"storage",
+			"network",
+			"resources",
+			"container_edit_metadata",
+			"directory_manipulation",
+			"file_symlinks",
+			"file_append",
+			"file_delete",
+			"event_lifecycle",
+			"certificate_update",
+			"certificate_roles",
+			"snapshot_scheduling",
+			"container_backup",
+			"container_syscall_filtering",
+			"gpu_devices",
+			"usb_devices",
+			"proxy",
+			"console",
+			"container_user_group_cwd",
+			"container_exec_recording",
+			"container_incremental_copy",
+			"container_only_migration",
+			"snapshot_expiry",
+			"image_force_refresh",
+			"image_compression_algorithm",
+			"image_simplestreams_index",
+			"image_download_progress",
+			"storage_api_volume_rename",
 		},
 		APIStatus:  "stable",
 		APIVersion: version.APIVersion,
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/simplestreams"
+)
+
+var apiSimpleStreams = []Command{
+	simpleStreamsIndexCmd,
+	simpleStreamsImagesCmd,
+}
+
+// The hash and size of a single file of an image
+type simpleStreamsFile struct {
+	sha256 string
+	size   int64
+}
+
+// Image files never change, so their hashes only need to be computed once
+var simpleStreamsFiles = map[string]simpleStreamsFile{}
+var simpleStreamsFilesLock sync.Mutex
+
+func simpleStreamsFileGet(path string) (simpleStreamsFile, error) {
+	simpleStreamsFilesLock.Lock()
+	defer simpleStreamsFilesLock.Unlock()
+
+	file, ok := simpleStreamsFiles[path]
+	if ok {
+		return file, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return simpleStreamsFile{}, err
+	}
+	defer f.Close()
+
+	sha256 := sha256.New()
+	size, err := io.Copy(sha256, f)
+	if err != nil {
+		return simpleStreamsFile{}, err
+	}
+
+	file = simpleStreamsFile{sha256: fmt.Sprintf("%x", sha256.Sum(nil)), size: size}
+	simpleStreamsFiles[path] = file
+
+	return file, nil
+}
+
+// Build a simplestreams product manifest out of the public images.
+func simpleStreamsManifestGet(d *Daemon) (*simplestreams.SimpleStreamsManifest, error) {
+	fingerprints, err := db.ImagesGet(d.db, true)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := simplestreams.SimpleStreamsManifest{
+		Updated:  time.Now().UTC().Format(time.RFC1123Z),
+		DataType: "image-downloads",
+		Format:   "products:1.0",
+		Products: map[string]simplestreams.SimpleStreamsManifestProduct{},
+	}
+
+	// The aliases of each product and the creation date of its newest version
+	aliases := map[string][]string{}
+	newest := map[string]time.Time{}
+
+	for _, fingerprint := range fingerprints {
+		_, info, err := db.ImageGet(d.db, fingerprint, true, true)
+		if err != nil {
+			return nil, err
+		}
+
+		// Simplestreams only knows about split images
+		imagePath := shared.VarPath("images", info.Fingerprint)
+		rootfsPath := imagePath + ".rootfs"
+		if !shared.PathExists(rootfsPath) {
+			logger.Debugf("Not listing unified image %s in the simplestreams index", info.Fingerprint)
+			continue
+		}
+
+		meta, err := simpleStreamsFileGet(imagePath)
+		if err != nil {
+			return nil, err
+		}
+
+		rootfs, err := simpleStreamsFileGet(rootfsPath)
+		if err != nil {
+			return nil, err
+		}
+
+		// Both parts are served by the image export endpoint
+		metaItem := simplestreams.SimpleStreamsManifestProductVersionItem{
+			Path:          fmt.Sprintf("1.0/images/%s/export?part=metadata", info.Fingerprint),
+			FileType:      "lxd.tar.xz",
+			HashSha256:    meta.sha256,
+			Size:          meta.size,
+			LXDHashSha256: info.Fingerprint,
+		}
+
+		rootfsItem := simplestreams.SimpleStreamsManifestProductVersionItem{
+			Path:       fmt.Sprintf("1.0/images/%s/export?part=rootfs", info.Fingerprint),
+			FileType:   "root.tar.xz",
+			HashSha256: rootfs.sha256,
+			Size:       rootfs.size,
+		}
+
+		_, ext, err := detectCompression(rootfsPath)
+		if err == nil && ext == ".squashfs" {
+			rootfsItem.FileType = "squashfs"
+			metaItem.LXDHashSha256SquashFs = info.Fingerprint
+		} else {
+			metaItem.LXDHashSha256RootXz = info.Fingerprint
+		}
+
+		// The version name must start with the image's creation date
+		created := info.CreatedAt
+		if !shared.TimeIsSet(created) {
+			created = info.UploadedAt
+		}
+
+		version := simplestreams.SimpleStreamsManifestProductVersion{
+			Label: info.Properties["label"],
+			Items: map[string]simplestreams.SimpleStreamsManifestProductVersionItem{
+				metaItem.FileType:   metaItem,
+				rootfsItem.FileType: rootfsItem,
+			},
+		}
+
+		// Images of the same os, release and architecture are versions of
+		// the same product, images lacking those are products of their own
+		name := info.Fingerprint
+		if info.Properties["os"] != "" && info.Properties["release"] != "" {
+			name = fmt.Sprintf("%s:%s:%s", info.Properties["os"], info.Properties["release"], info.Architecture)
+		}
+
+		product, ok := manifest.Products[name]
+		if !ok {
+			product = simplestreams.SimpleStreamsManifestProduct{
+				Architecture:    info.Architecture,
+				OperatingSystem: info.Properties["os"],
+				Release:         info.Properties["release"],
+				ReleaseTitle:    info.Properties["release"],
+				Versions:        map[string]simplestreams.SimpleStreamsManifestProductVersion{},
+			}
+		}
+
+		versionName := created.UTC().Format("20060102_1504")
+		_, ok = product.Versions[versionName]
+		if ok {
+			versionName = fmt.Sprintf("%s_%s", versionName, info.Fingerprint[0:12])
+		}
+		product.Versions[versionName] = version
+
+		// Clients point the product's aliases to its newest version
+		for _, alias := range info.Aliases {
+			if !shared.StringInSlice(alias.Name, aliases[name]) {
+				aliases[name] = append(aliases[name], alias.Name)
+			}
+		}
+		product.Aliases = strings.Join(aliases[name], ",")
+
+		// The product is described by its newest version
+		if !created.Before(newest[name]) {
+			newest[name] = created
+			product.Version = info.Properties["version"]
+			product.SupportedEOL = ""
+			if shared.TimeIsSet(info.ExpiresAt) {
+				product.SupportedEOL = info.ExpiresAt.UTC().Format("2006-01-02")
+			}
+		}
+
+		manifest.Products[name] = product
+	}
+
+	return &manifest, nil
+}
+
+// Render a simplestreams document as a plain JSON file.
+func simpleStreamsResponse(r *http.Request, filename string, content interface{}) Response {
+	buf, err := json.Marshal(content)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	files := []fileResponseEntry{{identifier: filename, filename: filename, buffer: buf}}
+	return FileResponse(r, files, nil, false)
+}
+
+func simpleStreamsIndexGet(d *Daemon, r *http.Request) Response {
+	manifest, err := simpleStreamsManifestGet(d)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	products := []string{}
+	for name := range manifest.Products {
+		products = append(products, name)
+	}
+	sort.Strings(products)
+
+	index := simplestreams.SimpleStreamsIndex{
+		Format:  "index:1.0",
+		Updated: manifest.Updated,
+		Index: map[string]simplestreams.SimpleStreamsIndexStream{
+			"images": {
+				Updated:  manifest.Updated,
+				DataType: manifest.DataType,
+				Path:     "streams/v1/images.json",
+				Products: products,
+			},
+		},
+	}
+
+	return simpleStreamsResponse(r, "index.json", index)
+}
+
+func simpleStreamsImagesGet(d *Daemon, r *http.Request) Response {
+	manifest, err := simpleStreamsManifestGet(d)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return simpleStreamsResponse(r, "images.json", manifest)
+}
+
+var simpleStreamsIndexCmd = Command{name: "v1/index.json", untrustedGet: true, get: simpleStreamsIndexGet}
+var simpleStreamsImagesCmd = Command{name: "v1/images.json", untrustedGet: true, get: simpleStreamsImagesGet}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/types"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+/* A backup is a gzip compressed tarball with the following layout:
+
+   backup/index.yaml              the backupInfo describing the content
+   backup/container/              the container's directory
+   backup/snapshots/<name>/       the directory of each snapshot
+
+   Optimized backups replace the container and snapshot directories with
+   container.bin and snapshots/<name>.bin, in the native send format of the
+   storage backend they were made on.
+*/
+
+// backupInfo is the index of a backup, recording what's needed to recreate
+// the database records of the container and its snapshots.
+type backupInfo struct {
+	Name      string            `yaml:"name"`
+	Backend   string            `yaml:"backend"`
+	Optimized bool              `yaml:"optimized"`
+	Container backupContainer   `yaml:"container"`
+	Snapshots []backupContainer `yaml:"snapshots,omitempty"`
+}
+
+// backupContainer holds the configuration of a container or snapshot in a
+// backup. Snapshots are listed by their name relative to the container.
+type backupContainer struct {
+	Name         string                       `yaml:"name"`
+	Architecture string                       `yaml:"architecture"`
+	Config       map[string]string            `yaml:"config"`
+	Devices      map[string]map[string]string `yaml:"devices"`
+	Ephemeral    bool                         `yaml:"ephemeral"`
+	Profiles     []string                     `yaml:"profiles"`
+	Stateful     bool                         `yaml:"stateful"`
+}
+
+func backupContainerFromContainer(c container) (backupContainer, error) {
+	architecture, err := osarch.ArchitectureName(c.Architecture())
+	if err != nil {
+		return backupContainer{}, err
+	}
+
+	_, name, isSnapshot := containerGetParentAndSnapshotName(c.Name())
+	if !isSnapshot {
+		name = c.Name()
+	}
+
+	devices := map[string]map[string]string{}
+	for k, v := range c.LocalDevices() {
+		devices[k] = v
+	}
+
+	return backupContainer{
+		Name:         name,
+		Architecture: architecture,
+		Config:       c.LocalConfig(),
+		Devices:      devices,
+		Ephemeral:    c.IsEphemeral(),
+		Profiles:     c.Profiles(),
+		Stateful:     c.IsStateful(),
+	}, nil
+}
+
+// containerArgs returns the arguments to create the container (or snapshot,
+// if a parent container name is given) described in the backup.
+func (b backupContainer) containerArgs(parent string) (db.ContainerArgs, error) {
+	architecture, err := osarch.ArchitectureId(b.Architecture)
+	if err != nil {
+		return db.ContainerArgs{}, err
+	}
+
+	devices := types.Devices{}
+	for k, v := range b.Devices {
+		devices[k] = v
+	}
+
+	args := db.ContainerArgs{
+		Architecture: architecture,
+		Config:       b.Config,
+		Ctype:        db.CTypeRegular,
+		Devices:      devices,
+		Ephemeral:    b.Ephemeral,
+		Name:         b.Name,
+		Profiles:     b.Profiles,
+		Stateful:     b.Stateful,
+	}
+
+	// The names are used as paths when unpacking the backup
+	if parent == "" {
+		err = containerValidName(b.Name)
+	} else {
+		err = containerSnapshotValidName(b.Name)
+	}
+	if err != nil {
+		return db.ContainerArgs{}, err
+	}
+
+	if parent != "" {
+		args.Ctype = db.CTypeSnapshot
+		args.Name = parent + shared.SnapshotDelimiter + b.Name
+	}
+
+	return args, nil
+}
+
+// backupPath returns the path of the tarball of a container backup.
+func backupPath(containerName string, backupName string) string {
+	return shared.VarPath("backups", containerName, backupName)
+}
+
+// backupRsync copies the directory of a container or snapshot into the
+// backup being assembled.
+func backupRsync(c container, target string) error {
+	cleanup, err := containerMetadataStorageStart(c)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	output, err := storageRsyncCopy(c.Path(), target)
+	if err != nil {
+		return fmt.Errorf("Failed to rsync %s: %s: %s", c.Name(), err, output)
+	}
+
+	return nil
+}
+
+// backupCreate writes the tarball of a new container backup.
+func backupCreate(c container, backup db.ContainerBackupArgs) error {
+	var err error
+
+	snapshots := []container{}
+	if !backup.ContainerOnly {
+		snapshots, err = c.Snapshots()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Assemble the backup next to where it will be stored
+	err = os.MkdirAll(shared.VarPath("backups", c.Name()), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmpPath, err := ioutil.TempDir(shared.VarPath("backups", c.Name()), ".backup_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	contentPath := filepath.Join(tmpPath, "backup")
+	err = os.MkdirAll(filepath.Join(contentPath, "snapshots"), 0700)
+	if err != nil {
+		return err
+	}
+
+	info := backupInfo{
+		Name:      c.Name(),
+		Backend:   c.Storage().GetStorageTypeName(),
+		Optimized: backup.OptimizedStorage,
+		Snapshots: []backupContainer{},
+	}
+
+	info.Container, err = backupContainerFromContainer(c)
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range snapshots {
+		snapInfo, err := backupContainerFromContainer(snap)
+		if err != nil {
+			return err
+		}
+
+		info.Snapshots = append(info.Snapshots, snapInfo)
+	}
+
+	index, err := yaml.Marshal(&info)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(contentPath, "index.yaml"), index, 0600)
+	if err != nil {
+		return err
+	}
+
+	if backup.OptimizedStorage {
+		err = c.Storage().ContainerBackupDump(c, snapshots, contentPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		for i, snap := range snapshots {
+			err = backupRsync(snap, filepath.Join(contentPath, "snapshots", info.Snapshots[i].Name))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = backupRsync(c, filepath.Join(contentPath, "container"))
+		if err != nil {
+			return err
+		}
+	}
+
+	output, err := shared.RunCommand("tar", "-czf", backupPath(c.Name(), backup.Name), "--numeric-owner", "--xattrs", "-C", tmpPath, "backup")
+	if err != nil {
+		return fmt.Errorf("Failed to create the backup tarball: %s: %s", err, strings.SplitN(output, "\n", 2)[0])
+	}
+
+	return nil
+}
+
+// backupGetInfo reads the index of a backup tarball.
+func backupGetInfo(path string) (*backupInfo, error) {
+	output, err := shared.RunCommand("tar", "-xzf", path, "-O", "backup/index.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("Could not extract the backup index: %v (%s)", err, strings.SplitN(output, "\n", 2)[0])
+	}
+
+	info := backupInfo{}
+	err = yaml.Unmarshal([]byte(output), &info)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse the backup index: %v", err)
+	}
+
+	if info.Name == "" || info.Name != info.Container.Name {
+		return nil, fmt.Errorf("Invalid backup index: bad container name")
+	}
+
+	return &info, nil
+}
+
+// backupLoad recreates a container and its snapshots from a backup tarball.
+func backupLoad(d *Daemon, info *backupInfo, path string) (container, error) {
+	args, err := info.Container.containerArgs("")
+	if err != nil {
+		return nil, err
+	}
+
+	// The container is restored on the pool of its root disk
+	if info.Optimized {
+		ct := containerLXC{
+			state:        d.State(),
+			name:         args.Name,
+			profiles:     args.Profiles,
+			localDevices: args.Devices,
+		}
+
+		err = ct.expandDevices()
+		if err != nil {
+			return nil, err
+		}
+
+		st, err := storageForPool(d.State(), d.Storage, storagePoolForDevices(ct.expandedDevices))
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Backend != st.GetStorageTypeName() {
+			return nil, fmt.Errorf("Optimized backups can only be restored on the storage backend they were made on (%s)", info.Backend)
+		}
+	}
+
+	snapshots := []db.ContainerArgs{}
+	for _, snap := range info.Snapshots {
+		snapArgs, err := snap.containerArgs(info.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapArgs)
+	}
+
+	err = os.MkdirAll(shared.VarPath("backups"), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpPath, err := ioutil.TempDir(shared.VarPath("backups"), ".restore_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	output, err := shared.RunCommand("tar", "-xzf", path, "-C", tmpPath, "--numeric-owner", "--xattrs")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unpack the backup: %s: %s", err, strings.SplitN(output, "\n", 2)[0])
+	}
+	contentPath := filepath.Join(tmpPath, "backup")
+
+	c, err := containerCreateAsEmpty(d, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Optimized {
+		err = c.Storage().ContainerBackupLoad(c, snapshots, contentPath)
+	} else {
+		err = backupLoadRsync(c, snapshots, contentPath)
+	}
+	if err != nil {
+		c.Delete()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// backupLoadRsync fills an empty container with the content of a backup,
+// creating each snapshot from the container as its content is copied in.
+func backupLoadRsync(c container, snapshots []db.ContainerArgs, contentPath string) error {
+	err := c.StorageStart()
+	if err != nil {
+		return err
+	}
+	defer c.StorageStop()
+
+	for _, args := range snapshots {
+		_, snapName, _ := containerGetParentAndSnapshotName(args.Name)
+		output, err := storageRsyncCopy(filepath.Join(contentPath, "snapshots", snapName), c.Path())
+		if err != nil {
+			return fmt.Errorf("Failed to restore snapshot %s: %s: %s", snapName, err, output)
+		}
+
+		// The state of stateful snapshots was copied along with them
+		stateful := args.Stateful
+		args.Stateful = false
+
+		snap, err := containerCreateAsSnapshot(c.StateObject(), c.Storage(), args, c)
+		if err != nil {
+			return err
+		}
+
+		if stateful {
+			err = db.ContainerSetStateful(c.StateObject().DB, snap.Id(), true)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	output, err := storageRsyncCopy(filepath.Join(contentPath, "container"), c.Path())
+	if err != nil {
+		return fmt.Errorf("Failed to restore the container: %s: %s", err, output)
+	}
+
+	return nil
+}
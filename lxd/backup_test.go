@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+func TestBackupInfoContainerArgs(t *testing.T) {
+	index := `name: c1
+backend: btrfs
+optimized: true
+container:
+  name: c1
+  architecture: x86_64
+  config:
+    limits.cpu: "2"
+  devices:
+    root:
+      path: /
+      type: disk
+  ephemeral: false
+  profiles:
+  - default
+  stateful: false
+snapshots:
+- name: snap0
+  architecture: x86_64
+  config: {}
+  devices: {}
+  ephemeral: false
+  profiles:
+  - default
+  stateful: true
+`
+
+	info := backupInfo{}
+	assert.NoError(t, yaml.Unmarshal([]byte(index), &info))
+	assert.True(t, info.Optimized)
+	assert.Len(t, info.Snapshots, 1)
+
+	args, err := info.Container.containerArgs("")
+	assert.NoError(t, err)
+	assert.Equal(t, "c1", args.Name)
+	assert.Equal(t, db.CTypeRegular, args.Ctype)
+	assert.Equal(t, osarch.ARCH_64BIT_INTEL_X86, args.Architecture)
+	assert.Equal(t, "2", args.Config["limits.cpu"])
+	assert.Equal(t, "disk", args.Devices["root"]["type"])
+
+	args, err = info.Snapshots[0].containerArgs(info.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, "c1/snap0", args.Name)
+	assert.Equal(t, db.CTypeSnapshot, args.Ctype)
+	assert.True(t, args.Stateful)
+
+	info.Container.Architecture = "unknown"
+	_, err = info.Container.containerArgs("")
+	assert.Error(t, err)
+
+	for _, name := range []string{"", "../../c2", "snap/0", ".."} {
+		info.Snapshots[0].Name = name
+		_, err = info.Snapshots[0].containerArgs(info.Name)
+		assert.Error(t, err)
+	}
+}
+
+func TestContainerBackupValidName(t *testing.T) {
+	assert.NoError(t, containerBackupValidName("backup0"))
+	assert.Error(t, containerBackupValidName(""))
+	assert.Error(t, containerBackupValidName("a/b"))
+	assert.Error(t, containerBackupValidName(".import"))
+}
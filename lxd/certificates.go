@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 
@@ -30,15 +31,7 @@ func certificatesGet(d *Daemon, r *http.Request) Response {
 			return SmartError(err)
 		}
 		for _, baseCert := range baseCerts {
-			resp := api.Certificate{}
-			resp.Fingerprint = baseCert.Fingerprint
-			resp.Certificate = baseCert.Certificate
-			if baseCert.Type == 1 {
-				resp.Type = "client"
-			} else {
-				resp.Type = "unknown"
-			}
-			certResponses = append(certResponses, resp)
+			certResponses = append(certResponses, certificateToAPI(baseCert))
 		}
 		return SyncResponse(true, certResponses)
 	}
@@ -52,8 +45,190 @@ func certificatesGet(d *Daemon, r *http.Request) Response {
 	return SyncResponse(true, body)
 }
 
+// The roles which can be given to a client certificate
+var certificateRoles = []string{"admin", "operator", "read-only"}
+
+// The endpoints on which the operator role may do more than GET
+var certificateOperatorCommands = []string{
+	"containers/{name}/console",
+	"containers/{name}/exec",
+	"containers/{name}/files",
+	"containers/{name}/state",
+	"containers/{name}/snapshots",
+	"containers/{name}/snapshots/{snapshotName}",
+	"operations/{id}",
+}
+
+func certificateToAPI(cert *db.CertInfo) api.Certificate {
+	resp := api.Certificate{}
+	resp.Fingerprint = cert.Fingerprint
+	resp.Certificate = cert.Certificate
+	resp.Name = cert.Name
+	resp.Role = cert.Role
+	resp.Containers = cert.Containers
+	resp.Profiles = cert.Profiles
+	if cert.Type == 1 {
+		resp.Type = "client"
+	} else {
+		resp.Type = "unknown"
+	}
+
+	return resp
+}
+
+func certificateValidate(req api.CertificatePut) error {
+	if req.Type != "client" {
+		return fmt.Errorf("Unknown request type %s", req.Type)
+	}
+
+	if req.Role != "" && !shared.StringInSlice(req.Role, certificateRoles) {
+		return fmt.Errorf("Invalid certificate role: %s (not one of %s)", req.Role, certificateRoles)
+	}
+
+	for _, name := range append(req.Containers, req.Profiles...) {
+		if name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("Invalid resource name '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+// certificateFromRequest returns the trusted certificate used for a request,
+// or nil for local users.
+func certificateFromRequest(d *Daemon, r *http.Request) *db.CertInfo {
+	if r.TLS == nil {
+		return nil
+	}
+
+	for _, peer := range r.TLS.PeerCertificates {
+		cert := d.clientCertsInfo[shared.CertFingerprint(peer)]
+		if cert != nil {
+			return cert
+		}
+	}
+
+	return nil
+}
+
+// certificateIsRestricted returns whether the certificate is limited to some
+// containers or profiles.
+func certificateIsRestricted(cert *db.CertInfo) bool {
+	return len(cert.Containers) > 0 || len(cert.Profiles) > 0
+}
+
+// certificateIsFullAdmin returns whether the client behind a request has
+// unrestricted administrative access, as local users do.
+func certificateIsFullAdmin(d *Daemon, r *http.Request) bool {
+	cert := certificateFromRequest(d, r)
+	if cert == nil {
+		return true
+	}
+
+	return shared.StringInSlice(cert.Role, []string{"", "admin"}) && !certificateIsRestricted(cert)
+}
+
+// certificateAllowsContainer returns whether the certificate may access the
+// given container or snapshot.
+func certificateAllowsContainer(cert *db.CertInfo, name string) bool {
+	if !certificateIsRestricted(cert) {
+		return true
+	}
+
+	fields := strings.SplitN(name, shared.SnapshotDelimiter, 2)
+	return shared.StringInSlice(fields[0], cert.Containers)
+}
+
+// certificateAllowsProfile returns whether the certificate may access the
+// given profile.
+func certificateAllowsProfile(cert *db.CertInfo, name string) bool {
+	if !certificateIsRestricted(cert) {
+		return true
+	}
+
+	return shared.StringInSlice(name, cert.Profiles)
+}
+
+// certificateAllowsRead returns whether the certificate, nil for local users,
+// may read the given container or profile. Restricted certificates are only
+// limited in the collections they were given resources of.
+func certificateAllowsRead(cert *db.CertInfo, collection string, name string) bool {
+	if cert == nil {
+		return true
+	}
+
+	switch collection {
+	case "containers":
+		return len(cert.Containers) == 0 || certificateAllowsContainer(cert, name)
+	case "profiles":
+		return len(cert.Profiles) == 0 || certificateAllowsProfile(cert, name)
+	}
+
+	return true
+}
+
+// certificateAccessAllowed checks whether the role and restrictions of the
+// trusted certificate used for a request permit it.
+func certificateAccessAllowed(d *Daemon, r *http.Request, c Command) bool {
+	// Local users aren't subject to roles
+	cert := certificateFromRequest(d, r)
+	if cert == nil {
+		return true
+	}
+
+	switch cert.Role {
+	case "read-only":
+		if r.Method != "GET" {
+			return false
+		}
+	case "operator":
+		if r.Method != "GET" && !shared.StringInSlice(c.name, certificateOperatorCommands) {
+			return false
+		}
+	}
+
+	if !certificateIsRestricted(cert) {
+		return true
+	}
+
+	// Whether the request targets one of the listed resources of a collection
+	listed := func(collection string, allowed []string) bool {
+		if c.name != collection+"/{name}" && !strings.HasPrefix(c.name, collection+"/{name}/") {
+			return false
+		}
+
+		return shared.StringInSlice(mux.Vars(r)["name"], allowed)
+	}
+
+	// Restricted certificates may read anything but the resources they
+	// weren't given in a restricted collection, the listing of which is
+	// filtered by its handler
+	if r.Method == "GET" {
+		for collection, allowed := range map[string][]string{"containers": cert.Containers, "profiles": cert.Profiles} {
+			if len(allowed) == 0 {
+				continue
+			}
+
+			if c.name != collection && !listed(collection, allowed) && strings.HasPrefix(c.name, collection+"/") {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	// Operations are filtered by their handlers
+	if strings.HasPrefix(c.name, "operations/") {
+		return true
+	}
+
+	// Anything else may only be done to the listed resources
+	return listed("containers", cert.Containers) || listed("profiles", cert.Profiles)
+}
+
 func readSavedClientCAList(d *Daemon) {
 	d.clientCerts = []x509.Certificate{}
+	d.clientCertsInfo = map[string]*db.CertInfo{}
 
 	dbCerts, err := db.CertsGet(d.db)
 	if err != nil {
@@ -74,14 +249,22 @@ func readSavedClientCAList(d *Daemon) {
 			continue
 		}
 		d.clientCerts = append(d.clientCerts, *cert)
+		d.clientCertsInfo[dbCert.Fingerprint] = dbCert
 	}
 }
 
-func saveCert(dbObj *sql.DB, host string, cert *x509.Certificate) error {
+func saveCert(dbObj *sql.DB, host string, cert *x509.Certificate, role string, containers []string, profiles []string) error {
+	if role == "" {
+		role = "admin"
+	}
+
 	baseCert := new(db.CertInfo)
 	baseCert.Fingerprint = shared.CertFingerprint(cert)
 	baseCert.Type = 1
 	baseCert.Name = host
+	baseCert.Role = role
+	baseCert.Containers = containers
+	baseCert.Profiles = profiles
 	baseCert.Certificate = string(
 		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
 	)
@@ -96,14 +279,19 @@ func certificatesPost(d *Daemon, r *http.Request) Response {
 		return BadRequest(err)
 	}
 
-	// Access check
+	// Access check, only unrestricted administrators may add certificates
 	secret := daemonConfig["core.trust_password"].Get()
-	if !util.IsTrustedClient(r, d.clientCerts) && util.PasswordCheck(secret, req.Password) != nil {
+	if util.IsTrustedClient(r, d.clientCerts) {
+		if !certificateIsFullAdmin(d, r) {
+			return Forbidden
+		}
+	} else if util.PasswordCheck(secret, req.Password) != nil {
 		return Forbidden
 	}
 
-	if req.Type != "client" {
-		return BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+	err := certificateValidate(req.CertificatePut)
+	if err != nil {
+		return BadRequest(err)
 	}
 
 	// Extract the certificate
@@ -143,12 +331,12 @@ func certificatesPost(d *Daemon, r *http.Request) Response {
 		}
 	}
 
-	err := saveCert(d.db, name, cert)
+	err = saveCert(d.db, name, cert, req.Role, req.Containers, req.Profiles)
 	if err != nil {
 		return SmartError(err)
 	}
 
-	d.clientCerts = append(d.clientCerts, *cert)
+	readSavedClientCAList(d)
 
 	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 }
@@ -182,21 +370,53 @@ func doCertificateGet(dbObj *sql.DB, fingerprint string) (api.Certificate, error
 		return resp, err
 	}
 
-	resp.Fingerprint = dbCertInfo.Fingerprint
-	resp.Certificate = dbCertInfo.Certificate
-	resp.Name = dbCertInfo.Name
-	if dbCertInfo.Type == 1 {
-		resp.Type = "client"
-	} else {
-		resp.Type = "unknown"
+	return certificateToAPI(dbCertInfo), nil
+}
+
+func certificateFingerprintPut(d *Daemon, r *http.Request) Response {
+	fingerprint := mux.Vars(r)["fingerprint"]
+
+	// Only unrestricted administrators may change certificates
+	if !certificateIsFullAdmin(d, r) {
+		return Forbidden
+	}
+
+	certInfo, err := db.CertGet(d.db, fingerprint)
+	if err != nil {
+		return NotFound
 	}
 
-	return resp, nil
+	req := api.CertificatePut{}
+	if err := shared.ReadToJSON(r.Body, &req); err != nil {
+		return BadRequest(err)
+	}
+
+	err = certificateValidate(req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Role == "" {
+		req.Role = "admin"
+	}
+
+	err = db.CertUpdate(d.db, certInfo.Fingerprint, req.Name, req.Role, req.Containers, req.Profiles)
+	if err != nil {
+		return SmartError(err)
+	}
+	readSavedClientCAList(d)
+
+	return EmptySyncResponse
 }
 
 func certificateFingerprintDelete(d *Daemon, r *http.Request) Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
+	// Only unrestricted administrators may remove certificates
+	if !certificateIsFullAdmin(d, r) {
+		return Forbidden
+	}
+
 	certInfo, err := db.CertGet(d.db, fingerprint)
 	if err != nil {
 		return NotFound
@@ -216,7 +436,7 @@ var certificateFingerprintCmd = Command{
 	false,
 	false,
 	certificateFingerprintGet,
-	nil,
+	certificateFingerprintPut,
 	nil,
 	certificateFingerprintDelete,
 }
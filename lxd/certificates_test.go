@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// certificateAccessCheck routes a request through a router so that the path
+// variables are set, and reports whether the given certificate may issue it.
+func certificateAccessCheck(cert *db.CertInfo, c Command, method string, url string) bool {
+	peer := &x509.Certificate{Raw: []byte(cert.Name)}
+
+	d := &Daemon{clientCertsInfo: map[string]*db.CertInfo{shared.CertFingerprint(peer): cert}}
+
+	uri := "/1.0"
+	if c.name != "" {
+		uri = "/1.0/" + c.name
+	}
+
+	allowed := false
+	router := mux.NewRouter()
+	router.HandleFunc(uri, func(w http.ResponseWriter, r *http.Request) {
+		allowed = certificateAccessAllowed(d, r, c)
+	})
+
+	r := httptest.NewRequest(method, url, nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{peer}}
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	return allowed
+}
+
+func TestCertificateAccessAllowedRoles(t *testing.T) {
+	admin := &db.CertInfo{Name: "admin", Role: "admin"}
+	assert.True(t, certificateAccessCheck(admin, api10Cmd, "PUT", "/1.0"))
+	assert.True(t, certificateAccessCheck(admin, containerCmd, "DELETE", "/1.0/containers/c1"))
+
+	readonly := &db.CertInfo{Name: "read-only", Role: "read-only"}
+	assert.True(t, certificateAccessCheck(readonly, containerCmd, "GET", "/1.0/containers/c1"))
+	assert.False(t, certificateAccessCheck(readonly, containerStateCmd, "PUT", "/1.0/containers/c1/state"))
+
+	operator := &db.CertInfo{Name: "operator", Role: "operator"}
+	assert.True(t, certificateAccessCheck(operator, containerExecCmd, "POST", "/1.0/containers/c1/exec"))
+	assert.True(t, certificateAccessCheck(operator, containerStateCmd, "PUT", "/1.0/containers/c1/state"))
+	assert.False(t, certificateAccessCheck(operator, containerCmd, "DELETE", "/1.0/containers/c1"))
+	assert.False(t, certificateAccessCheck(operator, api10Cmd, "PUT", "/1.0"))
+}
+
+func TestCertificateAccessAllowedRestrictions(t *testing.T) {
+	cert := &db.CertInfo{Name: "ci", Role: "operator", Containers: []string{"c1"}}
+
+	assert.True(t, certificateAccessCheck(cert, containerExecCmd, "POST", "/1.0/containers/c1/exec"))
+	assert.False(t, certificateAccessCheck(cert, containerExecCmd, "POST", "/1.0/containers/c2/exec"))
+	assert.False(t, certificateAccessCheck(cert, containerCmd, "GET", "/1.0/containers/c2"))
+	assert.True(t, certificateAccessCheck(cert, containersCmd, "GET", "/1.0/containers"))
+	assert.False(t, certificateAccessCheck(cert, containersCmd, "POST", "/1.0/containers"))
+
+	// Profiles aren't restricted when only containers are listed
+	assert.True(t, certificateAccessCheck(cert, profileCmd, "GET", "/1.0/profiles/default"))
+}
+
+func TestCertificateAccessAllowedRestrictedWrites(t *testing.T) {
+	cert := &db.CertInfo{Name: "ci", Role: "admin", Containers: []string{"c1"}}
+
+	assert.True(t, certificateAccessCheck(cert, containerCmd, "PUT", "/1.0/containers/c1"))
+	assert.True(t, certificateAccessCheck(cert, operationCmd, "DELETE", "/1.0/operations/1234"))
+	assert.False(t, certificateAccessCheck(cert, api10Cmd, "PUT", "/1.0"))
+	assert.False(t, certificateAccessCheck(cert, certificatesCmd, "POST", "/1.0/certificates"))
+	assert.False(t, certificateAccessCheck(cert, certificateFingerprintCmd, "PUT", "/1.0/certificates/abcd"))
+	assert.False(t, certificateAccessCheck(cert, imagesCmd, "POST", "/1.0/images"))
+	assert.False(t, certificateAccessCheck(cert, networksCmd, "POST", "/1.0/networks"))
+	assert.False(t, certificateAccessCheck(cert, storagePoolCmd, "PUT", "/1.0/storage-pools/default"))
+	assert.False(t, certificateAccessCheck(cert, profileCmd, "PUT", "/1.0/profiles/default"))
+}
+
+func TestCertificateFingerprintPutRestricted(t *testing.T) {
+	peer := &x509.Certificate{Raw: []byte("ci")}
+	cert := &db.CertInfo{Name: "ci", Role: "admin", Fingerprint: shared.CertFingerprint(peer), Containers: []string{"c1"}}
+	d := &Daemon{clientCertsInfo: map[string]*db.CertInfo{cert.Fingerprint: cert}}
+
+	r := httptest.NewRequest("PUT", "/1.0/certificates/"+cert.Fingerprint, nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{peer}}
+
+	assert.Equal(t, Forbidden, certificateFingerprintPut(d, r))
+	assert.Equal(t, Forbidden, certificateFingerprintDelete(d, r))
+}
+
+func TestCertificateOperationsFiltered(t *testing.T) {
+	peer := &x509.Certificate{Raw: []byte("ci")}
+	other := &x509.Certificate{Raw: []byte("other")}
+	cert := &db.CertInfo{Name: "ci", Role: "operator", Fingerprint: shared.CertFingerprint(peer), Containers: []string{"c1"}}
+	otherCert := &db.CertInfo{Name: "other", Role: "operator", Fingerprint: shared.CertFingerprint(other)}
+	d := &Daemon{clientCertsInfo: map[string]*db.CertInfo{cert.Fingerprint: cert, otherCert.Fingerprint: otherCert}}
+
+	request := func(peer *x509.Certificate) *http.Request {
+		r := httptest.NewRequest("GET", "/1.0/operations", nil)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{peer}}
+		return r
+	}
+
+	exec := func(container string, requestor string) *operation {
+		return &operation{
+			class:     operationClassWebsocket,
+			resources: map[string][]string{"containers": {container}},
+			metadata:  map[string]interface{}{"fds": map[string]string{"0": "secret"}},
+			requestor: requestor,
+		}
+	}
+
+	// Restricted certificates only see the operations of their containers
+	assert.True(t, operationVisible(d, request(peer), exec("c1", "")))
+	assert.True(t, operationVisible(d, request(peer), exec("c1/snap0", "")))
+	assert.False(t, operationVisible(d, request(peer), exec("c2", "")))
+	assert.False(t, operationVisible(d, request(peer), &operation{class: operationClassTask}))
+	assert.True(t, operationVisible(d, request(other), exec("c2", "")))
+
+	// Secrets are only shown to the creator of the operation
+	body, err := operationRender(d, request(peer), exec("c1", cert.Fingerprint))
+	assert.Nil(t, err)
+	assert.Contains(t, body.Metadata, "fds")
+
+	body, err = operationRender(d, request(other), exec("c1", cert.Fingerprint))
+	assert.Nil(t, err)
+	assert.NotContains(t, body.Metadata, "fds")
+
+	// Local users see everything
+	local := httptest.NewRequest("GET", "/1.0/operations", nil)
+	body, err = operationRender(d, local, exec("c1", cert.Fingerprint))
+	assert.Nil(t, err)
+	assert.Contains(t, body.Metadata, "fds")
+}
+
+func TestCertificateCollectionsFiltered(t *testing.T) {
+	d := NewDaemon()
+	err := initializeDbObject(d, ":memory:")
+	assert.Nil(t, err)
+
+	_, err = d.db.Exec(`
+    INSERT INTO containers (name, architecture, type) VALUES ('c1', 1, 0);
+    INSERT INTO containers (name, architecture, type) VALUES ('c2', 1, 0);
+    INSERT INTO profiles (name) VALUES ('ci');`)
+	assert.Nil(t, err)
+
+	peer := &x509.Certificate{Raw: []byte("ci")}
+	cert := &db.CertInfo{Name: "ci", Role: "read-only", Fingerprint: shared.CertFingerprint(peer), Containers: []string{"c1"}, Profiles: []string{"ci"}}
+	d.clientCertsInfo = map[string]*db.CertInfo{cert.Fingerprint: cert}
+
+	// Restricted certificates only list their containers and profiles
+	containers, err := doContainersGet(d.State(), d.Storage, false, cert)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/1.0/containers/c1"}, containers)
+
+	r := httptest.NewRequest("GET", "/1.0/profiles?recursion=1", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{peer}}
+	profiles := profilesGet(d, r).(*syncResponse).metadata.([]*api.Profile)
+	assert.Len(t, profiles, 1)
+	assert.Equal(t, "ci", profiles[0].Name)
+
+	// Unrestricted clients list everything
+	containers, err = doContainersGet(d.State(), d.Storage, false, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/1.0/containers/c1", "/1.0/containers/c2"}, containers)
+}
@@ -186,6 +186,25 @@ func containerValidConfigKey(key string, value string) error {
 		return nil
 	case "security.privileged":
 		return isBool(key, value)
+	case "snapshots.schedule":
+		if value == "" {
+			return nil
+		}
+
+		_, err := snapshotScheduleParse(value)
+		return err
+	case "snapshots.schedule.stopped":
+		return isBool(key, value)
+	case "snapshots.pattern":
+		if value == "" {
+			return nil
+		}
+
+		_, err := snapshotPatternRender(value, time.Now())
+		return err
+	case "snapshots.expiry":
+		_, err := snapshotExpiryDate(value, time.Now())
+		return err
 	case "security.nesting":
 		return isBool(key, value)
 	case "security.idmap.base":
@@ -200,6 +219,14 @@ func containerValidConfigKey(key string, value string) error {
 		return nil
 	case "raw.lxc":
 		return lxcValidConfig(value)
+	case "raw.seccomp":
+		return nil
+	case "security.syscalls.blacklist":
+		return nil
+	case "security.syscalls.blacklist_compat":
+		return isBool(key, value)
+	case "security.syscalls.whitelist":
+		return nil
 	case "volatile.apply_template":
 		return nil
 	case "volatile.base_image":
@@ -299,6 +326,8 @@ func containerValidDeviceConfigKey(t, k string) bool {
 			return true
 		case "path":
 			return true
+		case "pool":
+			return true
 		case "readonly":
 			return true
 		case "size":
@@ -310,6 +339,51 @@ func containerValidDeviceConfigKey(t, k string) bool {
 		default:
 			return false
 		}
+	case "gpu":
+		switch k {
+		case "vendorid":
+			return true
+		case "productid":
+			return true
+		case "id":
+			return true
+		case "pci":
+			return true
+		case "mode":
+			return true
+		case "gid":
+			return true
+		case "uid":
+			return true
+		default:
+			return false
+		}
+	case "usb":
+		switch k {
+		case "vendorid":
+			return true
+		case "productid":
+			return true
+		case "mode":
+			return true
+		case "gid":
+			return true
+		case "uid":
+			return true
+		case "required":
+			return true
+		default:
+			return false
+		}
+	case "proxy":
+		switch k {
+		case "listen":
+			return true
+		case "connect":
+			return true
+		default:
+			return false
+		}
 	case "none":
 		return false
 	default:
@@ -333,6 +407,10 @@ func containerValidConfig(os *sys.OS, config map[string]string, profile bool, ex
 		}
 	}
 
+	if config["security.syscalls.whitelist"] != "" && (config["security.syscalls.blacklist"] != "" || shared.IsTrue(config["security.syscalls.blacklist_compat"])) {
+		return fmt.Errorf("security.syscalls.whitelist is mutually exclusive with security.syscalls.blacklist*")
+	}
+
 	if expanded && (config["security.privileged"] == "" || !shared.IsTrue(config["security.privileged"])) && os.IdmapSet == nil {
 		return fmt.Errorf("LXD doesn't have a uid/gid allocation. In this mode, only privileged containers are supported.")
 	}
@@ -383,7 +461,7 @@ func containerValidDevices(devices types.Devices, profile bool, expanded bool) e
 			return fmt.Errorf("Missing device type for device '%s'", name)
 		}
 
-		if !shared.StringInSlice(m["type"], []string{"none", "nic", "disk", "unix-char", "unix-block"}) {
+		if !shared.StringInSlice(m["type"], []string{"none", "nic", "disk", "unix-char", "unix-block", "gpu", "usb", "proxy"}) {
 			return fmt.Errorf("Invalid device type for device '%s'", name)
 		}
 
@@ -424,11 +502,15 @@ func containerValidDevices(devices types.Devices, profile bool, expanded bool) e
 				return fmt.Errorf("Root disk entry may not have a \"source\" property set.")
 			}
 
+			if m["pool"] != "" && m["path"] != "/" && strings.Contains(m["source"], "/") {
+				return fmt.Errorf("Storage volume disk entries must use a volume name as their \"source\" property.")
+			}
+
 			if m["size"] != "" && m["path"] != "/" {
 				return fmt.Errorf("Only the root disk may have a size quota.")
 			}
 
-			if (m["path"] == "/" || !shared.IsDir(m["source"])) && m["recursive"] != "" {
+			if (m["path"] == "/" || m["pool"] != "" || !shared.IsDir(m["source"])) && m["recursive"] != "" {
 				return fmt.Errorf("The recursive option is only supported for additional bind-mounted paths.")
 			}
 		} else if shared.StringInSlice(m["type"], []string{"unix-char", "unix-block"}) {
@@ -454,7 +536,29 @@ func containerValidDevices(devices types.Devices, profile bool, expanded bool) e
 					return fmt.Errorf("Path specified for unix-block device is a character device.")
 				}
 			}
-		} else if m["type"] == "none" {
+		} else if m["type"] == "usb" {
+			if m["vendorid"] == "" {
+				return fmt.Errorf("Missing vendorid for USB device.")
+			}
+		} else if m["type"] == "proxy" {
+			if m["listen"] == "" || m["connect"] == "" {
+				return fmt.Errorf("Proxy device entry is missing the required \"listen\" or \"connect\" property.")
+			}
+
+			listenProto, _, err := proxyParseAddr(m["listen"])
+			if err != nil {
+				return err
+			}
+
+			connectProto, _, err := proxyParseAddr(m["connect"])
+			if err != nil {
+				return err
+			}
+
+			if (listenProto == "udp") != (connectProto == "udp") {
+				return fmt.Errorf("Proxy devices can only forward udp to udp.")
+			}
+		} else if shared.StringInSlice(m["type"], []string{"gpu", "none"}) {
 			continue
 		} else {
 			return fmt.Errorf("Invalid device type: %s", m["type"])
@@ -502,9 +606,9 @@ type container interface {
 	ConfigKeySet(key string, value string) error
 
 	// File handling
-	FilePull(srcpath string, dstpath string) (int64, int64, os.FileMode, error)
+	FilePull(srcpath string, dstpath string, followSymlinks bool) (int64, int64, os.FileMode, string, []string, error)
 	FileExists(path string) error
-	FilePush(srcpath string, dstpath string, uid int64, gid int64, mode int) error
+	FilePush(type_ string, srcpath string, dstpath string, uid int64, gid int64, mode int, write string) error
 	FileRemove(path string) error
 
 	/* Command execution:
@@ -518,8 +622,15 @@ type container interface {
 	         *      be waited upon since it's a child of the lxd forkexec command
 	         *      (the PID returned in the first return argument). It can however
 	         *      be used to e.g. forward signals.)
+	 * The command runs with the given uid and gid, in the cwd directory (or
+	 * in $HOME if empty).
 	*/
-	Exec(command []string, env map[string]string, stdin *os.File, stdout *os.File, stderr *os.File, wait bool) (*exec.Cmd, int, int, error)
+	Exec(command []string, env map[string]string, stdin *os.File, stdout *os.File, stderr *os.File, wait bool, cwd string, uid uint32, gid uint32) (*exec.Cmd, int, int, error)
+
+	// Console returns the command attaching the given terminal to the
+	// container's console, it's the callers responsibility to start it
+	// and wait on it.
+	Console(terminal *os.File) *exec.Cmd
 
 	// Status
 	Render() (interface{}, error)
@@ -541,6 +652,8 @@ type container interface {
 	Name() string
 	Architecture() int
 	CreationDate() time.Time
+	Description() string
+	ExpiryDate() time.Time
 	ExpandedConfig() map[string]string
 	ExpandedDevices() types.Devices
 	LocalConfig() map[string]string
@@ -555,6 +668,7 @@ type container interface {
 	TemplatesPath() string
 	StatePath() string
 	LogFilePath() string
+	ConsoleLogPath() string
 	LogPath() string
 
 	// FIXME: Those should be internal functions
@@ -665,6 +779,79 @@ func containerCreateAsCopy(s *state.State, storage storage, args db.ContainerArg
 	return c, nil
 }
 
+// containerRefreshFromCopy syncs an existing container with the one it was
+// copied from. This goes through the same storage drivers as a migration, so
+// missing snapshots get transferred (as deltas where the storage supports it)
+// and the filesystem gets shifted to the container's idmap.
+func containerRefreshFromCopy(c container, sourceContainer container) error {
+	err := sourceContainer.StorageStart()
+	if err != nil {
+		return err
+	}
+	defer sourceContainer.StorageStop()
+
+	srcIdmap, err := sourceContainer.IdmapSet()
+	if err != nil {
+		return err
+	}
+
+	// Use the optimized transfer if both containers are on the same type
+	// of storage, rsync otherwise
+	sink := c.Storage().MigrationSink
+	driver, err := sourceContainer.Storage().MigrationSource(sourceContainer, false)
+	if sourceContainer.Storage().MigrationType() != c.Storage().MigrationType() {
+		sink = rsyncMigrationSink
+		driver, err = rsyncMigrationSource(sourceContainer, false)
+	}
+	if err != nil {
+		return err
+	}
+	defer driver.Cleanup()
+
+	// Only transfer the snapshots the container is missing
+	snapshots := []*Snapshot{}
+	for _, snap := range driver.Snapshots() {
+		snapshots = append(snapshots, snapshotToProtobuf(snap))
+	}
+
+	snapshots, err = migrationRefreshSnapshots(c, snapshots)
+	if err != nil {
+		return err
+	}
+
+	snapshotNames := []string{}
+	for _, snap := range snapshots {
+		snapshotNames = append(snapshotNames, snap.GetName())
+	}
+	driver.Refresh(snapshotNames)
+
+	srcConn, dstConn, err := migrationLocalConns()
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	defer dstConn.Close()
+
+	fsTransfer := make(chan error, 1)
+	go func() {
+		fsTransfer <- sink(false, c, snapshots, dstConn, srcIdmap)
+	}()
+
+	err = driver.SendWhileRunning(srcConn)
+	if err != nil {
+		srcConn.Close()
+		<-fsTransfer
+		return err
+	}
+
+	err = <-fsTransfer
+	if err != nil {
+		return err
+	}
+
+	return c.TemplateApply("copy")
+}
+
 func containerCreateAsSnapshot(s *state.State, storage storage, args db.ContainerArgs, sourceContainer container) (container, error) {
 	// Deal with state
 	if args.Stateful {
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+func containerBackupToAPI(backup db.ContainerBackupArgs) *api.ContainerBackup {
+	return &api.ContainerBackup{
+		Name:             backup.Name,
+		CreationDate:     backup.CreationDate,
+		ContainerOnly:    backup.ContainerOnly,
+		OptimizedStorage: backup.OptimizedStorage,
+	}
+}
+
+// containerBackupValidName makes sure the name of a backup can be used as a
+// file name.
+func containerBackupValidName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No backup name provided")
+	}
+
+	if strings.Contains(name, "/") || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("Invalid backup name '%s'", name)
+	}
+
+	return nil
+}
+
+func containerBackupsGet(d *Daemon, r *http.Request) Response {
+	recursionStr := r.FormValue("recursion")
+	recursion, err := strconv.Atoi(recursionStr)
+	if err != nil {
+		recursion = 0
+	}
+
+	cname := mux.Vars(r)["name"]
+	_, err = db.ContainerId(d.db, cname)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	names, err := db.ContainerGetBackups(d.db, cname)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []*api.ContainerBackup{}
+
+	for _, name := range names {
+		if recursion == 0 {
+			url := fmt.Sprintf("/%s/containers/%s/backups/%s", version.APIVersion, cname, name)
+			resultString = append(resultString, url)
+		} else {
+			backup, err := db.ContainerBackupGet(d.db, cname, name)
+			if err != nil {
+				continue
+			}
+
+			resultMap = append(resultMap, containerBackupToAPI(backup))
+		}
+	}
+
+	if recursion == 0 {
+		return SyncResponse(true, resultString)
+	}
+
+	return SyncResponse(true, resultMap)
+}
+
+func containerBackupsPost(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	c, err := containerLoadByName(d.State(), d.Storage, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	req := api.ContainerBackupsPost{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Name == "" {
+		// come up with a name
+		backups, err := db.ContainerGetBackups(d.db, name)
+		if err != nil {
+			return SmartError(err)
+		}
+
+		for i := 0; ; i++ {
+			req.Name = fmt.Sprintf("backup%d", i)
+			if !shared.StringInSlice(req.Name, backups) {
+				break
+			}
+		}
+	}
+
+	err = containerBackupValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	_, err = db.ContainerBackupGet(d.db, name, req.Name)
+	if err == nil {
+		return Conflict
+	}
+
+	if req.OptimizedStorage && c.Storage().MigrationType() == MigrationFSType_RSYNC {
+		return BadRequest(fmt.Errorf("Optimized backups aren't supported by the %s storage backend", c.Storage().GetStorageTypeName()))
+	}
+
+	backup := func(op *operation) error {
+		args := db.ContainerBackupArgs{
+			ContainerID:      c.Id(),
+			Name:             req.Name,
+			CreationDate:     time.Now().UTC(),
+			ContainerOnly:    req.ContainerOnly,
+			OptimizedStorage: req.OptimizedStorage,
+		}
+
+		err := backupCreate(c, args)
+		if err != nil {
+			os.Remove(backupPath(name, req.Name))
+			return err
+		}
+
+		_, err = db.ContainerBackupCreate(d.db, args)
+		if err != nil {
+			os.Remove(backupPath(name, req.Name))
+			return err
+		}
+
+		eventSendLifecycle("container-backup-created", fmt.Sprintf("/%s/containers/%s/backups/%s", version.APIVersion, name, req.Name),
+			map[string]interface{}{"container": name}, r)
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{name}
+	resources["backups"] = []string{req.Name}
+
+	op, err := operationCreate(operationClassTask, resources, nil, backup, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func containerBackupHandler(d *Daemon, r *http.Request) Response {
+	containerName := mux.Vars(r)["name"]
+	backupName := mux.Vars(r)["backupName"]
+
+	backup, err := db.ContainerBackupGet(d.db, containerName, backupName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	switch r.Method {
+	case "GET":
+		return SyncResponse(true, containerBackupToAPI(backup))
+	case "POST":
+		return containerBackupPost(d, r, backup, containerName)
+	case "DELETE":
+		return containerBackupDelete(d, r, backup, containerName)
+	default:
+		return NotFound
+	}
+}
+
+func containerBackupPost(d *Daemon, r *http.Request, backup db.ContainerBackupArgs, containerName string) Response {
+	req := api.ContainerBackupPost{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	err := containerBackupValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Check that the name isn't already in use
+	_, err = db.ContainerBackupGet(d.db, containerName, req.Name)
+	if err == nil {
+		return Conflict
+	}
+
+	rename := func(op *operation) error {
+		err := os.Rename(backupPath(containerName, backup.Name), backupPath(containerName, req.Name))
+		if err != nil {
+			return err
+		}
+
+		err = db.ContainerBackupRename(d.db, backup.ID, req.Name)
+		if err != nil {
+			return err
+		}
+
+		eventSendLifecycle("container-backup-renamed", fmt.Sprintf("/%s/containers/%s/backups/%s", version.APIVersion, containerName, backup.Name),
+			map[string]interface{}{"container": containerName, "new_name": req.Name}, r)
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{containerName}
+
+	op, err := operationCreate(operationClassTask, resources, nil, rename, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func containerBackupDelete(d *Daemon, r *http.Request, backup db.ContainerBackupArgs, containerName string) Response {
+	remove := func(op *operation) error {
+		err := os.Remove(backupPath(containerName, backup.Name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		err = db.ContainerBackupRemove(d.db, backup.ID)
+		if err != nil {
+			return err
+		}
+
+		eventSendLifecycle("container-backup-deleted", fmt.Sprintf("/%s/containers/%s/backups/%s", version.APIVersion, containerName, backup.Name),
+			map[string]interface{}{"container": containerName}, r)
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{containerName}
+
+	op, err := operationCreate(operationClassTask, resources, nil, remove, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func containerBackupExportGet(d *Daemon, r *http.Request) Response {
+	containerName := mux.Vars(r)["name"]
+	backupName := mux.Vars(r)["backupName"]
+
+	backup, err := db.ContainerBackupGet(d.db, containerName, backupName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	files := []fileResponseEntry{{
+		identifier: backup.Name,
+		path:       backupPath(containerName, backup.Name),
+		filename:   fmt.Sprintf("%s.tar.gz", backup.Name),
+	}}
+
+	return FileResponse(r, files, nil, false)
+}
+
+// createFromBackup creates a container from a backup tarball sent as the
+// body of the request.
+func createFromBackup(d *Daemon, r *http.Request, data io.Reader) Response {
+	err := os.MkdirAll(shared.VarPath("backups"), 0700)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	f, err := ioutil.TempFile(shared.VarPath("backups"), ".import_")
+	if err != nil {
+		return InternalError(err)
+	}
+
+	_, err = io.Copy(f, data)
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return InternalError(err)
+	}
+
+	info, err := backupGetInfo(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return BadRequest(err)
+	}
+
+	_, err = db.ContainerId(d.db, info.Name)
+	if err == nil {
+		os.Remove(f.Name())
+		return BadRequest(fmt.Errorf("Container '%s' already exists", info.Name))
+	}
+
+	run := func(op *operation) error {
+		defer os.Remove(f.Name())
+
+		_, err := backupLoad(d, info, f.Name())
+		return err
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{info.Name}
+
+	op, err := operationCreate(operationClassTask, resources, nil, containerCreateNotify(r, info.Name, run), nil, nil)
+	if err != nil {
+		os.Remove(f.Name())
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+type consoleWs struct {
+	container container
+
+	rootUid          int64
+	rootGid          int64
+	conns            map[int]*websocket.Conn
+	connsLock        sync.Mutex
+	allConnected     chan bool
+	controlConnected chan bool
+	fds              map[int]string
+	width            int
+	height           int
+}
+
+func (s *consoleWs) Metadata() interface{} {
+	fds := shared.Jmap{}
+	for fd, secret := range s.fds {
+		if fd == -1 {
+			fds["control"] = secret
+		} else {
+			fds[strconv.Itoa(fd)] = secret
+		}
+	}
+
+	return shared.Jmap{"fds": fds}
+}
+
+func (s *consoleWs) Connect(op *operation, r *http.Request, w http.ResponseWriter) error {
+	secret := r.FormValue("secret")
+	if secret == "" {
+		return fmt.Errorf("missing secret")
+	}
+
+	for fd, fdSecret := range s.fds {
+		if secret == fdSecret {
+			conn, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return err
+			}
+
+			s.connsLock.Lock()
+			s.conns[fd] = conn
+			s.connsLock.Unlock()
+
+			if fd == -1 {
+				s.controlConnected <- true
+				return nil
+			}
+
+			s.allConnected <- true
+			return nil
+		}
+	}
+
+	/* If we didn't find the right secret, the user provided a bad one,
+	 * which 403, not 404, since this operation actually exists */
+	return os.ErrPermission
+}
+
+func (s *consoleWs) Do(op *operation) error {
+	<-s.allConnected
+
+	master, slave, err := shared.OpenPty(s.rootUid, s.rootGid)
+	if err != nil {
+		return err
+	}
+
+	if s.width > 0 && s.height > 0 {
+		shared.SetSize(int(master.Fd()), s.width, s.height)
+	}
+
+	consCmd := s.container.Console(slave)
+	err = consCmd.Start()
+	if err != nil {
+		master.Close()
+		slave.Close()
+		return err
+	}
+
+	controlExit := make(chan bool, 1)
+	consoleDead := make(chan bool, 1)
+	var wgEOF sync.WaitGroup
+
+	go func() {
+		select {
+		case <-s.controlConnected:
+			break
+
+		case <-controlExit:
+			return
+		}
+
+		for {
+			s.connsLock.Lock()
+			conn := s.conns[-1]
+			s.connsLock.Unlock()
+
+			_, r, err := conn.NextReader()
+			if err != nil {
+				logger.Debugf("Got error getting next reader %s", err)
+				break
+			}
+
+			buf, err := ioutil.ReadAll(r)
+			if err != nil {
+				logger.Debugf("Failed to read message %s", err)
+				break
+			}
+
+			command := api.ContainerConsoleControl{}
+
+			if err := json.Unmarshal(buf, &command); err != nil {
+				logger.Debugf("Failed to unmarshal control socket command: %s", err)
+				continue
+			}
+
+			if command.Command == "window-resize" {
+				winchWidth, err := strconv.Atoi(command.Args["width"])
+				if err != nil {
+					logger.Debugf("Unable to extract window width: %s", err)
+					continue
+				}
+
+				winchHeight, err := strconv.Atoi(command.Args["height"])
+				if err != nil {
+					logger.Debugf("Unable to extract window height: %s", err)
+					continue
+				}
+
+				err = shared.SetSize(int(master.Fd()), winchWidth, winchHeight)
+				if err != nil {
+					logger.Debugf("Failed to set window size to: %dx%d", winchWidth, winchHeight)
+					continue
+				}
+			}
+		}
+	}()
+
+	wgEOF.Add(1)
+	go func() {
+		s.connsLock.Lock()
+		conn := s.conns[0]
+		s.connsLock.Unlock()
+
+		logger.Debugf("Starting to mirror websocket")
+		readDone, writeDone := shared.WebsocketExecMirror(conn, master, master, consoleDead, int(master.Fd()))
+
+		// The client detaches by closing the websocket
+		<-writeDone
+		consCmd.Process.Kill()
+
+		<-readDone
+		logger.Debugf("Finished to mirror websocket")
+
+		conn.Close()
+		wgEOF.Done()
+	}()
+
+	err = consCmd.Wait()
+	slave.Close()
+	consoleDead <- true
+
+	s.connsLock.Lock()
+	conn := s.conns[-1]
+	s.connsLock.Unlock()
+
+	if conn == nil {
+		controlExit <- true
+	} else {
+		conn.Close()
+	}
+
+	wgEOF.Wait()
+	master.Close()
+
+	// Being killed on detach is the normal way for the console to end
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func containerConsolePost(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+	c, err := containerLoadByName(d.State(), d.Storage, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if !c.IsRunning() {
+		return BadRequest(fmt.Errorf("Container is not running."))
+	}
+
+	if c.IsFrozen() {
+		return BadRequest(fmt.Errorf("Container is frozen."))
+	}
+
+	post := api.ContainerConsolePost{}
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if err := json.Unmarshal(buf, &post); err != nil {
+		return BadRequest(err)
+	}
+
+	ws := &consoleWs{}
+	ws.fds = map[int]string{}
+
+	idmapset, err := c.IdmapSet()
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if idmapset != nil {
+		ws.rootUid, ws.rootGid = idmapset.ShiftIntoNs(0, 0)
+	}
+
+	ws.conns = map[int]*websocket.Conn{}
+	ws.conns[-1] = nil
+	ws.conns[0] = nil
+	ws.allConnected = make(chan bool, 1)
+	ws.controlConnected = make(chan bool, 1)
+	for i := -1; i < len(ws.conns)-1; i++ {
+		ws.fds[i], err = shared.RandomCryptoString()
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	ws.container = c
+	ws.width = post.Width
+	ws.height = post.Height
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{ws.container.Name()}
+
+	op, err := operationCreate(operationClassWebsocket, resources, ws.Metadata(), ws.Do, nil, ws.Connect)
+	if err != nil {
+		return InternalError(err)
+	}
+	op.SetRequestor(d, r)
+
+	return OperationResponse(op)
+}
+
+func containerConsoleLogGet(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+	c, err := containerLoadByName(d.State(), d.Storage, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	ent := fileResponseEntry{
+		path:     c.ConsoleLogPath(),
+		filename: "console.log",
+	}
+
+	// Nothing was ever written to the console
+	if !shared.PathExists(ent.path) {
+		ent.path = ""
+		ent.buffer = []byte{}
+	}
+
+	return FileResponse(r, []fileResponseEntry{ent}, nil, false)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,6 +19,7 @@ import (
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
 
 	log "gopkg.in/inconshreveable/log15.v2"
 )
@@ -26,6 +28,9 @@ type execWs struct {
 	command   []string
 	container container
 	env       map[string]string
+	cwd       string
+	uid       uint32
+	gid       uint32
 
 	rootUid          int64
 	rootGid          int64
@@ -288,7 +293,7 @@ func (s *execWs) Do(op *operation) error {
 		return cmdErr
 	}
 
-	cmd, _, attachedPid, err := s.container.Exec(s.command, s.env, stdin, stdout, stderr, false)
+	cmd, _, attachedPid, err := s.container.Exec(s.command, s.env, stdin, stdout, stderr, false, s.cwd, s.uid, s.gid)
 	if err != nil {
 		return err
 	}
@@ -343,6 +348,10 @@ func containerExecPost(d *Daemon, r *http.Request) Response {
 		return BadRequest(err)
 	}
 
+	if post.RecordOutput && post.WaitForWS {
+		return BadRequest(fmt.Errorf("Output can only be recorded without websockets"))
+	}
+
 	env := map[string]string{}
 
 	for k, v := range c.ExpandedConfig() {
@@ -366,16 +375,24 @@ func containerExecPost(d *Daemon, r *http.Request) Response {
 		}
 	}
 
-	// Set default value for HOME
-	_, ok = env["HOME"]
-	if !ok {
-		env["HOME"] = "/root"
-	}
+	if post.User == 0 {
+		// Set default value for HOME
+		_, ok = env["HOME"]
+		if !ok {
+			env["HOME"] = "/root"
+		}
 
-	// Set default value for USER
-	_, ok = env["USER"]
-	if !ok {
-		env["USER"] = "root"
+		// Set default value for USER
+		_, ok = env["USER"]
+		if !ok {
+			env["USER"] = "root"
+		}
+	} else if post.Cwd == "" {
+		// Set default value for the working directory
+		_, ok = env["HOME"]
+		if !ok {
+			post.Cwd = "/"
+		}
 	}
 
 	// Set default value for USER
@@ -417,6 +434,9 @@ func containerExecPost(d *Daemon, r *http.Request) Response {
 		ws.command = post.Command
 		ws.container = c
 		ws.env = env
+		ws.cwd = post.Cwd
+		ws.uid = post.User
+		ws.gid = post.Group
 
 		ws.width = post.Width
 		ws.height = post.Height
@@ -428,13 +448,45 @@ func containerExecPost(d *Daemon, r *http.Request) Response {
 		if err != nil {
 			return InternalError(err)
 		}
+		op.SetRequestor(d, r)
 
 		return OperationResponse(op)
 	}
 
 	run := func(op *operation) error {
-		_, cmdResult, _, cmdErr := c.Exec(post.Command, env, nil, nil, nil, true)
-		metadata := shared.Jmap{"return": cmdResult}
+		var stdout, stderr *os.File
+		var err error
+		metadata := shared.Jmap{}
+
+		if post.RecordOutput {
+			// Keep the output around, it can be retrieved through
+			// the container's logs once the command is done
+			stdout, err = os.OpenFile(filepath.Join(c.LogPath(), fmt.Sprintf("exec_%s.stdout", op.id)), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+			if err != nil {
+				return err
+			}
+			defer stdout.Close()
+
+			stderr, err = os.OpenFile(filepath.Join(c.LogPath(), fmt.Sprintf("exec_%s.stderr", op.id)), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+			if err != nil {
+				return err
+			}
+			defer stderr.Close()
+
+			metadata["output"] = shared.Jmap{
+				"1": fmt.Sprintf("/%s/containers/%s/logs/%s", version.APIVersion, c.Name(), filepath.Base(stdout.Name())),
+				"2": fmt.Sprintf("/%s/containers/%s/logs/%s", version.APIVersion, c.Name(), filepath.Base(stderr.Name())),
+			}
+
+			// Let clients find the output while the command is running
+			err = op.UpdateMetadata(metadata)
+			if err != nil {
+				logger.Error("error updating metadata for cmd", log.Ctx{"err": err, "cmd": post.Command})
+			}
+		}
+
+		_, cmdResult, _, cmdErr := c.Exec(post.Command, env, nil, stdout, stderr, true, post.Cwd, post.User, post.Group)
+		metadata["return"] = cmdResult
 
 		err = op.UpdateMetadata(metadata)
 		if err != nil {
@@ -30,6 +30,8 @@ func containerFileHandler(d *Daemon, r *http.Request) Response {
 		return containerFileGet(c, path, r)
 	case "POST":
 		return containerFilePut(c, path, r)
+	case "DELETE":
+		return containerFileDelete(c, path, r)
 	default:
 		return NotFound
 	}
@@ -49,9 +51,13 @@ func containerFileGet(c container, path string, r *http.Request) Response {
 	}
 	defer temp.Close()
 
-	// Pul the file from the container
-	uid, gid, mode, err := c.FilePull(path, temp.Name())
+	// Symlinks are followed unless the client asks for them
+	_, _, _, reqType, _ := shared.ParseLXDFileHeaders(r.Header)
+
+	// Pull the file from the container
+	uid, gid, mode, type_, dirEnts, err := c.FilePull(path, temp.Name(), reqType != "symlink")
 	if err != nil {
+		os.Remove(temp.Name())
 		return SmartError(err)
 	}
 
@@ -59,6 +65,24 @@ func containerFileGet(c container, path string, r *http.Request) Response {
 		"X-LXD-uid":  fmt.Sprintf("%d", uid),
 		"X-LXD-gid":  fmt.Sprintf("%d", gid),
 		"X-LXD-mode": fmt.Sprintf("%04o", mode),
+		"X-LXD-type": type_,
+	}
+
+	// Directories are returned as a list of their entries
+	if type_ == "directory" {
+		os.Remove(temp.Name())
+
+		if dirEnts == nil {
+			dirEnts = []string{}
+		}
+
+		return SyncResponseHeaders(true, dirEnts, headers)
+	}
+
+	// Files are returned as is, symlinks as the path they point to
+	if type_ != "file" && type_ != "symlink" {
+		os.Remove(temp.Name())
+		return InternalError(fmt.Errorf("Bad file type %s", type_))
 	}
 
 	// Make a file response struct
@@ -71,8 +95,20 @@ func containerFileGet(c container, path string, r *http.Request) Response {
 }
 
 func containerFilePut(c container, path string, r *http.Request) Response {
-	// Extract file ownership and mode from headers
-	uid, gid, mode, _, _ := shared.ParseLXDFileHeaders(r.Header)
+	// Extract file ownership, mode, type and write mode from headers
+	uid, gid, mode, type_, write := shared.ParseLXDFileHeaders(r.Header)
+
+	if !shared.StringInSlice(type_, []string{"file", "directory", "symlink"}) {
+		return BadRequest(fmt.Errorf("Bad file type %s", type_))
+	}
+
+	if !shared.StringInSlice(write, []string{"overwrite", "append"}) {
+		return BadRequest(fmt.Errorf("Bad file write mode %s", write))
+	}
+
+	if write == "append" && type_ != "file" {
+		return BadRequest(fmt.Errorf("Only regular files can be appended to"))
+	}
 
 	// Write file content to a tempfile
 	temp, err := ioutil.TempFile("", "lxd_forkputfile_")
@@ -90,10 +126,19 @@ func containerFilePut(c container, path string, r *http.Request) Response {
 	}
 
 	// Transfer the file into the container
-	err = c.FilePush(temp.Name(), path, uid, gid, mode)
+	err = c.FilePush(type_, temp.Name(), path, uid, gid, mode, write)
 	if err != nil {
 		return InternalError(err)
 	}
 
 	return EmptySyncResponse
 }
+
+func containerFileDelete(c container, path string, r *http.Request) Response {
+	err := c.FileRemove(path)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return EmptySyncResponse
+}
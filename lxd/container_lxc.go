@@ -33,6 +33,7 @@ import (
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/osarch"
+	"github.com/lxc/lxd/shared/version"
 
 	log "gopkg.in/inconshreveable/log15.v2"
 )
@@ -252,6 +253,8 @@ func containerLXCCreate(s *state.State, storage storage, args db.ContainerArgs)
 		cType:        args.Ctype,
 		stateful:     args.Stateful,
 		creationDate: args.CreationDate,
+		description:  args.Description,
+		expiryDate:   args.ExpiryDate,
 		profiles:     args.Profiles,
 		localConfig:  args.Config,
 		localDevices: args.Devices,
@@ -303,6 +306,18 @@ func containerLXCCreate(s *state.State, storage storage, args db.ContainerArgs)
 		}
 	}
 
+	// Use the storage pool referenced by the root disk
+	poolName := storagePoolForDevices(c.expandedDevices)
+	if poolName != "" {
+		storage, err = storageForPool(s, storage, poolName)
+		if err != nil {
+			c.Delete()
+			logger.Error("Failed creating container", ctxMap)
+			return nil, err
+		}
+		c.storage = storage
+	}
+
 	// Validate expanded config
 	err = containerValidConfig(s.OS, c.expandedConfig, false, true)
 	if err != nil {
@@ -387,6 +402,14 @@ func containerLXCCreate(s *state.State, storage storage, args db.ContainerArgs)
 		return nil, err
 	}
 
+	if !c.IsSnapshot() {
+		// Refresh the DHCP host entries of the managed networks
+		err = networkUpdateStatic(c.state)
+		if err != nil {
+			logger.Warn("Failed to update the network DHCP host entries", log.Ctx{"name": c.name, "err": err})
+		}
+	}
+
 	logger.Info("Created container", ctxMap)
 
 	return c, nil
@@ -402,23 +425,25 @@ func containerLXCLoad(s *state.State, storage storage, args db.ContainerArgs) (c
 		architecture: args.Architecture,
 		cType:        args.Ctype,
 		creationDate: args.CreationDate,
+		description:  args.Description,
+		expiryDate:   args.ExpiryDate,
 		profiles:     args.Profiles,
 		localConfig:  args.Config,
 		localDevices: args.Devices,
 		stateful:     args.Stateful}
 
-	// Detect the storage backend
-	storage, err := storageForFilename(s, storage, shared.VarPath("containers", strings.Split(c.name, "/")[0]))
+	// Load the config
+	err := c.init()
 	if err != nil {
 		return nil, err
 	}
-	c.storage = storage
 
-	// Load the config
-	err = c.init()
+	// Detect the storage backend
+	storage, err = storageForContainer(s, storage, c.name, c.expandedDevices)
 	if err != nil {
 		return nil, err
 	}
+	c.storage = storage
 
 	return c, nil
 }
@@ -429,7 +454,9 @@ type containerLXC struct {
 	architecture int
 	cType        db.ContainerType
 	creationDate time.Time
+	description  string
 	ephemeral    bool
+	expiryDate   time.Time
 	id           int
 	name         string
 	stateful     bool
@@ -993,6 +1020,24 @@ func (c *containerLXC) initLXC() error {
 		return err
 	}
 
+	err = lxcSetConfigItem(cc, "lxc.console.logfile", c.ConsoleLogPath())
+	if err != nil {
+		return err
+	}
+
+	// Keep the console output in a ring buffer and cap the size of the log
+	if lxc.VersionAtLeast(3, 0, 0) {
+		err = lxcSetConfigItem(cc, "lxc.console.buffer.size", "auto")
+		if err != nil {
+			return err
+		}
+
+		err = lxcSetConfigItem(cc, "lxc.console.size", "auto")
+		if err != nil {
+			return err
+		}
+	}
+
 	// Setup the hostname
 	err = lxcSetConfigItem(cc, "lxc.uts.name", c.Name())
 	if err != nil {
@@ -1547,6 +1592,15 @@ func (c *containerLXC) startCommon() (string, error) {
 		}
 	}
 
+	// Older liblxc can't cap the size of the console log, so only keep
+	// the output of the current boot
+	if !lxc.VersionAtLeast(3, 0, 0) {
+		err = os.Truncate(c.ConsoleLogPath(), 0)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
 	// Load any required kernel modules
 	kernelModules := c.expandedConfig["linux.kernel_modules"]
 	if kernelModules != "" {
@@ -1653,23 +1707,39 @@ func (c *containerLXC) startCommon() (string, error) {
 	// Create the devices
 	for _, k := range c.expandedDevices.DeviceNames() {
 		m := c.expandedDevices[k]
-		if shared.StringInSlice(m["type"], []string{"unix-char", "unix-block"}) {
-			// Unix device
-			devPath, err := c.createUnixDevice(m)
+		if shared.StringInSlice(m["type"], []string{"unix-char", "unix-block", "gpu", "usb"}) {
+			unixDevices, err := deviceUnixDevices(m)
 			if err != nil {
 				return "", err
 			}
 
-			if c.IsPrivileged() && !runningInUserns && cgDevicesController {
-				// Add the new device cgroup rule
-				dType, dMajor, dMinor, err := deviceGetAttributes(devPath)
+			for _, d := range unixDevices {
+				// Unix device
+				devPath, err := c.createUnixDevice(d)
 				if err != nil {
 					return "", err
 				}
 
-				err = lxcSetConfigItem(c.c, "lxc.cgroup.devices.allow", fmt.Sprintf("%s %d:%d rwm", dType, dMajor, dMinor))
-				if err != nil {
-					return "", fmt.Errorf("Failed to add cgroup rule for device")
+				// The nodes of gpu and usb devices are only looked up at startup
+				if shared.StringInSlice(m["type"], []string{"gpu", "usb"}) {
+					tgtPath := strings.TrimPrefix(d["path"], "/")
+					err = lxcSetConfigItem(c.c, "lxc.mount.entry", fmt.Sprintf("%s %s none bind,create=file", devPath, tgtPath))
+					if err != nil {
+						return "", err
+					}
+				}
+
+				if c.IsPrivileged() && !runningInUserns && cgDevicesController {
+					// Add the new device cgroup rule
+					dType, dMajor, dMinor, err := deviceGetAttributes(devPath)
+					if err != nil {
+						return "", err
+					}
+
+					err = lxcSetConfigItem(c.c, "lxc.cgroup.devices.allow", fmt.Sprintf("%s %d:%d rwm", dType, dMajor, dMinor))
+					if err != nil {
+						return "", fmt.Errorf("Failed to add cgroup rule for device")
+					}
 				}
 			}
 		} else if m["type"] == "disk" {
@@ -1745,6 +1815,12 @@ func (c *containerLXC) Start(stateful bool) error {
 		return err
 	}
 
+	// Refresh the DHCP host entries of the managed networks
+	err = networkUpdateStatic(c.state)
+	if err != nil {
+		logger.Warn("Failed to update the network DHCP host entries", log.Ctx{"name": c.name, "err": err})
+	}
+
 	ctxMap = log.Ctx{"name": c.name,
 		"action":        op.action,
 		"creation date": c.creationDate,
@@ -1773,6 +1849,13 @@ func (c *containerLXC) Start(stateful bool) error {
 			return err
 		}
 
+		err = c.startProxyDevices()
+		if err != nil {
+			logger.Error("Failed starting container", ctxMap)
+			c.abortStart(op, err)
+			return err
+		}
+
 		logger.Info("Started container", ctxMap)
 
 		return err
@@ -1844,11 +1927,31 @@ func (c *containerLXC) Start(stateful bool) error {
 			err, lxcLog)
 	}
 
+	// Start the proxies now that the container's network namespace exists
+	err = c.startProxyDevices()
+	if err != nil {
+		logger.Error("Failed starting container", ctxMap)
+		c.abortStart(op, err)
+		return err
+	}
+
 	logger.Info("Started container", ctxMap)
 
 	return nil
 }
 
+// abortStart stops a container whose start failed after it began running,
+// so it isn't left running with only some of its proxies.
+func (c *containerLXC) abortStart(op *lxcContainerOperation, err error) {
+	op.Done(err)
+	c.removeProxyDevices()
+
+	err = c.Stop(false)
+	if err != nil {
+		logger.Error("Failed stopping container after a failed start", log.Ctx{"name": c.name, "err": err})
+	}
+}
+
 func (c *containerLXC) OnStart() error {
 	// Make sure we can't call go-lxc functions by mistake
 	c.fromHook = true
@@ -2132,6 +2235,9 @@ func (c *containerLXC) OnStop(target string) error {
 			logger.Error("Failed to destroy apparmor namespace", log.Ctx{"container": c.Name(), "err": err})
 		}
 
+		// Stop all the proxies
+		c.removeProxyDevices()
+
 		// Clean all the unix devices
 		err = c.removeUnixDevices()
 		if err != nil {
@@ -2148,6 +2254,10 @@ func (c *containerLXC) OnStop(target string) error {
 		if target == "reboot" {
 			// Start the container again
 			err = c.Start(false)
+			if err == nil && op == nil {
+				eventSendLifecycle("container-restarted", fmt.Sprintf("/%s/containers/%s", version.APIVersion, c.name), nil, nil)
+			}
+
 			return
 		}
 
@@ -2160,9 +2270,16 @@ func (c *containerLXC) OnStop(target string) error {
 			logger.Error("Failed to set container state", log.Ctx{"container": c.Name(), "err": err})
 		}
 
+		if op == nil {
+			eventSendLifecycle("container-stopped", fmt.Sprintf("/%s/containers/%s", version.APIVersion, c.name), nil, nil)
+		}
+
 		// Destroy ephemeral containers
 		if c.ephemeral {
 			err = c.Delete()
+			if err == nil {
+				eventSendLifecycle("container-deleted", fmt.Sprintf("/%s/containers/%s", version.APIVersion, c.name), nil, nil)
+			}
 		}
 	}(c, target, op)
 
@@ -2276,6 +2393,10 @@ func (c *containerLXC) Render() (interface{}, error) {
 
 	if c.IsSnapshot() {
 		return &api.ContainerSnapshot{
+			ContainerSnapshotPut: api.ContainerSnapshotPut{
+				Description: c.description,
+				ExpiresAt:   c.expiryDate,
+			},
 			Architecture:    architectureName,
 			Config:          c.localConfig,
 			CreationDate:    c.creationDate,
@@ -2497,6 +2618,13 @@ func (c *containerLXC) Delete() error {
 		// Clean things up
 		c.cleanup()
 
+		// Remove the backups
+		err = os.RemoveAll(shared.VarPath("backups", c.Name()))
+		if err != nil {
+			logger.Warn("Failed to delete backups", log.Ctx{"name": c.Name(), "err": err})
+			return err
+		}
+
 		// Delete the container from disk
 		if c.storage != nil {
 			if shared.PathExists(c.Path()) {
@@ -2515,6 +2643,14 @@ func (c *containerLXC) Delete() error {
 		return err
 	}
 
+	if !c.IsSnapshot() {
+		// Refresh the DHCP host entries of the managed networks
+		err := networkUpdateStatic(c.state)
+		if err != nil {
+			logger.Warn("Failed to update the network DHCP host entries", log.Ctx{"name": c.name, "err": err})
+		}
+	}
+
 	logger.Info("Deleted container", ctxMap)
 
 	return nil
@@ -2551,6 +2687,15 @@ func (c *containerLXC) Rename(newName string) error {
 		}
 	}
 
+	// Rename the backups path
+	if !c.IsSnapshot() && shared.PathExists(shared.VarPath("backups", oldName)) {
+		err := os.Rename(shared.VarPath("backups", oldName), shared.VarPath("backups", newName))
+		if err != nil {
+			logger.Error("Failed renaming container", ctxMap)
+			return err
+		}
+	}
+
 	// Rename the storage entry
 	if c.IsSnapshot() {
 		if err := c.storage.ContainerSnapshotRename(c, newName); err != nil {
@@ -2587,6 +2732,12 @@ func (c *containerLXC) Rename(newName string) error {
 				return err
 			}
 		}
+
+		// Refresh the DHCP host entries of the managed networks
+		err = networkUpdateStatic(c.state)
+		if err != nil {
+			logger.Warn("Failed to update the network DHCP host entries", log.Ctx{"name": newName, "err": err})
+		}
 	}
 
 	// Set the new name in the struct
@@ -2817,6 +2968,12 @@ func (c *containerLXC) Update(args db.ContainerArgs, userRequested bool) error {
 	// Diff the devices
 	removeDevices, addDevices, updateDevices, updateDiff := oldExpandedDevices.Update(c.expandedDevices)
 
+	// Confirm that the storage pool didn't change
+	_, _, err = containerGetRootDiskDevice(oldExpandedDevices)
+	if err == nil && storagePoolForDevices(oldExpandedDevices) != storagePoolForDevices(c.expandedDevices) {
+		return fmt.Errorf("Cannot change the storage pool of an existing container")
+	}
+
 	// Do some validation of the config diff
 	err = containerValidConfig(c.state.OS, c.expandedConfig, false, true)
 	if err != nil {
@@ -3203,6 +3360,23 @@ func (c *containerLXC) Update(args db.ContainerArgs, userRequested bool) error {
 				if err != nil {
 					return err
 				}
+			} else if shared.StringInSlice(m["type"], []string{"gpu", "usb"}) {
+				unixDevices, err := deviceUnixDevices(m)
+				if err != nil {
+					return err
+				}
+
+				for _, d := range unixDevices {
+					err = c.removeUnixDevice(d)
+					if err != nil {
+						return err
+					}
+				}
+			} else if m["type"] == "proxy" {
+				err = c.removeProxyDevice(k, m)
+				if err != nil {
+					return err
+				}
 			}
 		}
 
@@ -3221,6 +3395,23 @@ func (c *containerLXC) Update(args db.ContainerArgs, userRequested bool) error {
 				if err != nil {
 					return err
 				}
+			} else if shared.StringInSlice(m["type"], []string{"gpu", "usb"}) {
+				unixDevices, err := deviceUnixDevices(m)
+				if err != nil {
+					return err
+				}
+
+				for _, d := range unixDevices {
+					err = c.insertUnixDevice(k, d)
+					if err != nil {
+						return err
+					}
+				}
+			} else if m["type"] == "proxy" {
+				err = c.insertProxyDevice(k, m)
+				if err != nil {
+					return err
+				}
 			}
 		}
 
@@ -3365,6 +3556,14 @@ func (c *containerLXC) Update(args db.ContainerArgs, userRequested bool) error {
 	// Success, update the closure to mark that the changes should be kept.
 	undoChanges = false
 
+	if !c.IsSnapshot() {
+		// Refresh the DHCP host entries of the managed networks
+		err = networkUpdateStatic(c.state)
+		if err != nil {
+			logger.Warn("Failed to update the network DHCP host entries", log.Ctx{"name": c.name, "err": err})
+		}
+	}
+
 	return nil
 }
 
@@ -3981,15 +4180,20 @@ func (c *containerLXC) FileExists(path string) error {
 	return nil
 }
 
-func (c *containerLXC) FilePull(srcpath string, dstpath string) (int64, int64, os.FileMode, error) {
+func (c *containerLXC) FilePull(srcpath string, dstpath string, followSymlinks bool) (int64, int64, os.FileMode, string, []string, error) {
 	// Setup container storage if needed
 	if !c.IsRunning() {
 		err := c.StorageStart()
 		if err != nil {
-			return -1, -1, 0, err
+			return -1, -1, 0, "", nil, err
 		}
 	}
 
+	follow := "follow"
+	if !followSymlinks {
+		follow = "nofollow"
+	}
+
 	// Get the file from the container
 	out, err := shared.RunCommand(
 		execPath,
@@ -3998,19 +4202,22 @@ func (c *containerLXC) FilePull(srcpath string, dstpath string) (int64, int64, o
 		fmt.Sprintf("%d", c.InitPID()),
 		dstpath,
 		srcpath,
+		follow,
 	)
 
 	// Tear down container storage if needed
 	if !c.IsRunning() {
 		err := c.StorageStop()
 		if err != nil {
-			return -1, -1, 0, err
+			return -1, -1, 0, "", nil, err
 		}
 	}
 
 	uid := int64(-1)
 	gid := int64(-1)
 	mode := -1
+	type_ := "unknown"
+	var dirEnts []string
 	var errStr string
 
 	// Process forkgetfile response
@@ -4028,17 +4235,17 @@ func (c *containerLXC) FilePull(srcpath string, dstpath string) (int64, int64, o
 		if strings.HasPrefix(line, "errno: ") {
 			errno := strings.TrimPrefix(line, "errno: ")
 			if errno == "2" {
-				return -1, -1, 0, os.ErrNotExist
+				return -1, -1, 0, "", nil, os.ErrNotExist
 			}
 
-			return -1, -1, 0, fmt.Errorf(errStr)
+			return -1, -1, 0, "", nil, fmt.Errorf(errStr)
 		}
 
 		// Extract the uid
 		if strings.HasPrefix(line, "uid: ") {
 			uid, err = strconv.ParseInt(strings.TrimPrefix(line, "uid: "), 10, 64)
 			if err != nil {
-				return -1, -1, 0, err
+				return -1, -1, 0, "", nil, err
 			}
 
 			continue
@@ -4048,7 +4255,7 @@ func (c *containerLXC) FilePull(srcpath string, dstpath string) (int64, int64, o
 		if strings.HasPrefix(line, "gid: ") {
 			gid, err = strconv.ParseInt(strings.TrimPrefix(line, "gid: "), 10, 64)
 			if err != nil {
-				return -1, -1, 0, err
+				return -1, -1, 0, "", nil, err
 			}
 
 			continue
@@ -4058,17 +4265,29 @@ func (c *containerLXC) FilePull(srcpath string, dstpath string) (int64, int64, o
 		if strings.HasPrefix(line, "mode: ") {
 			mode, err = strconv.Atoi(strings.TrimPrefix(line, "mode: "))
 			if err != nil {
-				return -1, -1, 0, err
+				return -1, -1, 0, "", nil, err
 			}
 
 			continue
 		}
 
+		// Extract the type
+		if strings.HasPrefix(line, "type: ") {
+			type_ = strings.TrimPrefix(line, "type: ")
+			continue
+		}
+
+		// Extract the directory entries
+		if strings.HasPrefix(line, "entry: ") {
+			dirEnts = append(dirEnts, strings.TrimPrefix(line, "entry: "))
+			continue
+		}
+
 		logger.Debugf("forkgetfile: %s", line)
 	}
 
 	if err != nil {
-		return -1, -1, 0, fmt.Errorf(
+		return -1, -1, 0, "", nil, fmt.Errorf(
 			"Error calling 'lxd forkgetfile %s %d %s %s': err='%v'",
 			c.RootfsPath(),
 			c.InitPID(),
@@ -4081,7 +4300,7 @@ func (c *containerLXC) FilePull(srcpath string, dstpath string) (int64, int64, o
 	if !c.IsRunning() {
 		idmapset, err := c.LastIdmapSet()
 		if err != nil {
-			return -1, -1, 0, err
+			return -1, -1, 0, "", nil, err
 		}
 
 		if idmapset != nil {
@@ -4089,14 +4308,20 @@ func (c *containerLXC) FilePull(srcpath string, dstpath string) (int64, int64, o
 		}
 	}
 
-	return uid, gid, os.FileMode(mode), nil
+	return uid, gid, os.FileMode(mode), type_, dirEnts, nil
 }
 
-func (c *containerLXC) FilePush(srcpath string, dstpath string, uid int64, gid int64, mode int) error {
+func (c *containerLXC) FilePush(type_ string, srcpath string, dstpath string, uid int64, gid int64, mode int, write string) error {
 	var rootUid int64
 	var rootGid int64
 	var errStr string
 
+	// New directories are created traversable, new files not executable
+	defaultMode := 0640
+	if type_ == "directory" {
+		defaultMode = 0755
+	}
+
 	// Map uid and gid if needed
 	if !c.IsRunning() {
 		idmapset, err := c.LastIdmapSet()
@@ -4126,12 +4351,14 @@ func (c *containerLXC) FilePush(srcpath string, dstpath string, uid int64, gid i
 		fmt.Sprintf("%d", c.InitPID()),
 		srcpath,
 		dstpath,
+		type_,
 		fmt.Sprintf("%d", uid),
 		fmt.Sprintf("%d", gid),
 		fmt.Sprintf("%d", mode),
 		fmt.Sprintf("%d", rootUid),
 		fmt.Sprintf("%d", rootGid),
-		fmt.Sprintf("%d", int(os.FileMode(0640)&os.ModePerm)),
+		fmt.Sprintf("%d", int(os.FileMode(defaultMode)&os.ModePerm)),
+		write,
 	)
 
 	// Tear down container storage if needed
@@ -4166,17 +4393,19 @@ func (c *containerLXC) FilePush(srcpath string, dstpath string, uid int64, gid i
 
 	if err != nil {
 		return fmt.Errorf(
-			"Error calling 'lxd forkputfile %s %d %s %s %d %d %d %d %d %d': err='%v'",
+			"Error calling 'lxd forkputfile %s %d %s %s %s %d %d %d %d %d %d %s': err='%v'",
 			c.RootfsPath(),
 			c.InitPID(),
 			srcpath,
 			dstpath,
+			type_,
 			uid,
 			gid,
 			mode,
 			rootUid,
 			rootGid,
-			int(os.FileMode(0640)&os.ModePerm),
+			int(os.FileMode(defaultMode)&os.ModePerm),
+			write,
 			err)
 	}
 
@@ -4245,14 +4474,14 @@ func (c *containerLXC) FileRemove(path string) error {
 	return nil
 }
 
-func (c *containerLXC) Exec(command []string, env map[string]string, stdin *os.File, stdout *os.File, stderr *os.File, wait bool) (*exec.Cmd, int, int, error) {
+func (c *containerLXC) Exec(command []string, env map[string]string, stdin *os.File, stdout *os.File, stderr *os.File, wait bool, cwd string, uid uint32, gid uint32) (*exec.Cmd, int, int, error) {
 	envSlice := []string{}
 
 	for k, v := range env {
 		envSlice = append(envSlice, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	args := []string{execPath, "forkexec", c.name, c.state.OS.LxcPath, filepath.Join(c.LogPath(), "lxc.conf")}
+	args := []string{execPath, "forkexec", c.name, c.state.OS.LxcPath, filepath.Join(c.LogPath(), "lxc.conf"), cwd, fmt.Sprintf("%d", uid), fmt.Sprintf("%d", gid)}
 
 	args = append(args, "--")
 	args = append(args, "env")
@@ -4316,6 +4545,19 @@ func (c *containerLXC) Exec(command []string, env map[string]string, stdin *os.F
 	return nil, 0, attachedPid, nil
 }
 
+func (c *containerLXC) Console(terminal *os.File) *exec.Cmd {
+	args := []string{execPath, "forkconsole", c.name, c.state.OS.LxcPath, filepath.Join(c.LogPath(), "lxc.conf")}
+
+	cmd := exec.Cmd{}
+	cmd.Path = execPath
+	cmd.Args = args
+	cmd.Stdin = terminal
+	cmd.Stdout = terminal
+	cmd.Stderr = terminal
+
+	return &cmd
+}
+
 func (c *containerLXC) diskState() map[string]api.ContainerStateDisk {
 	disk := map[string]api.ContainerStateDisk{}
 
@@ -4891,8 +5133,10 @@ func (c *containerLXC) removeUnixDevice(m types.Device) error {
 	}
 
 	dType := ""
-	if m["type"] != "" {
-		dType = m["type"]
+	if m["type"] == "unix-char" {
+		dType = "c"
+	} else if m["type"] == "unix-block" {
+		dType = "b"
 	}
 
 	if dType == "" || dMajor < 0 || dMinor < 0 {
@@ -4966,6 +5210,164 @@ func (c *containerLXC) removeUnixDevices() error {
 	return nil
 }
 
+// Proxy device handling
+func (c *containerLXC) proxyPidPath(name string) string {
+	return filepath.Join(c.DevicesPath(), fmt.Sprintf("proxy.%s", name))
+}
+
+// proxyListen creates the listening socket of a proxy device on the host,
+// returning it as a file to be inherited by the forkproxy process.
+func proxyListen(proto string, addr string) (*os.File, error) {
+	switch proto {
+	case "udp":
+		conn, err := net.ListenPacket(proto, addr)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		return conn.(*net.UDPConn).File()
+	case "unix":
+		// Replace any leftover socket
+		fi, err := os.Lstat(addr)
+		if err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(addr)
+		}
+
+		listener, err := net.ListenUnix(proto, &net.UnixAddr{Name: addr, Net: proto})
+		if err != nil {
+			return nil, err
+		}
+		listener.SetUnlinkOnClose(false)
+		defer listener.Close()
+
+		return listener.File()
+	default:
+		listener, err := net.Listen(proto, addr)
+		if err != nil {
+			return nil, err
+		}
+		defer listener.Close()
+
+		return listener.(*net.TCPListener).File()
+	}
+}
+
+func (c *containerLXC) insertProxyDevice(name string, m types.Device) error {
+	// Check that the container is running
+	pid := c.InitPID()
+	if pid == -1 {
+		return fmt.Errorf("Can't add proxy device to stopped container")
+	}
+
+	listenProto, listenAddr, err := proxyParseAddr(m["listen"])
+	if err != nil {
+		return err
+	}
+
+	f, err := proxyListen(listenProto, listenAddr)
+	if err != nil {
+		return fmt.Errorf("Failed to listen on %s: %s", m["listen"], err)
+	}
+	defer f.Close()
+
+	logFile, err := os.OpenFile(filepath.Join(c.LogPath(), fmt.Sprintf("proxy.%s.log", name)), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	// Spawn the proxy, it inherits the listening socket as fd 3
+	cmd := exec.Command(execPath, "forkproxy", fmt.Sprintf("%d", pid), m["listen"], m["connect"])
+	cmd.ExtraFiles = []*os.File{f}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("Failed to start the proxy for %s: %s", name, err)
+	}
+	go cmd.Wait()
+
+	// Record its pid so it can be stopped later
+	err = os.MkdirAll(c.DevicesPath(), 0711)
+	if err == nil {
+		err = ioutil.WriteFile(c.proxyPidPath(name), []byte(fmt.Sprintf("%d\n", cmd.Process.Pid)), 0600)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+
+	return nil
+}
+
+func (c *containerLXC) removeProxyDevice(name string, m types.Device) error {
+	pidPath := c.proxyPidPath(name)
+
+	content, err := ioutil.ReadFile(pidPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return fmt.Errorf("Invalid pid file for proxy device %s: %s", name, err)
+	}
+
+	// Make sure the pid wasn't recycled before killing it
+	cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err == nil && strings.Contains(string(cmdline), "forkproxy") {
+		err = syscall.Kill(pid, syscall.SIGTERM)
+		if err != nil {
+			return fmt.Errorf("Failed to stop the proxy for %s: %s", name, err)
+		}
+	}
+
+	// Remove the socket on the host
+	listenProto, listenAddr, err := proxyParseAddr(m["listen"])
+	if err == nil && listenProto == "unix" {
+		os.Remove(listenAddr)
+	}
+
+	return os.Remove(pidPath)
+}
+
+func (c *containerLXC) startProxyDevices() error {
+	for _, name := range c.expandedDevices.DeviceNames() {
+		m := c.expandedDevices[name]
+		if m["type"] != "proxy" {
+			continue
+		}
+
+		err := c.insertProxyDevice(name, m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *containerLXC) removeProxyDevices() {
+	for _, name := range c.expandedDevices.DeviceNames() {
+		m := c.expandedDevices[name]
+		if m["type"] != "proxy" {
+			continue
+		}
+
+		err := c.removeProxyDevice(name, m)
+		if err != nil {
+			logger.Error("Failed to stop proxy device", log.Ctx{"container": c.Name(), "device": name, "err": err})
+		}
+	}
+}
+
 // Network device handling
 func (c *containerLXC) createNetworkDevice(name string, m types.Device) (string, error) {
 	var dev, n1 string
@@ -5293,6 +5695,19 @@ func (c *containerLXC) createDiskDevice(name string, m types.Device) (string, er
 	// Prepare all the paths
 	srcPath := shared.HostPath(m["source"])
 	tgtPath := strings.TrimPrefix(m["path"], "/")
+
+	// Custom storage volumes are mounted and shifted on demand
+	if m["pool"] != "" {
+		var err error
+		srcPath, err = storagePoolVolumeAttach(c.state, m["pool"], m["source"], c)
+		if err != nil {
+			if shared.IsTrue(m["optional"]) {
+				return "", nil
+			}
+			return "", err
+		}
+	}
+
 	devName := fmt.Sprintf("disk.%s", strings.Replace(tgtPath, "/", "-", -1))
 	devPath := filepath.Join(c.DevicesPath(), devName)
 
@@ -5538,6 +5953,8 @@ func (c *containerLXC) getDiskLimits() (map[string]deviceBlockLimit, error) {
 		source := shared.HostPath(m["source"])
 		if source == "" {
 			source = c.RootfsPath()
+		} else if m["pool"] != "" {
+			source = getStoragePoolVolumeMountPoint(m["pool"], m["source"])
 		}
 
 		// Don't try to resolve the block device behind a non-existing path
@@ -5849,6 +6266,14 @@ func (c *containerLXC) Architecture() int {
 func (c *containerLXC) CreationDate() time.Time {
 	return c.creationDate
 }
+
+func (c *containerLXC) Description() string {
+	return c.description
+}
+
+func (c *containerLXC) ExpiryDate() time.Time {
+	return c.expiryDate
+}
 func (c *containerLXC) ExpandedConfig() map[string]string {
 	return c.expandedConfig
 }
@@ -5978,6 +6403,10 @@ func (c *containerLXC) LogFilePath() string {
 	return filepath.Join(c.LogPath(), "lxc.log")
 }
 
+func (c *containerLXC) ConsoleLogPath() string {
+	return filepath.Join(c.LogPath(), "console.log")
+}
+
 func (c *containerLXC) RootfsPath() string {
 	return filepath.Join(c.Path(), "rootfs")
 }
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// The triggers for which templates can be applied
+var containerTemplateTriggers = []string{"create", "copy", "start"}
+
+// containerMetadataStorageStart makes the container's filesystem available,
+// returning a function to undo it.
+func containerMetadataStorageStart(c container) (func(), error) {
+	// A running container already has its storage mounted
+	if c.IsRunning() {
+		return func() {}, nil
+	}
+
+	err := c.StorageStart()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() { c.StorageStop() }, nil
+}
+
+func containerMetadataGet(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	c, err := containerLoadByName(d.State(), d.Storage, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	cleanup, err := containerMetadataStorageStart(c)
+	if err != nil {
+		return SmartError(err)
+	}
+	defer cleanup()
+
+	metadata := api.ImageMetadata{
+		Properties: map[string]string{},
+		Templates:  map[string]*api.ImageMetadataTemplate{},
+	}
+
+	// Containers not created from an image may have no metadata
+	metadataPath := filepath.Join(c.Path(), "metadata.yaml")
+	if !shared.PathExists(metadataPath) {
+		return SyncResponse(true, metadata)
+	}
+
+	content, err := ioutil.ReadFile(metadataPath)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	err = yaml.Unmarshal(content, &metadata)
+	if err != nil {
+		return InternalError(fmt.Errorf("Could not parse %s: %v", metadataPath, err))
+	}
+
+	return SyncResponse(true, metadata)
+}
+
+func containerMetadataPut(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	c, err := containerLoadByName(d.State(), d.Storage, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	metadata := api.ImageMetadata{}
+	if err := json.NewDecoder(r.Body).Decode(&metadata); err != nil {
+		return BadRequest(err)
+	}
+
+	for path, template := range metadata.Templates {
+		if template == nil {
+			return BadRequest(fmt.Errorf("Missing template definition for %s", path))
+		}
+
+		err := containerTemplateValidName(template.Template)
+		if err != nil {
+			return BadRequest(err)
+		}
+
+		for _, trigger := range template.When {
+			if !shared.StringInSlice(trigger, containerTemplateTriggers) {
+				return BadRequest(fmt.Errorf("Invalid template trigger for %s: %s (not one of %s)", path, trigger, containerTemplateTriggers))
+			}
+		}
+	}
+
+	content, err := yaml.Marshal(metadata)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	cleanup, err := containerMetadataStorageStart(c)
+	if err != nil {
+		return SmartError(err)
+	}
+	defer cleanup()
+
+	err = ioutil.WriteFile(filepath.Join(c.Path(), "metadata.yaml"), content, 0644)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return EmptySyncResponse
+}
+
+// containerTemplateValidName makes sure the name of a template file doesn't
+// point outside of the container's templates directory.
+func containerTemplateValidName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No template name provided")
+	}
+
+	if strings.Contains(name, "/") || shared.StringInSlice(name, []string{".", ".."}) {
+		return fmt.Errorf("Invalid template name '%s'", name)
+	}
+
+	return nil
+}
+
+func containerMetadataTemplatesGet(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	c, err := containerLoadByName(d.State(), d.Storage, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	cleanup, err := containerMetadataStorageStart(c)
+	if err != nil {
+		return SmartError(err)
+	}
+	defer cleanup()
+
+	// List the templates when no file was requested
+	templateName := r.FormValue("path")
+	if templateName == "" {
+		templates := []string{}
+
+		if shared.PathExists(c.TemplatesPath()) {
+			entries, err := ioutil.ReadDir(c.TemplatesPath())
+			if err != nil {
+				return InternalError(err)
+			}
+
+			for _, entry := range entries {
+				if entry.Mode().IsRegular() {
+					templates = append(templates, entry.Name())
+				}
+			}
+		}
+
+		sort.Strings(templates)
+
+		return SyncResponse(true, templates)
+	}
+
+	err = containerTemplateValidName(templateName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	templatePath := filepath.Join(c.TemplatesPath(), templateName)
+	if !shared.PathExists(templatePath) {
+		return NotFound
+	}
+
+	// The storage may be stopped before the response is rendered, so
+	// serve the content from memory
+	content, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	files := []fileResponseEntry{{
+		identifier: templateName,
+		filename:   templateName,
+		buffer:     content,
+	}}
+
+	return FileResponse(r, files, nil, false)
+}
+
+func containerMetadataTemplatesPostPut(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	c, err := containerLoadByName(d.State(), d.Storage, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	templateName := r.FormValue("path")
+	err = containerTemplateValidName(templateName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	cleanup, err := containerMetadataStorageStart(c)
+	if err != nil {
+		return SmartError(err)
+	}
+	defer cleanup()
+
+	templatePath := filepath.Join(c.TemplatesPath(), templateName)
+
+	// POST creates a new template while PUT replaces an existing one
+	exists := shared.PathExists(templatePath)
+	if r.Method == "POST" && exists {
+		return Conflict
+	} else if r.Method == "PUT" && !exists {
+		return NotFound
+	}
+
+	if !shared.PathExists(c.TemplatesPath()) {
+		err := os.MkdirAll(c.TemplatesPath(), 0711)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	f, err := os.OpenFile(templatePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return InternalError(err)
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return EmptySyncResponse
+}
+
+func containerMetadataTemplatesDelete(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	c, err := containerLoadByName(d.State(), d.Storage, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	templateName := r.FormValue("path")
+	err = containerTemplateValidName(templateName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	cleanup, err := containerMetadataStorageStart(c)
+	if err != nil {
+		return SmartError(err)
+	}
+	defer cleanup()
+
+	templatePath := filepath.Join(c.TemplatesPath(), templateName)
+	if !shared.PathExists(templatePath) {
+		return NotFound
+	}
+
+	err = os.Remove(templatePath)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return EmptySyncResponse
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 
@@ -9,6 +10,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
 )
 
 func containerPost(d *Daemon, r *http.Request) Response {
@@ -30,7 +32,7 @@ func containerPost(d *Daemon, r *http.Request) Response {
 	}
 
 	if body.Migration {
-		ws, err := NewMigrationSource(c)
+		ws, err := NewMigrationSource(c, body.ContainerOnly)
 		if err != nil {
 			return InternalError(err)
 		}
@@ -42,6 +44,7 @@ func containerPost(d *Daemon, r *http.Request) Response {
 		if err != nil {
 			return InternalError(err)
 		}
+		op.SetRequestor(d, r)
 
 		return OperationResponse(op)
 	}
@@ -53,7 +56,15 @@ func containerPost(d *Daemon, r *http.Request) Response {
 	}
 
 	run := func(*operation) error {
-		return c.Rename(body.Name)
+		err := c.Rename(body.Name)
+		if err != nil {
+			return err
+		}
+
+		eventSendLifecycle("container-renamed", fmt.Sprintf("/%s/containers/%s", version.APIVersion, name),
+			map[string]interface{}{"new_name": body.Name}, r)
+
+		return nil
 	}
 
 	resources := map[string][]string{}
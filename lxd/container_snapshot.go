@@ -5,13 +5,18 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/version"
+
+	log "gopkg.in/inconshreveable/log15.v2"
 )
 
 func containerSnapshotsGet(d *Daemon, r *http.Request) Response {
@@ -61,8 +66,9 @@ func containerSnapshotsGet(d *Daemon, r *http.Request) Response {
  * Note, the code below doesn't deal with snapshots of snapshots.
  * To do that, we'll need to weed out based on # slashes in names
  */
-func nextSnapshot(d *Daemon, name string) int {
-	base := name + shared.SnapshotDelimiter + "snap"
+func nextSnapshot(d *Daemon, name string, pattern string) int {
+	// Only the part of the pattern before the index is looked at
+	base := name + shared.SnapshotDelimiter + strings.SplitN(pattern, "%d", 2)[0]
 	length := len(base)
 	q := fmt.Sprintf("SELECT name FROM containers WHERE type=? AND SUBSTR(name,1,?)=?")
 	var numstr string
@@ -93,6 +99,76 @@ func nextSnapshot(d *Daemon, name string) int {
 	return max
 }
 
+// containerSnapshotNextName generates the name of the next snapshot of the
+// container from its snapshots.pattern.
+func containerSnapshotNextName(d *Daemon, c container) (string, error) {
+	pattern := c.ExpandedConfig()["snapshots.pattern"]
+	if pattern == "" {
+		pattern = "snap%d"
+	}
+
+	pattern, err := snapshotPatternRender(pattern, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	// Add an index to fixed names which are already in use
+	if !strings.Contains(pattern, "%d") {
+		_, err := db.ContainerId(d.db, c.Name()+shared.SnapshotDelimiter+pattern)
+		if err != nil {
+			return pattern, nil
+		}
+
+		pattern = pattern + "-%d"
+	}
+
+	i := nextSnapshot(d, c.Name(), pattern)
+	return strings.Replace(pattern, "%d", strconv.Itoa(i), 1), nil
+}
+
+// containerSnapshotValidName makes sure the name of a snapshot can be used
+// as part of a path.
+func containerSnapshotValidName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No snapshot name provided")
+	}
+
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("Snapshot names may not contain slashes")
+	}
+
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("Invalid snapshot name '%s'", name)
+	}
+
+	return nil
+}
+
+// containerSnapshotCreate takes a snapshot of the container under the given
+// name, set to expire according to its snapshots.expiry.
+func containerSnapshotCreate(d *Daemon, c container, name string, stateful bool) error {
+	expiryDate, err := snapshotExpiryDate(c.ExpandedConfig()["snapshots.expiry"], time.Now())
+	if err != nil {
+		return err
+	}
+
+	args := db.ContainerArgs{
+		Name:         c.Name() + shared.SnapshotDelimiter + name,
+		Ctype:        db.CTypeSnapshot,
+		Config:       c.LocalConfig(),
+		Profiles:     c.Profiles(),
+		Ephemeral:    c.IsEphemeral(),
+		BaseImage:    c.ExpandedConfig()["volatile.base_image"],
+		Architecture: c.Architecture(),
+		Devices:      c.LocalDevices(),
+		Stateful:     stateful,
+		ExpiryDate:   expiryDate,
+	}
+
+	_, err = containerCreateAsSnapshot(d.State(), d.Storage, args, c)
+	return err
+}
+
 func containerSnapshotsPost(d *Daemon, r *http.Request) Response {
 	name := mux.Vars(r)["name"]
 
@@ -114,32 +190,26 @@ func containerSnapshotsPost(d *Daemon, r *http.Request) Response {
 
 	if req.Name == "" {
 		// come up with a name
-		i := nextSnapshot(d, name)
-		req.Name = fmt.Sprintf("snap%d", i)
+		req.Name, err = containerSnapshotNextName(d, c)
+		if err != nil {
+			return BadRequest(err)
+		}
 	}
 
-	fullName := name +
-		shared.SnapshotDelimiter +
-		req.Name
+	err = containerSnapshotValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
 
 	snapshot := func(op *operation) error {
-		args := db.ContainerArgs{
-			Name:         fullName,
-			Ctype:        db.CTypeSnapshot,
-			Config:       c.LocalConfig(),
-			Profiles:     c.Profiles(),
-			Ephemeral:    c.IsEphemeral(),
-			BaseImage:    c.ExpandedConfig()["volatile.base_image"],
-			Architecture: c.Architecture(),
-			Devices:      c.LocalDevices(),
-			Stateful:     req.Stateful,
-		}
-
-		_, err := containerCreateAsSnapshot(d.State(), d.Storage, args, c)
+		err := containerSnapshotCreate(d, c, req.Name, req.Stateful)
 		if err != nil {
 			return err
 		}
 
+		eventSendLifecycle("container-snapshot-created", fmt.Sprintf("/%s/containers/%s/snapshots/%s", version.APIVersion, name, req.Name),
+			map[string]interface{}{"container": name}, r)
+
 		return nil
 	}
 
@@ -173,8 +243,10 @@ func snapshotHandler(d *Daemon, r *http.Request) Response {
 		return snapshotGet(sc, snapshotName)
 	case "POST":
 		return snapshotPost(d, r, sc, containerName)
+	case "PUT":
+		return snapshotPut(d, r, sc, containerName, snapshotName)
 	case "DELETE":
-		return snapshotDelete(sc, snapshotName)
+		return snapshotDelete(r, sc, containerName, snapshotName)
 	default:
 		return NotFound
 	}
@@ -197,7 +269,8 @@ func snapshotPost(d *Daemon, r *http.Request, sc container, containerName string
 
 	migration, err := raw.GetBool("migration")
 	if err == nil && migration {
-		ws, err := NewMigrationSource(sc)
+		// Snapshots don't have snapshots of their own to send
+		ws, err := NewMigrationSource(sc, true)
 		if err != nil {
 			return SmartError(err)
 		}
@@ -209,6 +282,7 @@ func snapshotPost(d *Daemon, r *http.Request, sc container, containerName string
 		if err != nil {
 			return InternalError(err)
 		}
+		op.SetRequestor(d, r)
 
 		return OperationResponse(op)
 	}
@@ -218,6 +292,11 @@ func snapshotPost(d *Daemon, r *http.Request, sc container, containerName string
 		return BadRequest(err)
 	}
 
+	err = containerSnapshotValidName(newName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
 	fullName := containerName + shared.SnapshotDelimiter + newName
 
 	// Check that the name isn't already in use
@@ -227,7 +306,17 @@ func snapshotPost(d *Daemon, r *http.Request, sc container, containerName string
 	}
 
 	rename := func(op *operation) error {
-		return sc.Rename(fullName)
+		oldName := strings.SplitN(sc.Name(), shared.SnapshotDelimiter, 2)[1]
+
+		err := sc.Rename(fullName)
+		if err != nil {
+			return err
+		}
+
+		eventSendLifecycle("container-snapshot-renamed", fmt.Sprintf("/%s/containers/%s/snapshots/%s", version.APIVersion, containerName, oldName),
+			map[string]interface{}{"container": containerName, "new_name": newName}, r)
+
+		return nil
 	}
 
 	resources := map[string][]string{}
@@ -241,9 +330,46 @@ func snapshotPost(d *Daemon, r *http.Request, sc container, containerName string
 	return OperationResponse(op)
 }
 
-func snapshotDelete(sc container, name string) Response {
+func snapshotPut(d *Daemon, r *http.Request, sc container, containerName string, name string) Response {
+	req := api.ContainerSnapshotPut{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	update := func(op *operation) error {
+		err := db.ContainerSnapshotUpdate(d.db, sc.Id(), req.Description, req.ExpiresAt)
+		if err != nil {
+			return err
+		}
+
+		eventSendLifecycle("container-snapshot-updated", fmt.Sprintf("/%s/containers/%s/snapshots/%s", version.APIVersion, containerName, name),
+			map[string]interface{}{"container": containerName}, r)
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{sc.Name()}
+
+	op, err := operationCreate(operationClassTask, resources, nil, update, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
+func snapshotDelete(r *http.Request, sc container, containerName string, name string) Response {
 	remove := func(op *operation) error {
-		return sc.Delete()
+		err := sc.Delete()
+		if err != nil {
+			return err
+		}
+
+		eventSendLifecycle("container-snapshot-deleted", fmt.Sprintf("/%s/containers/%s/snapshots/%s", version.APIVersion, containerName, name),
+			map[string]interface{}{"container": containerName}, r)
+
+		return nil
 	}
 
 	resources := map[string][]string{}
@@ -256,3 +382,85 @@ func snapshotDelete(sc container, name string) Response {
 
 	return OperationResponse(op)
 }
+
+// autoCreateContainerSnapshots snapshots the containers whose
+// snapshots.schedule fires during the minute of the given time.
+func autoCreateContainerSnapshots(d *Daemon, now time.Time) {
+	names, err := db.ContainersList(d.db, db.CTypeRegular)
+	if err != nil {
+		logger.Error("Unable to retrieve the list of containers", log.Ctx{"err": err})
+		return
+	}
+
+	for _, name := range names {
+		c, err := containerLoadByName(d.State(), d.Storage, name)
+		if err != nil {
+			logger.Error("Error loading container", log.Ctx{"err": err, "container": name})
+			continue
+		}
+
+		config := c.ExpandedConfig()
+		if config["snapshots.schedule"] == "" {
+			continue
+		}
+
+		if !c.IsRunning() && !shared.IsTrue(config["snapshots.schedule.stopped"]) {
+			continue
+		}
+
+		schedule, err := snapshotScheduleParse(config["snapshots.schedule"])
+		if err != nil {
+			logger.Error("Invalid snapshot schedule", log.Ctx{"err": err, "container": name})
+			continue
+		}
+
+		if !schedule.Matches(now) {
+			continue
+		}
+
+		snapName, err := containerSnapshotNextName(d, c)
+		if err != nil {
+			logger.Error("Error generating the snapshot name", log.Ctx{"err": err, "container": name})
+			continue
+		}
+
+		logger.Info("Creating scheduled snapshot", log.Ctx{"container": name, "snapshot": snapName})
+		err = containerSnapshotCreate(d, c, snapName, false)
+		if err != nil {
+			logger.Error("Error creating scheduled snapshot", log.Ctx{"err": err, "container": name, "snapshot": snapName})
+			continue
+		}
+
+		eventSendLifecycle("container-snapshot-created", fmt.Sprintf("/%s/containers/%s/snapshots/%s", version.APIVersion, name, snapName),
+			map[string]interface{}{"container": name}, nil)
+	}
+}
+
+// pruneExpiredContainerSnapshots deletes the snapshots past their expiry
+// date.
+func pruneExpiredContainerSnapshots(d *Daemon) {
+	names, err := db.ContainerSnapshotsExpired(d.db, time.Now())
+	if err != nil {
+		logger.Error("Unable to retrieve the list of expired snapshots", log.Ctx{"err": err})
+		return
+	}
+
+	for _, name := range names {
+		sc, err := containerLoadByName(d.State(), d.Storage, name)
+		if err != nil {
+			logger.Error("Error loading snapshot", log.Ctx{"err": err, "snapshot": name})
+			continue
+		}
+
+		logger.Info("Deleting expired snapshot", log.Ctx{"snapshot": name})
+		err = sc.Delete()
+		if err != nil {
+			logger.Error("Error deleting expired snapshot", log.Ctx{"err": err, "snapshot": name})
+			continue
+		}
+
+		fields := strings.SplitN(name, shared.SnapshotDelimiter, 2)
+		eventSendLifecycle("container-snapshot-deleted", fmt.Sprintf("/%s/containers/%s/snapshots/%s", version.APIVersion, fields[0], fields[1]),
+			map[string]interface{}{"container": fields[0]}, nil)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/flosch/pongo2.v3"
+)
+
+// The shorthands accepted in place of a full schedule
+var snapshotScheduleAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// snapshotSchedule is a parsed cron expression, holding the accepted values
+// of each of its fields.
+type snapshotSchedule struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+
+	// Whether the day of month and day of week fields were restricted
+	anyDay     bool
+	anyWeekday bool
+}
+
+// snapshotScheduleParseField parses a single field of a cron expression,
+// made of comma separated values, ranges and steps.
+func snapshotScheduleParseField(field string, min int, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, entry := range strings.Split(field, ",") {
+		step := 1
+		fields := strings.SplitN(entry, "/", 2)
+		if len(fields) == 2 {
+			var err error
+			step, err = strconv.Atoi(fields[1])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("Invalid step: %s", entry)
+			}
+		}
+
+		start := min
+		end := max
+		if fields[0] != "*" {
+			bounds := strings.SplitN(fields[0], "-", 2)
+
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value: %s", entry)
+			}
+
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("Invalid value: %s", entry)
+				}
+			} else if len(fields) == 1 {
+				end = start
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("Value out of range: %s (must be between %d and %d)", entry, min, max)
+		}
+
+		for i := start; i <= end; i += step {
+			values[i] = true
+		}
+	}
+
+	return values, nil
+}
+
+// snapshotScheduleParse parses a cron expression made of the minute, hour,
+// day of month, month and day of week fields.
+func snapshotScheduleParse(spec string) (*snapshotSchedule, error) {
+	alias, ok := snapshotScheduleAliases[spec]
+	if ok {
+		spec = alias
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Schedule must have 5 fields: %s", spec)
+	}
+
+	var err error
+	schedule := snapshotSchedule{
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+
+	schedule.minutes, err = snapshotScheduleParseField(fields[0], 0, 59)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid minute in schedule: %v", err)
+	}
+
+	schedule.hours, err = snapshotScheduleParseField(fields[1], 0, 23)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid hour in schedule: %v", err)
+	}
+
+	schedule.days, err = snapshotScheduleParseField(fields[2], 1, 31)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid day of month in schedule: %v", err)
+	}
+
+	schedule.months, err = snapshotScheduleParseField(fields[3], 1, 12)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid month in schedule: %v", err)
+	}
+
+	schedule.weekdays, err = snapshotScheduleParseField(fields[4], 0, 7)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid day of week in schedule: %v", err)
+	}
+
+	// Both 0 and 7 are Sunday
+	if schedule.weekdays[7] {
+		schedule.weekdays[0] = true
+	}
+
+	return &schedule, nil
+}
+
+// Matches returns whether the schedule fires during the minute of the given
+// time.
+func (s *snapshotSchedule) Matches(t time.Time) bool {
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[int(t.Month())] {
+		return false
+	}
+
+	day := s.days[t.Day()]
+	weekday := s.weekdays[int(t.Weekday())]
+
+	// As with cron, restricting both the day of month and day of week
+	// matches either of them
+	if !s.anyDay && !s.anyWeekday {
+		return day || weekday
+	}
+
+	return day && weekday
+}
+
+// snapshotExpiryDate computes the expiry date of a snapshot taken at the
+// given time from an expression such as "1M 2H 3d 4w 5m 6y" (minutes, hours,
+// days, weeks, months and years).
+func snapshotExpiryDate(expiry string, t time.Time) (time.Time, error) {
+	if expiry == "" {
+		return time.Time{}, nil
+	}
+
+	for _, entry := range strings.Fields(expiry) {
+		if len(entry) < 2 {
+			return time.Time{}, fmt.Errorf("Invalid expiry: %s", entry)
+		}
+
+		value, err := strconv.Atoi(entry[:len(entry)-1])
+		if err != nil || value < 0 {
+			return time.Time{}, fmt.Errorf("Invalid expiry: %s", entry)
+		}
+
+		switch entry[len(entry)-1] {
+		case 'M':
+			t = t.Add(time.Duration(value) * time.Minute)
+		case 'H':
+			t = t.Add(time.Duration(value) * time.Hour)
+		case 'd':
+			t = t.AddDate(0, 0, value)
+		case 'w':
+			t = t.AddDate(0, 0, value*7)
+		case 'm':
+			t = t.AddDate(0, value, 0)
+		case 'y':
+			t = t.AddDate(value, 0, 0)
+		default:
+			return time.Time{}, fmt.Errorf("Invalid expiry unit: %s (not one of M, H, d, w, m or y)", entry)
+		}
+	}
+
+	return t, nil
+}
+
+// snapshotPatternRender renders a snapshots.pattern template, which has
+// access to the creation date of the snapshot.
+func snapshotPatternRender(pattern string, t time.Time) (string, error) {
+	tpl, err := pongo2.FromString("{% autoescape off %}" + pattern + "{% endautoescape %}")
+	if err != nil {
+		return "", fmt.Errorf("Invalid snapshot pattern: %v", err)
+	}
+
+	name, err := tpl.Execute(pongo2.Context{"creation_date": t})
+	if err != nil {
+		return "", fmt.Errorf("Invalid snapshot pattern: %v", err)
+	}
+
+	if name == "" || strings.Contains(name, "/") {
+		return "", fmt.Errorf("Invalid snapshot name generated by the pattern: '%s'", name)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotScheduleParse(t *testing.T) {
+	for _, spec := range []string{"* * * * *", "*/15 2,14 1-7 * 1-5", "5/10 * * 1,6 0", "@daily", "0 0 * * 7"} {
+		_, err := snapshotScheduleParse(spec)
+		assert.NoError(t, err, spec)
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 5-2 * * *", "*/0 * * * *", "a * * * *", "@often"} {
+		_, err := snapshotScheduleParse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSnapshotScheduleMatches(t *testing.T) {
+	// A Monday
+	monday := time.Date(2017, time.October, 2, 14, 30, 0, 0, time.UTC)
+
+	schedule, err := snapshotScheduleParse("*/15 14 * * 1-5")
+	assert.NoError(t, err)
+	assert.True(t, schedule.Matches(monday))
+	assert.False(t, schedule.Matches(monday.Add(time.Minute)))
+	assert.False(t, schedule.Matches(monday.AddDate(0, 0, 5)))
+
+	// Both 0 and 7 are Sunday
+	schedule, err = snapshotScheduleParse("30 14 * * 7")
+	assert.NoError(t, err)
+	assert.True(t, schedule.Matches(monday.AddDate(0, 0, 6)))
+
+	// Restricting the day of month and day of week matches either
+	schedule, err = snapshotScheduleParse("30 14 15 * 1")
+	assert.NoError(t, err)
+	assert.True(t, schedule.Matches(monday))
+	assert.True(t, schedule.Matches(monday.AddDate(0, 0, 13)))
+	assert.False(t, schedule.Matches(monday.AddDate(0, 0, 1)))
+}
+
+func TestSnapshotExpiryDate(t *testing.T) {
+	now := time.Date(2017, time.October, 2, 14, 30, 0, 0, time.UTC)
+
+	expiry, err := snapshotExpiryDate("", now)
+	assert.NoError(t, err)
+	assert.True(t, expiry.IsZero())
+
+	expiry, err = snapshotExpiryDate("30M 1H 2d 1w 1m 1y", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2018, time.November, 11, 16, 0, 0, 0, time.UTC), expiry)
+
+	_, err = snapshotExpiryDate("2x", now)
+	assert.Error(t, err)
+
+	_, err = snapshotExpiryDate("d", now)
+	assert.Error(t, err)
+}
+
+func TestSnapshotPatternRender(t *testing.T) {
+	now := time.Date(2017, time.October, 2, 14, 30, 0, 0, time.UTC)
+
+	name, err := snapshotPatternRender("snap%d", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "snap%d", name)
+
+	name, err = snapshotPatternRender(`daily-{{ creation_date|date:"2006-01-02" }}`, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "daily-2017-10-02", name)
+
+	_, err = snapshotPatternRender("a/b", now)
+	assert.Error(t, err)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
 )
 
 func containerState(d *Daemon, r *http.Request) Response {
@@ -155,10 +156,29 @@ func containerStatePut(d *Daemon, r *http.Request) Response {
 		return BadRequest(fmt.Errorf("unknown action %s", raw.Action))
 	}
 
+	// Notify the event listeners once the action succeeded
+	run := func(op *operation) error {
+		err := do(op)
+		if err != nil {
+			return err
+		}
+
+		action := map[shared.ContainerAction]string{
+			shared.Start:    "container-started",
+			shared.Stop:     "container-stopped",
+			shared.Restart:  "container-restarted",
+			shared.Freeze:   "container-frozen",
+			shared.Unfreeze: "container-unfrozen",
+		}[shared.ContainerAction(raw.Action)]
+		eventSendLifecycle(action, fmt.Sprintf("/%s/containers/%s", version.APIVersion, name), nil, r)
+
+		return nil
+	}
+
 	resources := map[string][]string{}
 	resources["containers"] = []string{name}
 
-	op, err := operationCreate(operationClassTask, resources, nil, do, nil, nil)
+	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
 	if err != nil {
 		return InternalError(err)
 	}
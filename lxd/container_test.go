@@ -3,6 +3,9 @@ package main
 import (
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/types"
@@ -392,6 +395,51 @@ func (suite *containerTestSuite) TestContainer_findIdmap_maxed() {
 	}
 }
 
+// Refreshing a container keeps the snapshots it shares with the source and
+// replaces the ones which were re-created on the source under the same name.
+func (suite *containerTestSuite) TestContainer_RefreshSnapshots() {
+	args := db.ContainerArgs{
+		Ctype: db.CTypeRegular,
+		Name:  "testRefresh",
+	}
+
+	c, err := containerCreateInternal(suite.d.State(), suite.d.Storage, args)
+	suite.Req.Nil(err)
+	defer c.Delete()
+
+	created := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"snap0", "snap1"} {
+		args := db.ContainerArgs{
+			Ctype:        db.CTypeSnapshot,
+			Name:         fmt.Sprintf("testRefresh%s%s", shared.SnapshotDelimiter, name),
+			CreationDate: created,
+		}
+
+		_, err := containerCreateInternal(suite.d.State(), suite.d.Storage, args)
+		suite.Req.Nil(err)
+	}
+
+	snapshot := func(name string, created time.Time) *Snapshot {
+		return &Snapshot{Name: proto.String(name), CreationDate: proto.Int64(created.Unix())}
+	}
+
+	source := []*Snapshot{
+		snapshot("snap0", created),
+		snapshot("snap1", created.Add(time.Hour)),
+		snapshot("snap2", created.Add(time.Hour)),
+	}
+
+	missing, err := migrationRefreshSnapshots(c, source)
+	suite.Req.Nil(err)
+	suite.Req.Equal(source[1:], missing)
+
+	snapshots, err := c.Snapshots()
+	suite.Req.Nil(err)
+	suite.Req.Len(snapshots, 1)
+	suite.Req.Equal("testRefresh/snap0", snapshots[0].Name())
+	suite.Req.Equal(created.Unix(), snapshots[0].CreationDate().Unix())
+}
+
 func TestContainerTestSuite(t *testing.T) {
 	suite.Run(t, new(containerTestSuite))
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"sort"
 	"strconv"
 	"sync"
@@ -10,6 +11,7 @@ import (
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
 
 	log "gopkg.in/inconshreveable/log15.v2"
 )
@@ -35,9 +37,24 @@ var containerStateCmd = Command{
 }
 
 var containerFileCmd = Command{
-	name: "containers/{name}/files",
-	get:  containerFileHandler,
-	post: containerFileHandler,
+	name:   "containers/{name}/files",
+	get:    containerFileHandler,
+	post:   containerFileHandler,
+	delete: containerFileHandler,
+}
+
+var containerMetadataCmd = Command{
+	name: "containers/{name}/metadata",
+	get:  containerMetadataGet,
+	put:  containerMetadataPut,
+}
+
+var containerMetadataTemplatesCmd = Command{
+	name:   "containers/{name}/metadata/templates",
+	get:    containerMetadataTemplatesGet,
+	post:   containerMetadataTemplatesPostPut,
+	put:    containerMetadataTemplatesPostPut,
+	delete: containerMetadataTemplatesDelete,
 }
 
 var containerSnapshotsCmd = Command{
@@ -50,9 +67,34 @@ var containerSnapshotCmd = Command{
 	name:   "containers/{name}/snapshots/{snapshotName}",
 	get:    snapshotHandler,
 	post:   snapshotHandler,
+	put:    snapshotHandler,
 	delete: snapshotHandler,
 }
 
+var containerBackupsCmd = Command{
+	name: "containers/{name}/backups",
+	get:  containerBackupsGet,
+	post: containerBackupsPost,
+}
+
+var containerBackupCmd = Command{
+	name:   "containers/{name}/backups/{backupName}",
+	get:    containerBackupHandler,
+	post:   containerBackupHandler,
+	delete: containerBackupHandler,
+}
+
+var containerBackupExportCmd = Command{
+	name: "containers/{name}/backups/{backupName}/export",
+	get:  containerBackupExportGet,
+}
+
+var containerConsoleCmd = Command{
+	name: "containers/{name}/console",
+	get:  containerConsoleLogGet,
+	post: containerConsolePost,
+}
+
 var containerExecCmd = Command{
 	name: "containers/{name}/exec",
 	post: containerExecPost,
@@ -114,7 +156,12 @@ func containersRestart(s *state.State, storage storage) error {
 				continue
 			}
 
-			c.Start(false)
+			err = c.Start(false)
+			if err != nil {
+				logger.Error("Failed to start container", log.Ctx{"container": c.Name(), "err": err})
+			} else {
+				eventSendLifecycle("container-started", fmt.Sprintf("/%s/containers/%s", version.APIVersion, c.Name()), nil, nil)
+			}
 
 			autoStartDelayInt, err := strconv.Atoi(autoStartDelay)
 			if err == nil {
@@ -15,7 +15,7 @@ import (
 
 func containersGet(d *Daemon, r *http.Request) Response {
 	for i := 0; i < 100; i++ {
-		result, err := doContainersGet(d.State(), d.Storage, util.IsRecursionRequest(r))
+		result, err := doContainersGet(d.State(), d.Storage, util.IsRecursionRequest(r), certificateFromRequest(d, r))
 		if err == nil {
 			return SyncResponse(true, result)
 		}
@@ -33,7 +33,7 @@ func containersGet(d *Daemon, r *http.Request) Response {
 	return InternalError(fmt.Errorf("DB is locked"))
 }
 
-func doContainersGet(s *state.State, storage storage, recursion bool) (interface{}, error) {
+func doContainersGet(s *state.State, storage storage, recursion bool, cert *db.CertInfo) (interface{}, error) {
 	result, err := db.ContainersList(s.DB, db.CTypeRegular)
 	if err != nil {
 		return nil, err
@@ -46,6 +46,11 @@ func doContainersGet(s *state.State, storage storage, recursion bool) (interface
 	}
 
 	for _, container := range result {
+		// Restricted certificates only see their containers
+		if !certificateAllowsRead(cert, "containers", container) {
+			continue
+		}
+
 		if !recursion {
 			url := fmt.Sprintf("/%s/containers/%s", version.APIVersion, container)
 			resultString = append(resultString, url)
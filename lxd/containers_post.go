@@ -17,11 +17,12 @@ import (
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/osarch"
+	"github.com/lxc/lxd/shared/version"
 
 	log "gopkg.in/inconshreveable/log15.v2"
 )
 
-func createFromImage(d *Daemon, req *api.ContainersPost) Response {
+func createFromImage(d *Daemon, r *http.Request, req *api.ContainersPost) Response {
 	var hash string
 	var err error
 
@@ -121,7 +122,7 @@ func createFromImage(d *Daemon, req *api.ContainersPost) Response {
 	resources := map[string][]string{}
 	resources["containers"] = []string{req.Name}
 
-	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	op, err := operationCreate(operationClassTask, resources, nil, containerCreateNotify(r, req.Name, run), nil, nil)
 	if err != nil {
 		return InternalError(err)
 	}
@@ -129,7 +130,7 @@ func createFromImage(d *Daemon, req *api.ContainersPost) Response {
 	return OperationResponse(op)
 }
 
-func createFromNone(d *Daemon, req *api.ContainersPost) Response {
+func createFromNone(d *Daemon, r *http.Request, req *api.ContainersPost) Response {
 	args := db.ContainerArgs{
 		Config:    req.Config,
 		Ctype:     db.CTypeRegular,
@@ -155,7 +156,7 @@ func createFromNone(d *Daemon, req *api.ContainersPost) Response {
 	resources := map[string][]string{}
 	resources["containers"] = []string{req.Name}
 
-	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	op, err := operationCreate(operationClassTask, resources, nil, containerCreateNotify(r, req.Name, run), nil, nil)
 	if err != nil {
 		return InternalError(err)
 	}
@@ -163,7 +164,7 @@ func createFromNone(d *Daemon, req *api.ContainersPost) Response {
 	return OperationResponse(op)
 }
 
-func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
+func createFromMigration(d *Daemon, r *http.Request, req *api.ContainersPost) Response {
 	// Validate migration mode
 	if req.Source.Mode != "pull" {
 		return NotImplemented
@@ -189,29 +190,51 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 		Profiles:     req.Profiles,
 	}
 
-	/* Only create a container from an image if we're going to
-	 * rsync over the top of it. In the case of a better file
-	 * transfer mechanism, let's just use that.
-	 *
-	 * TODO: we could invent some negotiation here, where if the
-	 * source and sink both have the same image, we can clone from
-	 * it, but we have to know before sending the snapshot that
-	 * we're sending the whole thing or just a delta from the
-	 * image, so one extra negotiation round trip is needed. An
-	 * alternative is to move actual container object to a later
-	 * point and just negotiate it over the migration control
-	 * socket. Anyway, it'll happen later :)
-	 */
-	_, _, err = db.ImageGet(d.db, req.Source.BaseImage, false, true)
-	if err == nil && d.Storage.MigrationType() == MigrationFSType_RSYNC {
-		c, err = containerCreateFromImage(d.State(), d.Storage, args, req.Source.BaseImage)
-		if err != nil {
-			return InternalError(err)
+	// Refresh the existing container if requested
+	refresh := false
+	if req.Source.Refresh {
+		c, err = containerLoadByName(d.State(), d.Storage, req.Name)
+		if err == nil {
+			if c.IsRunning() {
+				return BadRequest(fmt.Errorf("Cannot refresh a running container"))
+			}
+
+			refresh = true
 		}
-	} else {
-		c, err = containerCreateAsEmpty(d, args)
-		if err != nil {
-			return InternalError(err)
+	}
+
+	if !refresh {
+		/* Only create a container from an image if we're going to
+		 * rsync over the top of it. In the case of a better file
+		 * transfer mechanism, let's just use that.
+		 *
+		 * TODO: we could invent some negotiation here, where if the
+		 * source and sink both have the same image, we can clone from
+		 * it, but we have to know before sending the snapshot that
+		 * we're sending the whole thing or just a delta from the
+		 * image, so one extra negotiation round trip is needed. An
+		 * alternative is to move actual container object to a later
+		 * point and just negotiate it over the migration control
+		 * socket. Anyway, it'll happen later :)
+		 */
+		_, _, err = db.ImageGet(d.db, req.Source.BaseImage, false, true)
+		if err == nil && d.Storage.MigrationType() == MigrationFSType_RSYNC {
+			c, err = containerCreateFromImage(d.State(), d.Storage, args, req.Source.BaseImage)
+			if err != nil {
+				return InternalError(err)
+			}
+		} else {
+			c, err = containerCreateAsEmpty(d, args)
+			if err != nil {
+				return InternalError(err)
+			}
+		}
+	}
+
+	// Failures must not delete a container being refreshed
+	cleanup := func() {
+		if !refresh {
+			c.Delete()
 		}
 	}
 
@@ -219,20 +242,20 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 	if req.Source.Certificate != "" {
 		certBlock, _ := pem.Decode([]byte(req.Source.Certificate))
 		if certBlock == nil {
-			c.Delete()
+			cleanup()
 			return InternalError(fmt.Errorf("Invalid certificate"))
 		}
 
 		cert, err = x509.ParseCertificate(certBlock.Bytes)
 		if err != nil {
-			c.Delete()
+			cleanup()
 			return InternalError(err)
 		}
 	}
 
 	config, err := shared.GetTLSConfig("", "", "", cert)
 	if err != nil {
-		c.Delete()
+		cleanup()
 		return InternalError(err)
 	}
 
@@ -243,18 +266,24 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 			NetDial:         shared.RFC3493Dialer},
 		Container: c,
 		Secrets:   req.Source.Websockets,
+		Refresh:   refresh,
 	}
 
 	sink, err := NewMigrationSink(&migrationArgs)
 	if err != nil {
-		c.Delete()
+		cleanup()
 		return InternalError(err)
 	}
 
 	resources := map[string][]string{}
 	resources["containers"] = []string{req.Name}
 
-	op, err := operationCreate(operationClassTask, resources, nil, sink.Do, nil, nil)
+	run := containerCreateNotify(r, req.Name, sink.Do)
+	if refresh {
+		run = sink.Do
+	}
+
+	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
 	if err != nil {
 		return InternalError(err)
 	}
@@ -262,7 +291,7 @@ func createFromMigration(d *Daemon, req *api.ContainersPost) Response {
 	return OperationResponse(op)
 }
 
-func createFromCopy(d *Daemon, req *api.ContainersPost) Response {
+func createFromCopy(d *Daemon, r *http.Request, req *api.ContainersPost) Response {
 	if req.Source.Source == "" {
 		return BadRequest(fmt.Errorf("must specify a source container"))
 	}
@@ -326,6 +355,30 @@ func createFromCopy(d *Daemon, req *api.ContainersPost) Response {
 		Profiles:     req.Profiles,
 	}
 
+	// Refresh an existing container instead of creating a new one
+	if req.Source.Refresh {
+		c, err := containerLoadByName(d.State(), d.Storage, req.Name)
+		if err == nil {
+			if c.IsRunning() {
+				return BadRequest(fmt.Errorf("Cannot refresh a running container"))
+			}
+
+			run := func(op *operation) error {
+				return containerRefreshFromCopy(c, source)
+			}
+
+			resources := map[string][]string{}
+			resources["containers"] = []string{req.Name, req.Source.Source}
+
+			op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+			if err != nil {
+				return InternalError(err)
+			}
+
+			return OperationResponse(op)
+		}
+	}
+
 	run := func(op *operation) error {
 		_, err := containerCreateAsCopy(d.State(), d.Storage, args, source)
 		if err != nil {
@@ -338,7 +391,7 @@ func createFromCopy(d *Daemon, req *api.ContainersPost) Response {
 	resources := map[string][]string{}
 	resources["containers"] = []string{req.Name, req.Source.Source}
 
-	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	op, err := operationCreate(operationClassTask, resources, nil, containerCreateNotify(r, req.Name, run), nil, nil)
 	if err != nil {
 		return InternalError(err)
 	}
@@ -346,9 +399,29 @@ func createFromCopy(d *Daemon, req *api.ContainersPost) Response {
 	return OperationResponse(op)
 }
 
+// containerCreateNotify wraps the function creating a container so that the
+// event listeners get notified once it succeeded.
+func containerCreateNotify(r *http.Request, name string, run func(op *operation) error) func(op *operation) error {
+	return func(op *operation) error {
+		err := run(op)
+		if err != nil {
+			return err
+		}
+
+		eventSendLifecycle("container-created", fmt.Sprintf("/%s/containers/%s", version.APIVersion, name), nil, r)
+
+		return nil
+	}
+}
+
 func containersPost(d *Daemon, r *http.Request) Response {
 	logger.Debugf("Responding to container create")
 
+	// A raw body is a backup to import
+	if r.Header.Get("Content-Type") == "application/octet-stream" {
+		return createFromBackup(d, r, r.Body)
+	}
+
 	req := api.ContainersPost{}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		return BadRequest(err)
@@ -402,13 +475,13 @@ func containersPost(d *Daemon, r *http.Request) Response {
 
 	switch req.Source.Type {
 	case "image":
-		return createFromImage(d, &req)
+		return createFromImage(d, r, &req)
 	case "none":
-		return createFromNone(d, &req)
+		return createFromNone(d, r, &req)
 	case "migration":
-		return createFromMigration(d, &req)
+		return createFromMigration(d, r, &req)
 	case "copy":
-		return createFromCopy(d, &req)
+		return createFromCopy(d, r, &req)
 	default:
 		return BadRequest(fmt.Errorf("unknown source type %s", req.Source.Type))
 	}
@@ -66,6 +66,7 @@ type Socket struct {
 // A Daemon can respond to requests from a shared client.
 type Daemon struct {
 	clientCerts         []x509.Certificate
+	clientCertsInfo     map[string]*db.CertInfo
 	os                  *sys.OS
 	db                  *sql.DB
 	group               string
@@ -145,6 +146,14 @@ func (d *Daemon) createCmd(version string, c Command) {
 		w.Header().Set("Content-Type", "application/json")
 
 		if util.IsTrustedClient(r, d.clientCerts) {
+			if !certificateAccessAllowed(d, r, c) {
+				logger.Warn(
+					"rejecting request not permitted by the client certificate",
+					log.Ctx{"method": r.Method, "url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+				Forbidden.Render(w)
+				return
+			}
+
 			logger.Debug(
 				"handling",
 				log.Ctx{"method": r.Method, "url": r.URL.RequestURI(), "ip": r.RemoteAddr})
@@ -646,6 +655,10 @@ func (d *Daemon) Init() error {
 		d.createCmd("internal", c)
 	}
 
+	for _, c := range apiSimpleStreams {
+		d.createCmd("streams", c)
+	}
+
 	d.mux.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("Sending top level 404", log.Ctx{"url": r.URL})
 		w.Header().Set("Content-Type", "application/json")
@@ -811,6 +824,18 @@ func (d *Daemon) Ready() error {
 		}
 	}()
 
+	/* Scheduled container snapshots */
+	go func() {
+		for {
+			// Wake up at the start of every minute
+			now := time.Now()
+			time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+
+			autoCreateContainerSnapshots(d, time.Now())
+			pruneExpiredContainerSnapshots(d)
+		}
+	}()
+
 	/* Auto-update instance types */
 	go func() {
 		// Background update
@@ -822,6 +847,14 @@ func (d *Daemon) Ready() error {
 
 	s := d.State()
 
+	/* Setup the networks */
+	if !d.os.MockMode {
+		err := networkStartup(s)
+		if err != nil {
+			return err
+		}
+	}
+
 	/* Restore containers */
 	containersRestart(s, d.Storage)
 
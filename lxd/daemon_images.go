@@ -314,6 +314,14 @@ func (d *Daemon) ImageDownload(op *operation, server string, protocol string, ce
 
 		if meta["download_progress"] != progress.Text {
 			meta["download_progress"] = progress.Text
+
+			// Structured progress, only available for file transfers
+			if progress.TransferredBytes > 0 {
+				meta["download_progress_bytes"] = progress.TransferredBytes
+				meta["download_progress_total"] = progress.TotalBytes
+				meta["download_progress_speed"] = progress.Speed
+			}
+
 			op.UpdateMetadata(meta)
 		}
 	}
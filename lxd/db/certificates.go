@@ -6,6 +6,12 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Types of the resources a certificate can be restricted to
+const (
+	CertResourceContainer = iota
+	CertResourceProfile
+)
+
 // CertInfo is here to pass the certificates content
 // from the database around
 type CertInfo struct {
@@ -14,20 +20,21 @@ type CertInfo struct {
 	Type        int
 	Name        string
 	Certificate string
+	Role        string
+	Containers  []string
+	Profiles    []string
 }
 
 // CertsGet returns all certificates from the DB as CertBaseInfo objects.
 func CertsGet(db *sql.DB) (certs []*CertInfo, err error) {
 	rows, err := dbQuery(
 		db,
-		"SELECT id, fingerprint, type, name, certificate FROM certificates",
+		"SELECT id, fingerprint, type, name, certificate, role FROM certificates",
 	)
 	if err != nil {
 		return certs, err
 	}
 
-	defer rows.Close()
-
 	for rows.Next() {
 		cert := new(CertInfo)
 		rows.Scan(
@@ -36,13 +43,75 @@ func CertsGet(db *sql.DB) (certs []*CertInfo, err error) {
 			&cert.Type,
 			&cert.Name,
 			&cert.Certificate,
+			&cert.Role,
 		)
 		certs = append(certs, cert)
 	}
+	rows.Close()
+
+	for _, cert := range certs {
+		err = certResourcesGet(db, cert)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return certs, nil
 }
 
+// certResourcesGet fills in the containers and profiles the given
+// certificate is restricted to.
+func certResourcesGet(db *sql.DB, cert *CertInfo) error {
+	var resourceType int
+	var name string
+	q := "SELECT type, name FROM certificates_resources WHERE certificate_id=? ORDER BY name"
+	inargs := []interface{}{cert.ID}
+	outfmt := []interface{}{resourceType, name}
+	results, err := QueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return err
+	}
+
+	cert.Containers = []string{}
+	cert.Profiles = []string{}
+	for _, r := range results {
+		switch r[0].(int) {
+		case CertResourceContainer:
+			cert.Containers = append(cert.Containers, r[1].(string))
+		case CertResourceProfile:
+			cert.Profiles = append(cert.Profiles, r[1].(string))
+		}
+	}
+
+	return nil
+}
+
+// certResourcesAdd records the containers and profiles the certificate with
+// the given ID is restricted to.
+func certResourcesAdd(tx *sql.Tx, id int64, containers []string, profiles []string) error {
+	stmt, err := tx.Prepare("INSERT INTO certificates_resources (certificate_id, type, name) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, name := range containers {
+		_, err = stmt.Exec(id, CertResourceContainer, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range profiles {
+		_, err = stmt.Exec(id, CertResourceProfile, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // CertGet gets an CertBaseInfo object from the database.
 // The argument fingerprint will be queried with a LIKE query, means you can
 // pass a shortform and will get the full fingerprint.
@@ -58,11 +127,12 @@ func CertGet(db *sql.DB, fingerprint string) (cert *CertInfo, err error) {
 		&cert.Type,
 		&cert.Name,
 		&cert.Certificate,
+		&cert.Role,
 	}
 
 	query := `
 		SELECT
-			id, fingerprint, type, name, certificate
+			id, fingerprint, type, name, certificate, role
 		FROM
 			certificates
 		WHERE fingerprint LIKE ?`
@@ -71,6 +141,11 @@ func CertGet(db *sql.DB, fingerprint string) (cert *CertInfo, err error) {
 		return nil, err
 	}
 
+	err = certResourcesGet(db, cert)
+	if err != nil {
+		return nil, err
+	}
+
 	return cert, err
 }
 
@@ -86,25 +161,73 @@ func CertSave(db *sql.DB, cert *CertInfo) error {
 				fingerprint,
 				type,
 				name,
-				certificate
-			) VALUES (?, ?, ?, ?)`,
+				certificate,
+				role
+			) VALUES (?, ?, ?, ?, ?)`,
 	)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
-	_, err = stmt.Exec(
+	result, err := stmt.Exec(
 		cert.Fingerprint,
 		cert.Type,
 		cert.Name,
 		cert.Certificate,
+		cert.Role,
 	)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = certResourcesAdd(tx, id, cert.Containers, cert.Profiles)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return TxCommit(tx)
+}
+
+// CertUpdate updates the name, role and restrictions of the certificate with
+// the given fingerprint.
+func CertUpdate(db *sql.DB, fingerprint string, name string, role string, containers []string, profiles []string) error {
+	cert, err := CertGet(db, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	tx, err := Begin(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE certificates SET name=?, role=? WHERE id=?", name, role, cert.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM certificates_resources WHERE certificate_id=?", cert.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = certResourcesAdd(tx, int64(cert.ID), containers, profiles)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return TxCommit(tx)
 }
 
@@ -23,8 +23,10 @@ type ContainerArgs struct {
 	Config       map[string]string
 	CreationDate time.Time
 	Ctype        ContainerType
+	Description  string
 	Devices      types.Devices
 	Ephemeral    bool
+	ExpiryDate   time.Time
 	Name         string
 	Profiles     []string
 	Stateful     bool
@@ -76,14 +78,23 @@ func ContainerGet(db *sql.DB, name string) (ContainerArgs, error) {
 
 	ephemInt := -1
 	statefulInt := -1
-	q := "SELECT id, architecture, type, ephemeral, stateful, creation_date FROM containers WHERE name=?"
+	description := sql.NullString{}
+	expiryDate := time.Time{}
+	q := "SELECT id, architecture, type, ephemeral, stateful, creation_date, description, expiry_date FROM containers WHERE name=?"
 	arg1 := []interface{}{name}
-	arg2 := []interface{}{&args.Id, &args.Architecture, &args.Ctype, &ephemInt, &statefulInt, &args.CreationDate}
+	arg2 := []interface{}{&args.Id, &args.Architecture, &args.Ctype, &ephemInt, &statefulInt, &args.CreationDate, &description, &expiryDate}
 	err := dbQueryRowScan(db, q, arg1, arg2)
 	if err != nil {
 		return args, err
 	}
 
+	args.Description = description.String
+
+	// Containers without an expiry date are stored with 0
+	if expiryDate.Unix() != 0 {
+		args.ExpiryDate = expiryDate
+	}
+
 	if args.Id == -1 {
 		return args, fmt.Errorf("Unknown container")
 	}
@@ -143,16 +154,24 @@ func ContainerCreate(db *sql.DB, args ContainerArgs) (int, error) {
 		statefulInt = 1
 	}
 
-	args.CreationDate = time.Now().UTC()
+	if args.CreationDate.IsZero() {
+		args.CreationDate = time.Now().UTC()
+	}
 
-	str := fmt.Sprintf("INSERT INTO containers (name, architecture, type, ephemeral, creation_date, stateful) VALUES (?, ?, ?, ?, ?, ?)")
+	// Containers without an expiry date are stored with 0
+	expiryDate := int64(0)
+	if !args.ExpiryDate.IsZero() {
+		expiryDate = args.ExpiryDate.Unix()
+	}
+
+	str := fmt.Sprintf("INSERT INTO containers (name, architecture, type, ephemeral, creation_date, stateful, expiry_date, description) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
 	stmt, err := tx.Prepare(str)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
 	}
 	defer stmt.Close()
-	result, err := stmt.Exec(args.Name, args.Architecture, args.Ctype, ephemInt, args.CreationDate.Unix(), statefulInt)
+	result, err := stmt.Exec(args.Name, args.Architecture, args.Ctype, ephemInt, args.CreationDate.Unix(), statefulInt, expiryDate, args.Description)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
@@ -445,3 +464,36 @@ func ContainerGetSnapshots(db *sql.DB, name string) ([]string, error) {
 
 	return result, nil
 }
+
+// ContainerSnapshotUpdate updates the description and expiry date of a
+// snapshot. A zero expiry date means the snapshot never expires.
+func ContainerSnapshotUpdate(db *sql.DB, id int, description string, expiryDate time.Time) error {
+	expiry := int64(0)
+	if !expiryDate.IsZero() {
+		expiry = expiryDate.Unix()
+	}
+
+	_, err := Exec(db, "UPDATE containers SET description=?, expiry_date=? WHERE id=? AND type=?", description, expiry, id, CTypeSnapshot)
+	return err
+}
+
+// ContainerSnapshotsExpired returns the names of the snapshots whose expiry
+// date is past the given time.
+func ContainerSnapshotsExpired(db *sql.DB, now time.Time) ([]string, error) {
+	result := []string{}
+
+	var name string
+	q := "SELECT name FROM containers WHERE type=? AND expiry_date > 0 AND expiry_date <= ?"
+	inargs := []interface{}{CTypeSnapshot, now.Unix()}
+	outfmt := []interface{}{name}
+	dbResults, err := QueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return result, err
+	}
+
+	for _, r := range dbResults {
+		result = append(result, r[0].(string))
+	}
+
+	return result, nil
+}
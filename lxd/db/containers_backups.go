@@ -0,0 +1,111 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ContainerBackupArgs is a value object holding all db-related details
+// about a backup.
+type ContainerBackupArgs struct {
+	ID               int
+	ContainerID      int
+	Name             string
+	CreationDate     time.Time
+	ContainerOnly    bool
+	OptimizedStorage bool
+}
+
+// ContainerGetBackups returns the names of all the backups of the container
+// with the given name.
+func ContainerGetBackups(db *sql.DB, name string) ([]string, error) {
+	result := []string{}
+
+	var backupName string
+	q := `SELECT containers_backups.name FROM containers_backups
+JOIN containers ON containers_backups.container_id=containers.id
+WHERE containers.name=? ORDER BY containers_backups.name`
+	inargs := []interface{}{name}
+	outfmt := []interface{}{backupName}
+	dbResults, err := QueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return result, err
+	}
+
+	for _, r := range dbResults {
+		result = append(result, r[0].(string))
+	}
+
+	return result, nil
+}
+
+// ContainerBackupGet returns the backup of the given container with the
+// given name.
+func ContainerBackupGet(db *sql.DB, containerName string, name string) (ContainerBackupArgs, error) {
+	args := ContainerBackupArgs{}
+	args.Name = name
+
+	containerOnlyInt := -1
+	optimizedStorageInt := -1
+	q := `SELECT containers_backups.id, containers_backups.container_id, containers_backups.creation_date,
+    containers_backups.container_only, containers_backups.optimized_storage
+FROM containers_backups JOIN containers ON containers_backups.container_id=containers.id
+WHERE containers.name=? AND containers_backups.name=?`
+	arg1 := []interface{}{containerName, name}
+	arg2 := []interface{}{&args.ID, &args.ContainerID, &args.CreationDate, &containerOnlyInt, &optimizedStorageInt}
+	err := dbQueryRowScan(db, q, arg1, arg2)
+	if err != nil {
+		return args, err
+	}
+
+	args.ContainerOnly = containerOnlyInt == 1
+	args.OptimizedStorage = optimizedStorageInt == 1
+
+	return args, nil
+}
+
+// ContainerBackupCreate adds a new backup to the database.
+func ContainerBackupCreate(db *sql.DB, args ContainerBackupArgs) (int, error) {
+	containerOnlyInt := 0
+	if args.ContainerOnly {
+		containerOnlyInt = 1
+	}
+
+	optimizedStorageInt := 0
+	if args.OptimizedStorage {
+		optimizedStorageInt = 1
+	}
+
+	if args.CreationDate.IsZero() {
+		args.CreationDate = time.Now().UTC()
+	}
+
+	result, err := Exec(db, "INSERT INTO containers_backups (container_id, name, creation_date, container_only, optimized_storage) VALUES (?, ?, ?, ?, ?)",
+		args.ContainerID, args.Name, args.CreationDate.Unix(), containerOnlyInt, optimizedStorageInt)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("Error inserting %s into database", args.Name)
+	}
+
+	return int(id), nil
+}
+
+// ContainerBackupRemove removes the backup with the given ID from the
+// database.
+func ContainerBackupRemove(db *sql.DB, id int) error {
+	_, err := Exec(db, "DELETE FROM containers_backups WHERE id=?", id)
+	return err
+}
+
+// ContainerBackupRename renames the backup with the given ID.
+func ContainerBackupRename(db *sql.DB, id int, newName string) error {
+	_, err := Exec(db, "UPDATE containers_backups SET name=? WHERE id=?", newName, id)
+	return err
+}
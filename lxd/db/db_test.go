@@ -477,3 +477,232 @@ func (s *dbTestSuite) Test_dbDevices_containers() {
 			fmt.Sprintf("Mismatching value for key %s: %s != %s", key, subresult[key], value))
 	}
 }
+
+func (s *dbTestSuite) Test_dbStoragePools() {
+	var err error
+
+	id, err := StoragePoolCreate(s.db, "fast", "zfs", "SSD", map[string]string{"zfs.pool_name": "ssd/lxd"})
+	s.Nil(err)
+
+	names, err := StoragePools(s.db)
+	s.Nil(err)
+	s.Equal([]string{"fast"}, names)
+
+	poolID, pool, err := StoragePoolGet(s.db, "fast")
+	s.Nil(err)
+	s.Equal(id, poolID)
+	s.Equal("zfs", pool.Driver)
+	s.Equal("SSD", pool.Description)
+	s.Equal(map[string]string{"zfs.pool_name": "ssd/lxd"}, pool.Config)
+
+	err = StoragePoolUpdate(s.db, id, "NVMe", map[string]string{"zfs.pool_name": "nvme/lxd"})
+	s.Nil(err)
+
+	_, pool, err = StoragePoolGet(s.db, "fast")
+	s.Nil(err)
+	s.Equal("NVMe", pool.Description)
+	s.Equal(map[string]string{"zfs.pool_name": "nvme/lxd"}, pool.Config)
+
+	err = StoragePoolDelete(s.db, "fast")
+	s.Nil(err)
+
+	_, _, err = StoragePoolGet(s.db, "fast")
+	s.Equal(sql.ErrNoRows, err)
+}
+
+func (s *dbTestSuite) Test_dbStoragePoolVolumes() {
+	var err error
+
+	id, err := StoragePoolVolumeCreate(s.db, "default", "data", StoragePoolVolumeTypeCustom, "Shared data", map[string]string{"size": "20GB"})
+	s.Nil(err)
+
+	names, err := StoragePoolVolumes(s.db, "default", StoragePoolVolumeTypeCustom)
+	s.Nil(err)
+	s.Equal([]string{"data"}, names)
+
+	volumeID, volume, err := StoragePoolVolumeGet(s.db, "default", "data", StoragePoolVolumeTypeCustom)
+	s.Nil(err)
+	s.Equal(id, volumeID)
+	s.Equal("Shared data", volume.Description)
+	s.Equal(map[string]string{"size": "20GB"}, volume.Config)
+
+	_, _, err = StoragePoolVolumeGet(s.db, "other", "data", StoragePoolVolumeTypeCustom)
+	s.Equal(sql.ErrNoRows, err)
+
+	err = StoragePoolVolumeUpdate(s.db, id, "", map[string]string{"size": "20GB", "volatile.idmap.last": "[]"})
+	s.Nil(err)
+
+	_, volume, err = StoragePoolVolumeGet(s.db, "default", "data", StoragePoolVolumeTypeCustom)
+	s.Nil(err)
+	s.Equal("", volume.Description)
+	s.Equal("[]", volume.Config["volatile.idmap.last"])
+
+	err = StoragePoolVolumeRename(s.db, id, "shared")
+	s.Nil(err)
+
+	_, _, err = StoragePoolVolumeGet(s.db, "default", "data", StoragePoolVolumeTypeCustom)
+	s.Equal(sql.ErrNoRows, err)
+
+	volumeID, volume, err = StoragePoolVolumeGet(s.db, "default", "shared", StoragePoolVolumeTypeCustom)
+	s.Nil(err)
+	s.Equal(id, volumeID)
+	s.Equal("[]", volume.Config["volatile.idmap.last"])
+
+	err = StoragePoolVolumeDelete(s.db, id)
+	s.Nil(err)
+
+	names, err = StoragePoolVolumes(s.db, "default", StoragePoolVolumeTypeCustom)
+	s.Nil(err)
+	s.Equal([]string{}, names)
+}
+
+func (s *dbTestSuite) Test_dbNetworks() {
+	var err error
+
+	id, err := NetworkCreate(s.db, "lxdbr1", "Test bridge", map[string]string{"ipv4.address": "10.0.3.1/24", "ipv6.address": ""})
+	s.Nil(err)
+
+	networkID, network, err := NetworkGet(s.db, "lxdbr1")
+	s.Nil(err)
+	s.Equal(id, networkID)
+	s.True(network.Managed)
+	s.Equal("Test bridge", network.Description)
+	s.Equal(map[string]string{"ipv4.address": "10.0.3.1/24"}, network.Config)
+
+	err = NetworkUpdate(s.db, id, "", map[string]string{"ipv4.address": "none"})
+	s.Nil(err)
+
+	err = NetworkRename(s.db, "lxdbr1", "lxdbr2")
+	s.Nil(err)
+
+	names, err := Networks(s.db)
+	s.Nil(err)
+	s.Equal([]string{"lxdbr2"}, names)
+
+	_, network, err = NetworkGet(s.db, "lxdbr2")
+	s.Nil(err)
+	s.Equal("none", network.Config["ipv4.address"])
+
+	err = NetworkDelete(s.db, "lxdbr2")
+	s.Nil(err)
+
+	_, _, err = NetworkGet(s.db, "lxdbr2")
+	s.Equal(sql.ErrNoRows, err)
+}
+
+func (s *dbTestSuite) Test_dbCertificates() {
+	var err error
+
+	cert := &CertInfo{
+		Fingerprint: "abcdef",
+		Type:        1,
+		Name:        "ci",
+		Certificate: "PEM",
+		Role:        "operator",
+		Containers:  []string{"c2", "c1"},
+	}
+
+	err = CertSave(s.db, cert)
+	s.Nil(err)
+
+	cert, err = CertGet(s.db, "abc")
+	s.Nil(err)
+	s.Equal("abcdef", cert.Fingerprint)
+	s.Equal("operator", cert.Role)
+	s.Equal([]string{"c1", "c2"}, cert.Containers)
+	s.Equal([]string{}, cert.Profiles)
+
+	err = CertUpdate(s.db, "abcdef", "ci2", "read-only", nil, []string{"default"})
+	s.Nil(err)
+
+	certs, err := CertsGet(s.db)
+	s.Nil(err)
+	s.Len(certs, 1)
+	s.Equal("ci2", certs[0].Name)
+	s.Equal("read-only", certs[0].Role)
+	s.Equal([]string{}, certs[0].Containers)
+	s.Equal([]string{"default"}, certs[0].Profiles)
+
+	err = CertDelete(s.db, "abcdef")
+	s.Nil(err)
+
+	var count int
+	err = s.db.QueryRow("SELECT count(*) FROM certificates_resources").Scan(&count)
+	s.Nil(err)
+	s.Equal(0, count)
+}
+
+func (s *dbTestSuite) Test_ContainerSnapshotsExpired() {
+	var err error
+
+	now := time.Now()
+	for name, expiry := range map[string]time.Time{"thename/old": now.Add(-time.Hour), "thename/new": now.Add(time.Hour), "thename/kept": {}} {
+		_, err = ContainerCreate(s.db, ContainerArgs{Name: name, Ctype: CTypeSnapshot, ExpiryDate: expiry})
+		s.Nil(err)
+	}
+
+	names, err := ContainerSnapshotsExpired(s.db, now)
+	s.Nil(err)
+	s.Equal([]string{"thename/old"}, names)
+}
+
+func (s *dbTestSuite) Test_ContainerSnapshotUpdate() {
+	var err error
+
+	id, err := ContainerCreate(s.db, ContainerArgs{Name: "thename/snap0", Ctype: CTypeSnapshot, Description: "before"})
+	s.Nil(err)
+
+	args, err := ContainerGet(s.db, "thename/snap0")
+	s.Nil(err)
+	s.Equal("before", args.Description)
+	s.True(args.ExpiryDate.IsZero())
+
+	expiry := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	err = ContainerSnapshotUpdate(s.db, id, "after", expiry)
+	s.Nil(err)
+
+	args, err = ContainerGet(s.db, "thename/snap0")
+	s.Nil(err)
+	s.Equal("after", args.Description)
+	s.True(expiry.Equal(args.ExpiryDate))
+
+	err = ContainerSnapshotUpdate(s.db, id, "", time.Time{})
+	s.Nil(err)
+
+	args, err = ContainerGet(s.db, "thename/snap0")
+	s.Nil(err)
+	s.True(args.ExpiryDate.IsZero())
+}
+
+func (s *dbTestSuite) Test_ContainerBackups() {
+	var err error
+
+	id, err := ContainerId(s.db, "thename")
+	s.Nil(err)
+
+	_, err = ContainerBackupCreate(s.db, ContainerBackupArgs{ContainerID: id, Name: "backup1", OptimizedStorage: true})
+	s.Nil(err)
+
+	_, err = ContainerBackupCreate(s.db, ContainerBackupArgs{ContainerID: id, Name: "backup1"})
+	s.NotNil(err)
+
+	backup, err := ContainerBackupGet(s.db, "thename", "backup1")
+	s.Nil(err)
+	s.Equal(id, backup.ContainerID)
+	s.True(backup.OptimizedStorage)
+	s.False(backup.ContainerOnly)
+
+	err = ContainerBackupRename(s.db, backup.ID, "backup2")
+	s.Nil(err)
+
+	names, err := ContainerGetBackups(s.db, "thename")
+	s.Nil(err)
+	s.Equal([]string{"backup2"}, names)
+
+	err = ContainerBackupRemove(s.db, backup.ID)
+	s.Nil(err)
+
+	names, err = ContainerGetBackups(s.db, "thename")
+	s.Nil(err)
+	s.Equal([]string{}, names)
+}
@@ -21,6 +21,12 @@ func dbDeviceTypeToString(t int) (string, error) {
 		return "unix-char", nil
 	case 4:
 		return "unix-block", nil
+	case 5:
+		return "gpu", nil
+	case 6:
+		return "usb", nil
+	case 7:
+		return "proxy", nil
 	default:
 		return "", fmt.Errorf("Invalid device type %d", t)
 	}
@@ -38,6 +44,12 @@ func dbDeviceTypeToInt(t string) (int, error) {
 		return 3, nil
 	case "unix-block":
 		return 4, nil
+	case "gpu":
+		return 5, nil
+	case "usb":
+		return 6, nil
+	case "proxy":
+		return 7, nil
 	default:
 		return -1, fmt.Errorf("Invalid device type %s", t)
 	}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Networks returns the names of all the networks managed by LXD.
+func Networks(db *sql.DB) ([]string, error) {
+	q := "SELECT name FROM networks"
+	inargs := []interface{}{}
+	var name string
+	outfmt := []interface{}{name}
+	result, err := QueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return []string{}, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// NetworkGet returns the ID and content of the managed network with the given
+// name.
+func NetworkGet(db *sql.DB, name string) (int64, *api.Network, error) {
+	id := int64(-1)
+	description := sql.NullString{}
+
+	q := "SELECT id, description FROM networks WHERE name=?"
+	arg1 := []interface{}{name}
+	arg2 := []interface{}{&id, &description}
+	err := dbQueryRowScan(db, q, arg1, arg2)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	config, err := NetworkConfigGet(db, id)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	network := api.Network{
+		Name:    name,
+		Managed: true,
+		Type:    "bridge",
+	}
+	network.Description = description.String
+	network.Config = config
+
+	return id, &network, nil
+}
+
+// NetworkConfigGet returns the configuration of the network with the given
+// ID.
+func NetworkConfigGet(db *sql.DB, id int64) (map[string]string, error) {
+	var key, value string
+	q := "SELECT key, value FROM networks_config WHERE network_id=?"
+	inargs := []interface{}{id}
+	outfmt := []interface{}{key, value}
+	results, err := QueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]string{}
+	for _, r := range results {
+		key = r[0].(string)
+		value = r[1].(string)
+
+		config[key] = value
+	}
+
+	return config, nil
+}
+
+// NetworkCreate adds a new managed network to the database.
+func NetworkCreate(db *sql.DB, name string, description string, config map[string]string) (int64, error) {
+	tx, err := Begin(db)
+	if err != nil {
+		return -1, err
+	}
+
+	result, err := tx.Exec("INSERT INTO networks (name, description) VALUES (?, ?)", name, description)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	err = NetworkConfigAdd(tx, id, config)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	err = TxCommit(tx)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// NetworkUpdate replaces the description and configuration of the network
+// with the given ID.
+func NetworkUpdate(db *sql.DB, id int64, description string, config map[string]string) error {
+	tx, err := Begin(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE networks SET description=? WHERE id=?", description, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = NetworkConfigClear(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = NetworkConfigAdd(tx, id, config)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return TxCommit(tx)
+}
+
+// NetworkConfigAdd adds the given configuration keys to the network with the
+// given ID.
+func NetworkConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	str := "INSERT INTO networks_config (network_id, key, value) VALUES(?, ?, ?)"
+	stmt, err := tx.Prepare(str)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NetworkConfigClear removes all configuration keys of the network with the
+// given ID.
+func NetworkConfigClear(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec("DELETE FROM networks_config WHERE network_id=?", id)
+	return err
+}
+
+// NetworkRename changes the name of a managed network.
+func NetworkRename(db *sql.DB, oldName string, newName string) error {
+	_, err := Exec(db, "UPDATE networks SET name=? WHERE name=?", newName, oldName)
+	return err
+}
+
+// NetworkDelete removes the managed network with the given name from the
+// database, along with its configuration.
+func NetworkDelete(db *sql.DB, name string) error {
+	id, _, err := NetworkGet(db, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = Exec(db, "DELETE FROM networks WHERE id=?", id)
+	return err
+}
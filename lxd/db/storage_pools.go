@@ -0,0 +1,187 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// StoragePools returns the names of all the storage pools defined in the
+// database.
+func StoragePools(db *sql.DB) ([]string, error) {
+	q := "SELECT name FROM storage_pools"
+	inargs := []interface{}{}
+	var name string
+	outfmt := []interface{}{name}
+	result, err := QueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return []string{}, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// StoragePoolGet returns the ID and content of the storage pool with the
+// given name.
+func StoragePoolGet(db *sql.DB, name string) (int64, *api.StoragePool, error) {
+	id := int64(-1)
+	driver := ""
+	description := sql.NullString{}
+
+	q := "SELECT id, driver, description FROM storage_pools WHERE name=?"
+	arg1 := []interface{}{name}
+	arg2 := []interface{}{&id, &driver, &description}
+	err := dbQueryRowScan(db, q, arg1, arg2)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	config, err := StoragePoolConfigGet(db, id)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	pool := api.StoragePool{
+		Name:   name,
+		Driver: driver,
+	}
+	pool.Description = description.String
+	pool.Config = config
+
+	return id, &pool, nil
+}
+
+// StoragePoolConfigGet returns the configuration of the storage pool with the
+// given ID.
+func StoragePoolConfigGet(db *sql.DB, id int64) (map[string]string, error) {
+	var key, value string
+	q := "SELECT key, value FROM storage_pools_config WHERE storage_pool_id=?"
+	inargs := []interface{}{id}
+	outfmt := []interface{}{key, value}
+	results, err := QueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]string{}
+	for _, r := range results {
+		key = r[0].(string)
+		value = r[1].(string)
+
+		config[key] = value
+	}
+
+	return config, nil
+}
+
+// StoragePoolCreate adds a new storage pool to the database.
+func StoragePoolCreate(db *sql.DB, name string, driver string, description string, config map[string]string) (int64, error) {
+	tx, err := Begin(db)
+	if err != nil {
+		return -1, err
+	}
+
+	result, err := tx.Exec("INSERT INTO storage_pools (name, driver, description) VALUES (?, ?, ?)", name, driver, description)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	err = StoragePoolConfigAdd(tx, id, config)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	err = TxCommit(tx)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// StoragePoolUpdate replaces the description and configuration of the
+// storage pool with the given ID.
+func StoragePoolUpdate(db *sql.DB, id int64, description string, config map[string]string) error {
+	tx, err := Begin(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE storage_pools SET description=? WHERE id=?", description, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = StoragePoolConfigClear(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = StoragePoolConfigAdd(tx, id, config)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return TxCommit(tx)
+}
+
+// StoragePoolConfigAdd adds the given configuration keys to the storage pool
+// with the given ID.
+func StoragePoolConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	str := "INSERT INTO storage_pools_config (storage_pool_id, key, value) VALUES(?, ?, ?)"
+	stmt, err := tx.Prepare(str)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StoragePoolConfigClear removes all configuration keys of the storage pool
+// with the given ID.
+func StoragePoolConfigClear(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec("DELETE FROM storage_pools_config WHERE storage_pool_id=?", id)
+	return err
+}
+
+// StoragePoolDelete removes the storage pool with the given name from the
+// database, along with its configuration.
+func StoragePoolDelete(db *sql.DB, name string) error {
+	id, _, err := StoragePoolGet(db, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = Exec(db, "DELETE FROM storage_pools WHERE id=?", id)
+	return err
+}
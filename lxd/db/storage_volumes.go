@@ -0,0 +1,195 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// StoragePoolVolumeType is the type of a storage volume
+type StoragePoolVolumeType int
+
+const (
+	// StoragePoolVolumeTypeCustom is a volume created through the API,
+	// independent of any container or image.
+	StoragePoolVolumeTypeCustom StoragePoolVolumeType = 0
+)
+
+// StoragePoolVolumes returns the names of all the storage volumes of the
+// given type on a storage pool.
+func StoragePoolVolumes(db *sql.DB, pool string, volumeType StoragePoolVolumeType) ([]string, error) {
+	q := "SELECT name FROM storage_volumes WHERE storage_pool=? AND type=?"
+	inargs := []interface{}{pool, volumeType}
+	var name string
+	outfmt := []interface{}{name}
+	result, err := QueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return []string{}, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// StoragePoolVolumeGet returns the ID and content of a storage volume. The
+// Type field of the returned volume is left for the caller to fill in.
+func StoragePoolVolumeGet(db *sql.DB, pool string, name string, volumeType StoragePoolVolumeType) (int64, *api.StorageVolume, error) {
+	id := int64(-1)
+	description := sql.NullString{}
+
+	q := "SELECT id, description FROM storage_volumes WHERE storage_pool=? AND name=? AND type=?"
+	arg1 := []interface{}{pool, name, volumeType}
+	arg2 := []interface{}{&id, &description}
+	err := dbQueryRowScan(db, q, arg1, arg2)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	config, err := StoragePoolVolumeConfigGet(db, id)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	volume := api.StorageVolume{
+		Name: name,
+	}
+	volume.Description = description.String
+	volume.Config = config
+
+	return id, &volume, nil
+}
+
+// StoragePoolVolumeConfigGet returns the configuration of the storage volume
+// with the given ID.
+func StoragePoolVolumeConfigGet(db *sql.DB, id int64) (map[string]string, error) {
+	var key, value string
+	q := "SELECT key, value FROM storage_volumes_config WHERE storage_volume_id=?"
+	inargs := []interface{}{id}
+	outfmt := []interface{}{key, value}
+	results, err := QueryScan(db, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]string{}
+	for _, r := range results {
+		key = r[0].(string)
+		value = r[1].(string)
+
+		config[key] = value
+	}
+
+	return config, nil
+}
+
+// StoragePoolVolumeCreate adds a new storage volume to the database.
+func StoragePoolVolumeCreate(db *sql.DB, pool string, name string, volumeType StoragePoolVolumeType, description string, config map[string]string) (int64, error) {
+	tx, err := Begin(db)
+	if err != nil {
+		return -1, err
+	}
+
+	result, err := tx.Exec("INSERT INTO storage_volumes (storage_pool, name, type, description) VALUES (?, ?, ?, ?)", pool, name, volumeType, description)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	err = StoragePoolVolumeConfigAdd(tx, id, config)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	err = TxCommit(tx)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// StoragePoolVolumeUpdate replaces the description and configuration of the
+// storage volume with the given ID.
+func StoragePoolVolumeUpdate(db *sql.DB, id int64, description string, config map[string]string) error {
+	tx, err := Begin(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE storage_volumes SET description=? WHERE id=?", description, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = StoragePoolVolumeConfigClear(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = StoragePoolVolumeConfigAdd(tx, id, config)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return TxCommit(tx)
+}
+
+// StoragePoolVolumeConfigAdd adds the given configuration keys to the storage
+// volume with the given ID.
+func StoragePoolVolumeConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	str := "INSERT INTO storage_volumes_config (storage_volume_id, key, value) VALUES(?, ?, ?)"
+	stmt, err := tx.Prepare(str)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StoragePoolVolumeConfigClear removes all configuration keys of the storage
+// volume with the given ID.
+func StoragePoolVolumeConfigClear(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec("DELETE FROM storage_volumes_config WHERE storage_volume_id=?", id)
+	return err
+}
+
+// StoragePoolVolumeRename renames the storage volume with the given ID.
+func StoragePoolVolumeRename(db *sql.DB, id int64, name string) error {
+	_, err := Exec(db, "UPDATE storage_volumes SET name=? WHERE id=?", name, id)
+	return err
+}
+
+// StoragePoolVolumeDelete removes the storage volume with the given ID from
+// the database, along with its configuration.
+func StoragePoolVolumeDelete(db *sql.DB, id int64) error {
+	_, err := Exec(db, "DELETE FROM storage_volumes WHERE id=?", id)
+	return err
+}
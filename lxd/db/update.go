@@ -69,6 +69,13 @@ var updates = map[int]schema.Update{
 	30: updateFromV29,
 	31: updateFromV30,
 	32: updateFromV31,
+	33: updateFromV32,
+	34: updateFromV33,
+	35: updateFromV34,
+	36: updateFromV35,
+	37: updateFromV36,
+	38: updateFromV37,
+	39: updateFromV38,
 }
 
 // LegacyPatch is a "database" update that performs non-database work. They
@@ -182,6 +189,110 @@ const schemaDotGo = "package db\n\n" +
 	"%s`\n"
 
 // Schema updates begin here
+func updateFromV38(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE containers ADD COLUMN description TEXT;")
+	return err
+}
+
+func updateFromV37(tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE IF NOT EXISTS containers_backups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    container_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    creation_date DATETIME,
+    container_only INTEGER NOT NULL DEFAULT 0,
+    optimized_storage INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (container_id, name),
+    FOREIGN KEY (container_id) REFERENCES containers (id) ON DELETE CASCADE
+);`
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+func updateFromV36(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE containers ADD COLUMN expiry_date DATETIME NOT NULL DEFAULT 0;")
+	return err
+}
+
+func updateFromV35(tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE certificates ADD COLUMN role VARCHAR(255) NOT NULL DEFAULT 'admin';
+CREATE TABLE IF NOT EXISTS certificates_resources (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    certificate_id INTEGER NOT NULL,
+    type INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    UNIQUE (certificate_id, type, name),
+    FOREIGN KEY (certificate_id) REFERENCES certificates (id) ON DELETE CASCADE
+);`
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+func updateFromV34(tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE IF NOT EXISTS networks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    UNIQUE (name)
+);
+CREATE TABLE IF NOT EXISTS networks_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    network_id INTEGER NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    value TEXT,
+    UNIQUE (network_id, key),
+    FOREIGN KEY (network_id) REFERENCES networks (id) ON DELETE CASCADE
+);`
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+func updateFromV33(tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE IF NOT EXISTS storage_volumes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    storage_pool VARCHAR(255) NOT NULL,
+    type INTEGER NOT NULL,
+    description TEXT,
+    UNIQUE (storage_pool, name, type)
+);
+CREATE TABLE IF NOT EXISTS storage_volumes_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    storage_volume_id INTEGER NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    value TEXT,
+    UNIQUE (storage_volume_id, key),
+    FOREIGN KEY (storage_volume_id) REFERENCES storage_volumes (id) ON DELETE CASCADE
+);`
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+func updateFromV32(tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE IF NOT EXISTS storage_pools (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    driver VARCHAR(255) NOT NULL,
+    description TEXT,
+    UNIQUE (name)
+);
+CREATE TABLE IF NOT EXISTS storage_pools_config (
+    id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    storage_pool_id INTEGER NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    value TEXT,
+    UNIQUE (storage_pool_id, key),
+    FOREIGN KEY (storage_pool_id) REFERENCES storage_pools (id) ON DELETE CASCADE
+);`
+	_, err := tx.Exec(stmt)
+	return err
+}
+
 func updateFromV31(tx *sql.Tx) error {
 	stmt := `
 CREATE TABLE IF NOT EXISTS patches (
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,6 +21,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/types"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -47,7 +49,7 @@ func (c deviceTaskCPUs) Len() int           { return len(c) }
 func (c deviceTaskCPUs) Less(i, j int) bool { return *c[i].count < *c[j].count }
 func (c deviceTaskCPUs) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
 
-func deviceNetlinkListener() (chan []string, chan []string, error) {
+func deviceNetlinkListener() (chan []string, chan []string, chan usbDevice, error) {
 	NETLINK_KOBJECT_UEVENT := 15
 	UEVENT_BUFFER_SIZE := 2048
 
@@ -57,7 +59,7 @@ func deviceNetlinkListener() (chan []string, chan []string, error) {
 	)
 
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	nl := syscall.SockaddrNetlink{
@@ -68,23 +70,24 @@ func deviceNetlinkListener() (chan []string, chan []string, error) {
 
 	err = syscall.Bind(fd, &nl)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	chCPU := make(chan []string, 1)
 	chNetwork := make(chan []string, 0)
+	chUSB := make(chan usbDevice)
 
-	go func(chCPU chan []string, chNetwork chan []string) {
+	go func(chCPU chan []string, chNetwork chan []string, chUSB chan usbDevice) {
 		b := make([]byte, UEVENT_BUFFER_SIZE*2)
 		for {
-			_, err := syscall.Read(fd, b)
+			n, err := syscall.Read(fd, b)
 			if err != nil {
 				continue
 			}
 
 			props := map[string]string{}
 			last := 0
-			for i, e := range b {
+			for i, e := range b[:n] {
 				if i == len(b) || e == 0 {
 					msg := string(b[last+1 : i])
 					last = i
@@ -130,10 +133,24 @@ func deviceNetlinkListener() (chan []string, chan []string, error) {
 				// Network balancing is interface specific, so queue everything
 				chNetwork <- []string{props["INTERFACE"], props["ACTION"]}
 			}
+
+			if props["SUBSYSTEM"] == "usb" {
+				if props["ACTION"] != "add" && props["ACTION"] != "remove" {
+					continue
+				}
+
+				usb, err := deviceUsbFromUevent(props)
+				if err != nil {
+					continue
+				}
+
+				// Hotplug is device specific, so queue everything
+				chUSB <- usb
+			}
 		}
-	}(chCPU, chNetwork)
+	}(chCPU, chNetwork, chUSB)
 
-	return chCPU, chNetwork, nil
+	return chCPU, chNetwork, chUSB, nil
 }
 
 func parseCpuset(cpu string) ([]int, error) {
@@ -400,7 +417,7 @@ func deviceNetworkPriority(s *state.State, storage storage, netif string) {
 }
 
 func deviceEventListener(s *state.State, storage storage) {
-	chNetlinkCPU, chNetlinkNetwork, err := deviceNetlinkListener()
+	chNetlinkCPU, chNetlinkNetwork, chUSB, err := deviceNetlinkListener()
 	if err != nil {
 		logger.Errorf("scheduler: couldn't setup netlink listener")
 		return
@@ -432,6 +449,9 @@ func deviceEventListener(s *state.State, storage storage) {
 
 			logger.Debugf("Scheduler: network: %s has been added: updating network priorities", e[0])
 			deviceNetworkPriority(s, storage, e[0])
+		case e := <-chUSB:
+			logger.Debugf("Scheduler: usb: %s:%s %s at %s", e.vendorid, e.productid, e.action, e.path)
+			deviceUsbEvent(s, storage, e)
 		case e := <-deviceSchedRebalance:
 			if len(e) != 3 {
 				logger.Errorf("Scheduler: received an invalid rebalance event")
@@ -867,3 +887,458 @@ func deviceParseDiskLimit(readSpeed string, writeSpeed string) (int64, int64, in
 
 	return readBps, readIops, writeBps, writeIops, nil
 }
+
+// gpuDevice is a DRM node (card, control or render node) of a GPU on the
+// host.
+type gpuDevice struct {
+	// The DRM card number, shared by all the nodes of a GPU
+	id string
+
+	path      string
+	pci       string
+	vendorid  string
+	productid string
+
+	// The /dev/nvidia* node of NVIDIA cards
+	nvidia string
+}
+
+// matches returns whether the GPU is selected by the given gpu device.
+func (g gpuDevice) matches(m types.Device) bool {
+	if m["vendorid"] != "" && m["vendorid"] != g.vendorid {
+		return false
+	}
+
+	if m["productid"] != "" && m["productid"] != g.productid {
+		return false
+	}
+
+	if m["pci"] != "" && m["pci"] != g.pci {
+		return false
+	}
+
+	if m["id"] != "" && m["id"] != g.id {
+		return false
+	}
+
+	return true
+}
+
+// deviceLoadGpu returns the DRM nodes of all the GPUs found in the given
+// sysfs DRM class directory (usually /sys/class/drm).
+func deviceLoadGpu(drmPath string) ([]gpuDevice, error) {
+	gpus := []gpuDevice{}
+
+	ents, err := ioutil.ReadDir(drmPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return gpus, nil
+		}
+
+		return nil, err
+	}
+
+	nodeName := regexp.MustCompile(`^(card|controlD|renderD)[0-9]+$`)
+	cards := map[string]string{}
+
+	for _, ent := range ents {
+		// Skip connectors and anything which isn't a device node
+		if !nodeName.MatchString(ent.Name()) {
+			continue
+		}
+
+		// The parent device of the node is the PCI device of the GPU
+		devPath, err := filepath.EvalSymlinks(filepath.Join(drmPath, ent.Name(), "device"))
+		if err != nil {
+			continue
+		}
+
+		vendorid, err := ioutil.ReadFile(filepath.Join(devPath, "vendor"))
+		if err != nil {
+			continue
+		}
+
+		productid, err := ioutil.ReadFile(filepath.Join(devPath, "device"))
+		if err != nil {
+			continue
+		}
+
+		gpu := gpuDevice{
+			path:      filepath.Join("/dev/dri", ent.Name()),
+			pci:       filepath.Base(devPath),
+			vendorid:  strings.TrimPrefix(strings.TrimSpace(string(vendorid)), "0x"),
+			productid: strings.TrimPrefix(strings.TrimSpace(string(productid)), "0x"),
+		}
+
+		if strings.HasPrefix(ent.Name(), "card") {
+			cards[gpu.pci] = strings.TrimPrefix(ent.Name(), "card")
+
+			// NVIDIA cards come with their own device node
+			if gpu.vendorid == "10de" {
+				gpu.nvidia = deviceNvidiaCardNode(gpu.pci)
+			}
+		}
+
+		gpus = append(gpus, gpu)
+	}
+
+	// The control and render nodes share the id of their card
+	for i := range gpus {
+		gpus[i].id = cards[gpus[i].pci]
+	}
+
+	return gpus, nil
+}
+
+// deviceNvidiaCardNode returns the path of the /dev/nvidia* node of the
+// NVIDIA card with the given PCI address, or an empty string if the
+// proprietary driver isn't in use.
+func deviceNvidiaCardNode(pci string) string {
+	f, err := os.Open(filepath.Join("/proc/driver/nvidia/gpus", pci, "information"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 2)
+		if len(fields) != 2 || strings.TrimSpace(fields[0]) != "Device Minor" {
+			continue
+		}
+
+		minor, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return ""
+		}
+
+		path := fmt.Sprintf("/dev/nvidia%d", minor)
+		if !shared.PathExists(path) {
+			return ""
+		}
+
+		return path
+	}
+
+	return ""
+}
+
+// deviceNvidiaControlNodes returns the paths of the control nodes of the
+// NVIDIA driver (/dev/nvidiactl, /dev/nvidia-uvm, ...).
+func deviceNvidiaControlNodes() ([]string, error) {
+	nodes := []string{}
+
+	ents, err := ioutil.ReadDir("/dev")
+	if err != nil {
+		return nil, err
+	}
+
+	nodeName := regexp.MustCompile(`^nvidia[^0-9]+`)
+	for _, ent := range ents {
+		if !nodeName.MatchString(ent.Name()) || ent.Mode()&os.ModeCharDevice == 0 {
+			continue
+		}
+
+		nodes = append(nodes, filepath.Join("/dev", ent.Name()))
+	}
+
+	return nodes, nil
+}
+
+// deviceGpuUnixDevices returns the unix-char devices needed to pass the
+// GPUs selected by a gpu device through to a container.
+func deviceGpuUnixDevices(m types.Device) ([]types.Device, error) {
+	gpus, err := deviceLoadGpu("/sys/class/drm")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	sawNvidia := false
+	for _, gpu := range gpus {
+		if !gpu.matches(m) {
+			continue
+		}
+
+		paths = append(paths, gpu.path)
+		if gpu.nvidia != "" {
+			paths = append(paths, gpu.nvidia)
+			sawNvidia = true
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("Couldn't find a GPU matching the device configuration")
+	}
+
+	if sawNvidia {
+		nodes, err := deviceNvidiaControlNodes()
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, nodes...)
+	}
+
+	devices := []types.Device{}
+	for _, path := range paths {
+		devices = append(devices, types.Device{
+			"type": "unix-char",
+			"path": path,
+			"uid":  m["uid"],
+			"gid":  m["gid"],
+			"mode": m["mode"],
+		})
+	}
+
+	return devices, nil
+}
+
+// usbDevice is a USB device on the host, as found in sysfs or announced by
+// a uevent.
+type usbDevice struct {
+	action string
+
+	vendorid  string
+	productid string
+
+	path  string
+	major int
+	minor int
+}
+
+// matches returns whether the USB device is selected by the given usb
+// device.
+func (u usbDevice) matches(m types.Device) bool {
+	if m["vendorid"] != u.vendorid {
+		return false
+	}
+
+	if m["productid"] != "" && m["productid"] != u.productid {
+		return false
+	}
+
+	return true
+}
+
+// unixDevice returns the unix-char device used to pass the USB device
+// through to a container.
+func (u usbDevice) unixDevice(m types.Device) types.Device {
+	d := types.Device{
+		"type": "unix-char",
+		"path": u.path,
+		"uid":  m["uid"],
+		"gid":  m["gid"],
+		"mode": m["mode"],
+	}
+
+	// The node may not have been created on the host yet
+	if !runningInUserns {
+		d["major"] = fmt.Sprintf("%d", u.major)
+		d["minor"] = fmt.Sprintf("%d", u.minor)
+	}
+
+	return d
+}
+
+// deviceUsbFromUevent returns the USB device a uevent is about. Only events
+// about whole devices (rather than their interfaces) are considered.
+func deviceUsbFromUevent(props map[string]string) (usbDevice, error) {
+	if props["DEVTYPE"] != "usb_device" || props["DEVNAME"] == "" {
+		return usbDevice{}, fmt.Errorf("Not a USB device")
+	}
+
+	// PRODUCT is "<vendor>/<product>/<revision>", without leading zeros
+	ids := strings.Split(props["PRODUCT"], "/")
+	if len(ids) < 2 {
+		return usbDevice{}, fmt.Errorf("Bad USB product: %s", props["PRODUCT"])
+	}
+
+	vendorid, err := strconv.ParseUint(ids[0], 16, 16)
+	if err != nil {
+		return usbDevice{}, fmt.Errorf("Bad USB vendor id: %s", ids[0])
+	}
+
+	productid, err := strconv.ParseUint(ids[1], 16, 16)
+	if err != nil {
+		return usbDevice{}, fmt.Errorf("Bad USB product id: %s", ids[1])
+	}
+
+	major, err := strconv.Atoi(props["MAJOR"])
+	if err != nil {
+		return usbDevice{}, fmt.Errorf("Bad USB device major: %s", props["MAJOR"])
+	}
+
+	minor, err := strconv.Atoi(props["MINOR"])
+	if err != nil {
+		return usbDevice{}, fmt.Errorf("Bad USB device minor: %s", props["MINOR"])
+	}
+
+	return usbDevice{
+		action:    props["ACTION"],
+		vendorid:  fmt.Sprintf("%04x", vendorid),
+		productid: fmt.Sprintf("%04x", productid),
+		path:      filepath.Join("/dev", props["DEVNAME"]),
+		major:     major,
+		minor:     minor,
+	}, nil
+}
+
+// deviceLoadUsb returns the USB devices found in the given sysfs USB devices
+// directory (usually /sys/bus/usb/devices).
+func deviceLoadUsb(usbPath string) ([]usbDevice, error) {
+	usbs := []usbDevice{}
+
+	ents, err := ioutil.ReadDir(usbPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return usbs, nil
+		}
+
+		return nil, err
+	}
+
+	readValue := func(devPath string, key string) (string, error) {
+		content, err := ioutil.ReadFile(filepath.Join(devPath, key))
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	for _, ent := range ents {
+		devPath := filepath.Join(usbPath, ent.Name())
+
+		// Interfaces don't have ids of their own
+		vendorid, err := readValue(devPath, "idVendor")
+		if err != nil {
+			continue
+		}
+
+		productid, err := readValue(devPath, "idProduct")
+		if err != nil {
+			continue
+		}
+
+		dev, err := readValue(devPath, "dev")
+		if err != nil {
+			continue
+		}
+
+		busnum, err := readValue(devPath, "busnum")
+		if err != nil {
+			continue
+		}
+
+		devnum, err := readValue(devPath, "devnum")
+		if err != nil {
+			continue
+		}
+
+		usb := usbDevice{
+			vendorid:  vendorid,
+			productid: productid,
+		}
+
+		_, err = fmt.Sscanf(dev, "%d:%d", &usb.major, &usb.minor)
+		if err != nil {
+			continue
+		}
+
+		bus, err := strconv.Atoi(busnum)
+		if err != nil {
+			continue
+		}
+
+		num, err := strconv.Atoi(devnum)
+		if err != nil {
+			continue
+		}
+
+		usb.path = fmt.Sprintf("/dev/bus/usb/%03d/%03d", bus, num)
+		usbs = append(usbs, usb)
+	}
+
+	return usbs, nil
+}
+
+// deviceUsbUnixDevices returns the unix-char devices needed to pass the USB
+// devices selected by a usb device through to a container.
+func deviceUsbUnixDevices(m types.Device) ([]types.Device, error) {
+	usbs, err := deviceLoadUsb("/sys/bus/usb/devices")
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []types.Device{}
+	for _, usb := range usbs {
+		if !usb.matches(m) {
+			continue
+		}
+
+		devices = append(devices, usb.unixDevice(m))
+	}
+
+	if len(devices) == 0 && shared.IsTrue(m["required"]) {
+		return nil, fmt.Errorf("Couldn't find the required USB device %s:%s", m["vendorid"], m["productid"])
+	}
+
+	return devices, nil
+}
+
+// deviceUnixDevices returns the unix-char and unix-block devices backing a
+// device, looking up the host nodes of gpu and usb devices.
+func deviceUnixDevices(m types.Device) ([]types.Device, error) {
+	switch m["type"] {
+	case "gpu":
+		return deviceGpuUnixDevices(m)
+	case "usb":
+		return deviceUsbUnixDevices(m)
+	default:
+		return []types.Device{m}, nil
+	}
+}
+
+// deviceUsbEvent creates or removes the node of a USB device in all the
+// running containers having a usb device matching it.
+func deviceUsbEvent(s *state.State, storage storage, usb usbDevice) {
+	containers, err := db.ContainersList(s.DB, db.CTypeRegular)
+	if err != nil {
+		logger.Error("Problem loading containers list", log.Ctx{"err": err})
+		return
+	}
+
+	for _, name := range containers {
+		c, err := containerLoadByName(s, storage, name)
+		if err != nil {
+			continue
+		}
+
+		if !c.IsRunning() {
+			continue
+		}
+
+		lxc, ok := c.(*containerLXC)
+		if !ok {
+			continue
+		}
+
+		devices := c.ExpandedDevices()
+		for _, k := range devices.DeviceNames() {
+			m := devices[k]
+			if m["type"] != "usb" || !usb.matches(m) {
+				continue
+			}
+
+			if usb.action == "add" {
+				err = lxc.insertUnixDevice(k, usb.unixDevice(m))
+			} else {
+				err = lxc.removeUnixDevice(usb.unixDevice(m))
+			}
+			if err != nil {
+				logger.Error("Failed to hotplug USB device", log.Ctx{"container": name, "device": k, "path": usb.path, "err": err})
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/types"
+)
+
+func TestDeviceLoadGpu(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-devices-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Fake a sysfs with a GPU having a card and a render node
+	pciPath := filepath.Join(dir, "devices", "0000:00:02.0")
+	require.NoError(t, os.MkdirAll(pciPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pciPath, "vendor"), []byte("0x8086\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pciPath, "device"), []byte("0x5916\n"), 0644))
+
+	drmPath := filepath.Join(dir, "drm")
+	for _, name := range []string{"card0", "renderD128", "card0-DP-1"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(drmPath, name), 0755))
+		require.NoError(t, os.Symlink(pciPath, filepath.Join(drmPath, name, "device")))
+	}
+
+	gpus, err := deviceLoadGpu(drmPath)
+	require.NoError(t, err)
+	require.Len(t, gpus, 2)
+
+	for _, gpu := range gpus {
+		assert.Equal(t, "0", gpu.id)
+		assert.Equal(t, "0000:00:02.0", gpu.pci)
+		assert.Equal(t, "8086", gpu.vendorid)
+		assert.Equal(t, "5916", gpu.productid)
+		assert.Equal(t, "", gpu.nvidia)
+	}
+	assert.Equal(t, "/dev/dri/card0", gpus[0].path)
+	assert.Equal(t, "/dev/dri/renderD128", gpus[1].path)
+
+	assert.True(t, gpus[0].matches(types.Device{"type": "gpu"}))
+	assert.True(t, gpus[0].matches(types.Device{"vendorid": "8086", "id": "0"}))
+	assert.True(t, gpus[0].matches(types.Device{"pci": "0000:00:02.0"}))
+	assert.False(t, gpus[0].matches(types.Device{"vendorid": "10de"}))
+	assert.False(t, gpus[0].matches(types.Device{"productid": "5916", "id": "1"}))
+
+	gpus, err = deviceLoadGpu(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Len(t, gpus, 0)
+}
+
+func TestDeviceUsbFromUevent(t *testing.T) {
+	props := map[string]string{
+		"ACTION":    "add",
+		"SUBSYSTEM": "usb",
+		"DEVTYPE":   "usb_device",
+		"DEVNAME":   "bus/usb/001/007",
+		"PRODUCT":   "46d/c52b/1201",
+		"MAJOR":     "189",
+		"MINOR":     "6",
+	}
+
+	usb, err := deviceUsbFromUevent(props)
+	require.NoError(t, err)
+	assert.Equal(t, "add", usb.action)
+	assert.Equal(t, "046d", usb.vendorid)
+	assert.Equal(t, "c52b", usb.productid)
+	assert.Equal(t, "/dev/bus/usb/001/007", usb.path)
+	assert.Equal(t, 189, usb.major)
+	assert.Equal(t, 6, usb.minor)
+
+	assert.True(t, usb.matches(types.Device{"vendorid": "046d"}))
+	assert.True(t, usb.matches(types.Device{"vendorid": "046d", "productid": "c52b"}))
+	assert.False(t, usb.matches(types.Device{"vendorid": "046d", "productid": "c52c"}))
+	assert.False(t, usb.matches(types.Device{}))
+
+	props["DEVTYPE"] = "usb_interface"
+	_, err = deviceUsbFromUevent(props)
+	assert.Error(t, err)
+}
+
+func TestDeviceLoadUsb(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-devices-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]map[string]string{
+		"1-2": {
+			"idVendor":  "046d\n",
+			"idProduct": "c52b\n",
+			"dev":       "189:6\n",
+			"busnum":    "1\n",
+			"devnum":    "7\n",
+		},
+		"1-2:1.0": {
+			"bInterfaceClass": "03\n",
+		},
+	}
+
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0755))
+		for k, v := range content {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name, k), []byte(v), 0644))
+		}
+	}
+
+	usbs, err := deviceLoadUsb(dir)
+	require.NoError(t, err)
+	require.Len(t, usbs, 1)
+	assert.Equal(t, usbDevice{vendorid: "046d", productid: "c52b", path: "/dev/bus/usb/001/007", major: 189, minor: 6}, usbs[0])
+}
@@ -12,8 +12,11 @@ import (
 	"github.com/pborman/uuid"
 	log "gopkg.in/inconshreveable/log15.v2"
 
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
 )
 
 type eventsHandler struct {
@@ -53,26 +56,31 @@ type eventListener struct {
 	id           string
 	lock         sync.Mutex
 	done         bool
+
+	// The certificate of the client, nil for local users
+	cert      *db.CertInfo
+	fullAdmin bool
 }
 
 type eventsServe struct {
+	d   *Daemon
 	req *http.Request
 }
 
 func (r *eventsServe) Render(w http.ResponseWriter) error {
-	return eventsSocket(r.req, w)
+	return eventsSocket(r.d, r.req, w)
 }
 
 func (r *eventsServe) String() string {
 	return "event handler"
 }
 
-func eventsSocket(r *http.Request, w http.ResponseWriter) error {
+func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	listener := eventListener{}
 
 	typeStr := r.FormValue("type")
 	if typeStr == "" {
-		typeStr = "logging,operation"
+		typeStr = "logging,operation,lifecycle"
 	}
 
 	c, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
@@ -84,6 +92,8 @@ func eventsSocket(r *http.Request, w http.ResponseWriter) error {
 	listener.connection = c
 	listener.id = uuid.NewRandom().String()
 	listener.messageTypes = strings.Split(typeStr, ",")
+	listener.cert = certificateFromRequest(d, r)
+	listener.fullAdmin = certificateIsFullAdmin(d, r)
 
 	eventsLock.Lock()
 	eventListeners[listener.id] = &listener
@@ -97,22 +107,61 @@ func eventsSocket(r *http.Request, w http.ResponseWriter) error {
 }
 
 func eventsGet(d *Daemon, r *http.Request) Response {
-	return &eventsServe{r}
+	return &eventsServe{d, r}
 }
 
 var eventsCmd = Command{name: "events", get: eventsGet}
 
 func eventSend(eventType string, eventMessage interface{}) error {
-	event := shared.Jmap{}
-	event["type"] = eventType
-	event["timestamp"] = time.Now()
-	event["metadata"] = eventMessage
+	return eventBroadcast(eventType, func(listener *eventListener) (interface{}, bool) {
+		return eventMessage, eventAllowed(listener.cert, eventType, eventMessage)
+	})
+}
 
-	body, err := json.Marshal(event)
-	if err != nil {
-		return err
+// eventSendOperation notifies the listeners which may see the operation of a
+// change to it, leaving out the secrets they aren't allowed to see.
+func eventSendOperation(op *operation, md *api.Operation) error {
+	return eventBroadcast("operation", func(listener *eventListener) (interface{}, bool) {
+		if !operationVisibleTo(listener.cert, op) {
+			return nil, false
+		}
+
+		return operationHideSecrets(listener.cert, listener.fullAdmin, op, md), true
+	})
+}
+
+// eventAllowed returns whether the given certificate, nil for local users,
+// may receive an event. Restricted certificates only get the lifecycle events
+// of the containers and profiles they were given.
+func eventAllowed(cert *db.CertInfo, eventType string, eventMessage interface{}) bool {
+	if cert == nil || !certificateIsRestricted(cert) {
+		return true
 	}
 
+	// Log messages may be about any container
+	if eventType == "logging" {
+		return false
+	}
+
+	event, ok := eventMessage.(api.EventLifecycle)
+	if !ok {
+		return true
+	}
+
+	fields := strings.Split(strings.TrimPrefix(event.Source, fmt.Sprintf("/%s/", version.APIVersion)), "/")
+	if len(fields) < 2 {
+		return true
+	}
+
+	return certificateAllowsRead(cert, fields[0], fields[1])
+}
+
+// eventBroadcast sends an event to the listeners of its type, the render
+// function returning the metadata of the event for each of them, or false
+// if the listener mustn't get it.
+func eventBroadcast(eventType string, render func(listener *eventListener) (interface{}, bool)) error {
+	timestamp := time.Now()
+
 	eventsLock.Lock()
 	listeners := eventListeners
 	for _, listener := range listeners {
@@ -120,6 +169,22 @@ func eventSend(eventType string, eventMessage interface{}) error {
 			continue
 		}
 
+		eventMessage, ok := render(listener)
+		if !ok {
+			continue
+		}
+
+		event := shared.Jmap{}
+		event["type"] = eventType
+		event["timestamp"] = timestamp
+		event["metadata"] = eventMessage
+
+		body, err := json.Marshal(event)
+		if err != nil {
+			eventsLock.Unlock()
+			return err
+		}
+
 		go func(listener *eventListener, body []byte) {
 			// Check that the listener still exists
 			if listener == nil {
@@ -135,7 +200,7 @@ func eventSend(eventType string, eventMessage interface{}) error {
 				return
 			}
 
-			err = listener.connection.WriteMessage(websocket.TextMessage, body)
+			err := listener.connection.WriteMessage(websocket.TextMessage, body)
 			if err != nil {
 				// Remove the listener from the list
 				eventsLock.Lock()
@@ -154,3 +219,33 @@ func eventSend(eventType string, eventMessage interface{}) error {
 
 	return nil
 }
+
+// eventRequestor identifies the client behind a request, if any.
+func eventRequestor(r *http.Request) *api.EventLifecycleRequestor {
+	if r == nil {
+		return nil
+	}
+
+	if r.RemoteAddr == "@" {
+		return &api.EventLifecycleRequestor{Protocol: "unix", Address: r.RemoteAddr}
+	}
+
+	requestor := api.EventLifecycleRequestor{Protocol: "tls", Address: r.RemoteAddr}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		requestor.Username = shared.CertFingerprint(r.TLS.PeerCertificates[0])
+	}
+
+	return &requestor
+}
+
+// eventSendLifecycle notifies the listeners of a change to the resource
+// found at the given URL. The request is nil for changes initiated by LXD
+// itself.
+func eventSendLifecycle(action string, source string, context map[string]interface{}, r *http.Request) error {
+	return eventSend("lifecycle", api.EventLifecycle{
+		Action:    action,
+		Source:    source,
+		Context:   context,
+		Requestor: eventRequestor(r),
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+// eventsTestListener registers an event listener for the given certificate,
+// returning a function reading the next event it gets.
+func eventsTestListener(t *testing.T, cert *db.CertInfo, fullAdmin bool) (func() map[string]interface{}, func()) {
+	src, dst, err := migrationLocalConns()
+	require.NoError(t, err)
+
+	listener := &eventListener{
+		connection:   src,
+		messageTypes: []string{"operation", "lifecycle", "logging"},
+		active:       make(chan bool, 1),
+		id:           cert.Name,
+		cert:         cert,
+		fullAdmin:    fullAdmin,
+	}
+
+	eventsLock.Lock()
+	eventListeners[listener.id] = listener
+	eventsLock.Unlock()
+
+	next := func() map[string]interface{} {
+		dst.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := dst.ReadMessage()
+		require.NoError(t, err)
+
+		event := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(data, &event))
+
+		return event["metadata"].(map[string]interface{})
+	}
+
+	cleanup := func() {
+		eventsLock.Lock()
+		delete(eventListeners, listener.id)
+		eventsLock.Unlock()
+
+		src.Close()
+		dst.Close()
+	}
+
+	return next, cleanup
+}
+
+// Restricted certificates only get the events of their containers, without
+// the secrets of the operations they didn't create.
+func TestEventsRestrictedCertificate(t *testing.T) {
+	cert := &db.CertInfo{Name: "ci", Role: "operator", Fingerprint: "ci", Containers: []string{"c1"}}
+	next, cleanup := eventsTestListener(t, cert, false)
+	defer cleanup()
+
+	exec := func(id string, container string) *operation {
+		return &operation{
+			id:        id,
+			class:     operationClassWebsocket,
+			resources: map[string][]string{"containers": {container}},
+			metadata:  map[string]interface{}{"fds": map[string]string{"0": "secret"}},
+			requestor: "other",
+		}
+	}
+
+	for _, op := range []*operation{exec("op2", "c2"), exec("op1", "c1")} {
+		_, md, _ := op.Render()
+		eventSendOperation(op, md)
+	}
+
+	event := next()
+	assert.Equal(t, "op1", event["id"])
+	assert.NotContains(t, event["metadata"], "fds")
+
+	eventSend("logging", map[string]string{"message": "Started container c2"})
+	eventSendLifecycle("container-started", "/1.0/containers/c2", nil, nil)
+	eventSendLifecycle("container-started", "/1.0/containers/c1", nil, nil)
+
+	event = next()
+	assert.Equal(t, "/1.0/containers/c1", event["source"])
+}
+
+// Clients with full administrative access get all the events as they are.
+func TestEventsAdminCertificate(t *testing.T) {
+	cert := &db.CertInfo{Name: "admin", Role: "admin", Fingerprint: "admin"}
+	next, cleanup := eventsTestListener(t, cert, true)
+	defer cleanup()
+
+	op := &operation{
+		id:        "op1",
+		class:     operationClassWebsocket,
+		resources: map[string][]string{"containers": {"c2"}},
+		metadata:  map[string]interface{}{"fds": map[string]string{"0": "secret"}},
+		requestor: "other",
+	}
+
+	_, md, _ := op.Render()
+	eventSendOperation(op, md)
+
+	event := next()
+	assert.Equal(t, "op1", event["id"])
+	assert.Contains(t, event["metadata"], "fds")
+
+	eventSendLifecycle("container-started", "/1.0/containers/c2", nil, nil)
+
+	event = next()
+	assert.Equal(t, "/1.0/containers/c2", event["source"])
+}
@@ -261,6 +261,21 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost, builddir st
 		return nil, err
 	}
 
+	// Get the compression algorithm, the request may override the default
+	compress := req.CompressionAlgorithm
+	if compress != "" {
+		if !shared.StringInSlice(compress, []string{"bzip2", "gzip", "lzma", "xz", "none"}) {
+			return nil, fmt.Errorf("Unsupported compression algorithm '%s'", compress)
+		}
+
+		err = daemonConfigValidateCompression(d, "images.compression_algorithm", compress)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid compression algorithm '%s': %v", compress, err)
+		}
+	} else {
+		compress = daemonConfig["images.compression_algorithm"].Get()
+	}
+
 	// Build the actual image file
 	tarfile, err := ioutil.TempFile(builddir, "lxd_build_tar_")
 	if err != nil {
@@ -275,7 +290,6 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost, builddir st
 	tarfile.Close()
 
 	var compressedPath string
-	compress := daemonConfig["images.compression_algorithm"].Get()
 	if compress != "none" {
 		compressedPath, err = compressFile(tarfile.Name(), compress)
 		if err != nil {
@@ -731,6 +745,9 @@ func imagesPost(d *Daemon, r *http.Request) Response {
 		metadata["fingerprint"] = info.Fingerprint
 		metadata["size"] = strconv.FormatInt(info.Size, 10)
 		op.UpdateMetadata(metadata)
+
+		eventSendLifecycle("image-created", fmt.Sprintf("/%s/images/%s", version.APIVersion, info.Fingerprint), nil, r)
+
 		return nil
 	}
 
@@ -848,15 +865,15 @@ func autoUpdateImages(d *Daemon) {
 			continue
 		}
 
-		autoUpdateImage(d, nil, id, info)
+		autoUpdateImage(d, nil, id, info, nil)
 	}
 
 	logger.Infof("Done updating images")
 }
 
 // Update a single image.  The operation can be nil, if no progress tracking is needed.
-// Returns whether the image has been updated.
-func autoUpdateImage(d *Daemon, op *operation, id int, info *api.Image) error {
+// The request is nil when LXD updates the image on its own.
+func autoUpdateImage(d *Daemon, op *operation, id int, info *api.Image, r *http.Request) error {
 	fingerprint := info.Fingerprint
 	_, source, err := db.ImageSourceGet(d.db, id)
 	if err != nil {
@@ -867,12 +884,12 @@ func autoUpdateImage(d *Daemon, op *operation, id int, info *api.Image) error {
 	logger.Debug("Processing image", log.Ctx{"fp": fingerprint, "server": source.Server, "protocol": source.Protocol, "alias": source.Alias})
 
 	// Set operation metadata to indicate whether a refresh happened
-	setRefreshResult := func(result bool) {
+	setRefreshResult := func(result bool, hash string) {
 		if op == nil {
 			return
 		}
 
-		metadata := map[string]interface{}{"refreshed": result}
+		metadata := map[string]interface{}{"refreshed": result, "fingerprint": hash}
 		op.UpdateMetadata(metadata)
 	}
 
@@ -885,7 +902,7 @@ func autoUpdateImage(d *Daemon, op *operation, id int, info *api.Image) error {
 	// Image didn't change, nothing to do.
 	hash := newInfo.Fingerprint
 	if hash == fingerprint {
-		setRefreshResult(false)
+		setRefreshResult(false, fingerprint)
 		return nil
 	}
 
@@ -915,12 +932,16 @@ func autoUpdateImage(d *Daemon, op *operation, id int, info *api.Image) error {
 		return err
 	}
 
+	eventSendLifecycle("image-created", fmt.Sprintf("/%s/images/%s", version.APIVersion, hash), nil, r)
+
 	err = doDeleteImage(d, fingerprint)
 	if err != nil {
 		logger.Error("Error deleting image", log.Ctx{"err": err, "fp": fingerprint})
+	} else {
+		eventSendLifecycle("image-deleted", fmt.Sprintf("/%s/images/%s", version.APIVersion, fingerprint), nil, r)
 	}
 
-	setRefreshResult(true)
+	setRefreshResult(true, hash)
 	return nil
 }
 
@@ -944,7 +965,10 @@ func pruneExpiredImages(d *Daemon) {
 	for _, fp := range images {
 		if err := doDeleteImage(d, fp); err != nil {
 			logger.Error("Error deleting image", log.Ctx{"err": err, "fp": fp})
+			continue
 		}
+
+		eventSendLifecycle("image-deleted", fmt.Sprintf("/%s/images/%s", version.APIVersion, fp), nil, nil)
 	}
 
 	logger.Infof("Done pruning expired images")
@@ -968,6 +992,29 @@ func doDeleteImage(d *Daemon, fingerprint string) error {
 		}
 	}
 
+	// ZFS and LVM storage pools each hold their own copy of the image
+	pools, err := db.StoragePools(d.db)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range pools {
+		pool, err := storageForPool(d.State(), d.Storage, name)
+		if err != nil {
+			logger.Error("error loading storage pool", log.Ctx{"pool": name, "err": err})
+			continue
+		}
+
+		if pool.GetStorageType() != storageTypeZfs && pool.GetStorageType() != storageTypeLvm {
+			continue
+		}
+
+		err = pool.ImageDelete(imgInfo.Fingerprint)
+		if err != nil {
+			logger.Error("error deleting the image from storage pool", log.Ctx{"fingerprint": imgInfo.Fingerprint, "pool": name, "err": err})
+		}
+	}
+
 	// Remove main image file
 	fname := shared.VarPath("images", imgInfo.Fingerprint)
 	if shared.PathExists(fname) {
@@ -998,7 +1045,14 @@ func imageDelete(d *Daemon, r *http.Request) Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
 	rmimg := func(op *operation) error {
-		return doDeleteImage(d, fingerprint)
+		err := doDeleteImage(d, fingerprint)
+		if err != nil {
+			return err
+		}
+
+		eventSendLifecycle("image-deleted", fmt.Sprintf("/%s/images/%s", version.APIVersion, fingerprint), nil, r)
+
+		return nil
 	}
 
 	resources := map[string][]string{}
@@ -1086,6 +1140,8 @@ func imagePut(d *Daemon, r *http.Request) Response {
 		return SmartError(err)
 	}
 
+	eventSendLifecycle("image-updated", fmt.Sprintf("/%s/images/%s", version.APIVersion, info.Fingerprint), nil, r)
+
 	return EmptySyncResponse
 }
 
@@ -1244,6 +1300,7 @@ func imageExport(d *Daemon, r *http.Request) Response {
 
 	public := !util.IsTrustedClient(r, d.clientCerts)
 	secret := r.FormValue("secret")
+	part := r.FormValue("part")
 
 	_, imgInfo, err := db.ImageGet(d.db, fingerprint, false, false)
 	if err != nil {
@@ -1282,9 +1339,24 @@ func imageExport(d *Daemon, r *http.Request) Response {
 		files[1].path = rootfsPath
 		files[1].filename = filename
 
+		// Only send one of the two parts if requested
+		switch part {
+		case "":
+		case "metadata":
+			files = files[:1]
+		case "rootfs":
+			files = files[1:]
+		default:
+			return BadRequest(fmt.Errorf("Invalid image part '%s'", part))
+		}
+
 		return FileResponse(r, files, nil, false)
 	}
 
+	if part != "" {
+		return BadRequest(fmt.Errorf("Only split images can be retrieved in parts"))
+	}
+
 	files := make([]fileResponseEntry, 1)
 	files[0].identifier = filename
 	files[0].path = imagePath
@@ -1320,8 +1392,40 @@ func imageSecret(d *Daemon, r *http.Request) Response {
 	return OperationResponse(op)
 }
 
+func imageRefresh(d *Daemon, r *http.Request) Response {
+	fingerprint := mux.Vars(r)["fingerprint"]
+	imageId, imageInfo, err := db.ImageGet(d.db, fingerprint, false, false)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Only images that were downloaded from a remote can be refreshed
+	_, _, err = db.ImageSourceGet(d.db, imageId)
+	if err == db.NoSuchObjectError {
+		return BadRequest(fmt.Errorf("Image '%s' has no source to refresh from", imageInfo.Fingerprint))
+	} else if err != nil {
+		return SmartError(err)
+	}
+
+	// Begin background operation
+	run := func(op *operation) error {
+		return autoUpdateImage(d, op, imageId, imageInfo, r)
+	}
+
+	resources := map[string][]string{}
+	resources["images"] = []string{imageInfo.Fingerprint}
+
+	op, err := operationCreate(operationClassTask, resources, nil, run, nil, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return OperationResponse(op)
+}
+
 var imagesExportCmd = Command{name: "images/{fingerprint}/export", untrustedGet: true, get: imageExport}
 var imagesSecretCmd = Command{name: "images/{fingerprint}/secret", post: imageSecret}
+var imagesRefreshCmd = Command{name: "images/{fingerprint}/refresh", post: imageRefresh}
 
 var aliasesCmd = Command{name: "images/aliases", post: aliasesPost, get: aliasesGet}
 
@@ -109,6 +109,8 @@ Waitready options:
 
 
 Internal commands (don't call these directly):
+    forkconsole
+        Attach to the console of a container
     forkexec
         Execute a command in a container
     forkgetnet
@@ -117,6 +119,8 @@ Internal commands (don't call these directly):
         Grab a file from a running container
     forkmigrate
         Restore a container after migration
+    forkproxy
+        Forward connections to a container
     forkputfile
         Push a file to a running container
     forkstart
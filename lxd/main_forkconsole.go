@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/lxc/go-lxc.v2"
+)
+
+/*
+ * This is called by lxd when called as "lxd forkconsole <container> <lxcpath> <configpath>"
+ * It attaches its stdin/stdout/stderr to the container's console until it
+ * gets killed.
+ */
+func cmdForkConsole(args *Args) error {
+	if len(args.Params) != 3 {
+		return fmt.Errorf("Bad arguments: %q", args.Params)
+	}
+
+	name := args.Params[0]
+	lxcpath := args.Params[1]
+	configPath := args.Params[2]
+
+	c, err := lxc.NewContainer(name, lxcpath)
+	if err != nil {
+		return fmt.Errorf("Error initializing container: %q", err)
+	}
+
+	err = c.LoadConfigFile(configPath)
+	if err != nil {
+		return fmt.Errorf("Error opening config file: %q", err)
+	}
+
+	opts := lxc.ConsoleOptions{
+		Tty:      0,
+		StdinFd:  os.Stdin.Fd(),
+		StdoutFd: os.Stdout.Fd(),
+		StderrFd: os.Stderr.Fd(),
+
+		// No escape sequence, the client detaches by closing the websocket
+		EscapeCharacter: -1,
+	}
+
+	err = c.Console(opts)
+	if err != nil {
+		return fmt.Errorf("Failed to attach to the console: %s", err)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -13,10 +14,11 @@ import (
 )
 
 /*
- * This is called by lxd when called as "lxd forkexec <container>"
+ * This is called by lxd when called as
+ * "lxd forkexec <container> <lxcpath> <config> <cwd> <uid> <gid>"
  */
 func cmdForkExec(args *Args) (int, error) {
-	if len(args.Params) < 3 {
+	if len(args.Params) < 6 {
 		return -1, fmt.Errorf("Bad params: %q", args.Params)
 	}
 	if len(args.Extra) < 1 {
@@ -26,6 +28,17 @@ func cmdForkExec(args *Args) (int, error) {
 	name := args.Params[0]
 	lxcpath := args.Params[1]
 	configPath := args.Params[2]
+	cwd := args.Params[3]
+
+	uid, err := strconv.ParseUint(args.Params[4], 10, 32)
+	if err != nil {
+		return -1, fmt.Errorf("Bad uid: %q", args.Params[4])
+	}
+
+	gid, err := strconv.ParseUint(args.Params[5], 10, 32)
+	if err != nil {
+		return -1, fmt.Errorf("Bad gid: %q", args.Params[5])
+	}
 
 	c, err := lxc.NewContainer(name, lxcpath)
 	if err != nil {
@@ -50,6 +63,8 @@ func cmdForkExec(args *Args) (int, error) {
 	opts.StdinFd = 200
 	opts.StdoutFd = 201
 	opts.StderrFd = 202
+	opts.UID = int(uid)
+	opts.GID = int(gid)
 
 	logPath := shared.LogPath(name, "forkexec.log")
 	if shared.PathExists(logPath) {
@@ -80,7 +95,7 @@ func cmdForkExec(args *Args) (int, error) {
 
 		if section == "env" {
 			fields := strings.SplitN(arg, "=", 2)
-			if len(fields) == 2 && fields[0] == "HOME" {
+			if len(fields) == 2 && fields[0] == "HOME" && cwd == "" {
 				opts.Cwd = fields[1]
 			}
 			env = append(env, arg)
@@ -92,6 +107,9 @@ func cmdForkExec(args *Args) (int, error) {
 	}
 
 	opts.Env = env
+	if cwd != "" {
+		opts.Cwd = cwd
+	}
 
 	status, err := c.RunCommandNoWait(cmd, opts)
 	if err != nil {
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a UDP client can stay idle before its connection is dropped
+const proxyUDPTimeout = 5 * time.Minute
+
+// proxyParseAddr splits a proxy device address ("tcp:127.0.0.1:80",
+// "udp:[::]:53" or "unix:/run/app.socket") into its protocol and address.
+func proxyParseAddr(addr string) (string, string, error) {
+	fields := strings.SplitN(addr, ":", 2)
+	if len(fields) != 2 || fields[1] == "" {
+		return "", "", fmt.Errorf("Invalid proxy address: %s", addr)
+	}
+
+	switch fields[0] {
+	case "tcp", "udp":
+		_, _, err := net.SplitHostPort(fields[1])
+		if err != nil {
+			return "", "", fmt.Errorf("Invalid proxy address: %s: %s", addr, err)
+		}
+	case "unix":
+		if !strings.HasPrefix(fields[1], "/") {
+			return "", "", fmt.Errorf("Invalid proxy address: %s: unix sockets must use an absolute path", addr)
+		}
+	default:
+		return "", "", fmt.Errorf("Invalid proxy protocol: %s", fields[0])
+	}
+
+	return fields[0], fields[1], nil
+}
+
+/*
+ * This is called by lxd when called as "lxd forkproxy <pid> <listen> <connect>"
+ * The listening socket is created on the host by LXD and passed as fd 3,
+ * while main_nsexec.go attaches us to the container's namespaces, so the
+ * connections are made from inside the container.
+ */
+func cmdForkProxy(args *Args) error {
+	if len(args.Params) != 3 {
+		return fmt.Errorf("Bad arguments: %q", args.Params)
+	}
+
+	listenProto, _, err := proxyParseAddr(args.Params[1])
+	if err != nil {
+		return err
+	}
+
+	connectProto, connectAddr, err := proxyParseAddr(args.Params[2])
+	if err != nil {
+		return err
+	}
+
+	f := os.NewFile(3, "listener")
+	defer f.Close()
+
+	if listenProto == "udp" {
+		conn, err := net.FilePacketConn(f)
+		if err != nil {
+			return fmt.Errorf("Failed to use the listening socket: %s", err)
+		}
+		defer conn.Close()
+
+		return proxyPackets(conn, connectProto, connectAddr)
+	}
+
+	listener, err := net.FileListener(f)
+	if err != nil {
+		return fmt.Errorf("Failed to use the listening socket: %s", err)
+	}
+	defer listener.Close()
+
+	for {
+		src, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go proxyStream(src, connectProto, connectAddr)
+	}
+}
+
+// proxyStream forwards a stream connection to the connect address.
+func proxyStream(src net.Conn, connectProto string, connectAddr string) {
+	defer src.Close()
+
+	dst, err := net.Dial(connectProto, connectAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to %s:%s: %s\n", connectProto, connectAddr, err)
+		return
+	}
+	defer dst.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		io.Copy(dst, src)
+		proxyCloseWrite(dst)
+		wg.Done()
+	}()
+
+	go func() {
+		io.Copy(src, dst)
+		proxyCloseWrite(src)
+		wg.Done()
+	}()
+
+	wg.Wait()
+}
+
+// proxyCloseWrite propagates the end of a stream to the other side.
+func proxyCloseWrite(conn net.Conn) {
+	switch c := conn.(type) {
+	case *net.TCPConn:
+		c.CloseWrite()
+	case *net.UnixConn:
+		c.CloseWrite()
+	default:
+		c.Close()
+	}
+}
+
+// proxyPackets forwards the datagrams of each UDP client over their own
+// connection to the connect address, relaying the replies.
+func proxyPackets(conn net.PacketConn, connectProto string, connectAddr string) error {
+	clients := map[string]net.Conn{}
+	lock := sync.Mutex{}
+
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		dst, ok := clients[addr.String()]
+		if !ok {
+			dst, err = net.Dial(connectProto, connectAddr)
+			if err != nil {
+				lock.Unlock()
+				fmt.Fprintf(os.Stderr, "Failed to connect to %s:%s: %s\n", connectProto, connectAddr, err)
+				continue
+			}
+
+			clients[addr.String()] = dst
+
+			go func(addr net.Addr, dst net.Conn) {
+				buf := make([]byte, 65536)
+				for {
+					dst.SetReadDeadline(time.Now().Add(proxyUDPTimeout))
+					n, err := dst.Read(buf)
+					if err != nil {
+						break
+					}
+
+					conn.WriteTo(buf[:n], addr)
+				}
+
+				lock.Lock()
+				delete(clients, addr.String())
+				lock.Unlock()
+				dst.Close()
+			}(addr, dst)
+		}
+		lock.Unlock()
+
+		dst.Write(buf[:n])
+	}
+}
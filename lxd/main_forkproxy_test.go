@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyParseAddr(t *testing.T) {
+	proto, addr, err := proxyParseAddr("tcp:0.0.0.0:80")
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp", proto)
+	assert.Equal(t, "0.0.0.0:80", addr)
+
+	proto, addr, err = proxyParseAddr("udp:[::1]:53")
+	assert.NoError(t, err)
+	assert.Equal(t, "udp", proto)
+	assert.Equal(t, "[::1]:53", addr)
+
+	proto, addr, err = proxyParseAddr("unix:/run/app.socket")
+	assert.NoError(t, err)
+	assert.Equal(t, "unix", proto)
+	assert.Equal(t, "/run/app.socket", addr)
+
+	for _, bad := range []string{"", "tcp", "tcp:", "tcp:80", "unix:app.socket", "sctp:127.0.0.1:80"} {
+		_, _, err = proxyParseAddr(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestProxyStream(t *testing.T) {
+	// An echo server standing in for the container side
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer backend.Close()
+
+	go func() {
+		conn, err := backend.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte(line))
+	}()
+
+	frontend, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer frontend.Close()
+
+	go func() {
+		conn, err := frontend.Accept()
+		if err != nil {
+			return
+		}
+
+		proxyStream(conn, "tcp", backend.Addr().String())
+	}()
+
+	conn, err := net.Dial("tcp", frontend.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", line)
+}
@@ -38,12 +38,13 @@ package main
 #include <libgen.h>
 #include <ifaddrs.h>
 #include <grp.h>
+#include <dirent.h>
 
 // This expects:
-//  ./lxd forkputfile /source/path <pid> /target/path
+//  ./lxd forkputfile <rootfs> <pid> /source/path /target/path <type> <uid> <gid> <mode> <default uid> <default gid> <default mode> <write>
 // or
-//  ./lxd forkgetfile /target/path <pid> /soruce/path <uid> <gid> <mode>
-// i.e. 8 arguments, each which have a max length of PATH_MAX.
+//  ./lxd forkgetfile <rootfs> <pid> /target/path /source/path <follow|nofollow>
+// i.e. at most 14 arguments, most of which are short.
 // Unfortunately, lseek() and fstat() both fail (EINVAL and 0 size) for
 // procfs. Also, we can't mmap, because procfs doesn't support that, either.
 //
@@ -86,12 +87,12 @@ int mkdir_p(const char *dir, mode_t mode)
 	return 0;
 }
 
-int copy(int target, int source)
+int copy(int target, int source, bool append)
 {
 	ssize_t n;
 	char buf[1024];
 
-	if (ftruncate(target, 0) < 0) {
+	if (!append && ftruncate(target, 0) < 0) {
 		error("error: truncate");
 		return -1;
 	}
@@ -174,23 +175,30 @@ void attach_userns(int pid) {
 	}
 }
 
-int manip_file_in_ns(char *rootfs, int pid, char *host, char *container, bool is_put, uid_t uid, gid_t gid, mode_t mode, uid_t defaultUid, gid_t defaultGid, mode_t defaultMode) {
-	int host_fd, container_fd;
+int manip_file_in_ns(char *rootfs, int pid, char *host, char *container, bool is_put, char *type, uid_t uid, gid_t gid, mode_t mode, uid_t defaultUid, gid_t defaultGid, mode_t defaultMode, bool append, bool follow) {
+	int host_fd = -1, container_fd = -1;
 	int ret = -1;
 	int container_open_flags;
 	struct stat st;
 	int exists = 1;
+	bool is_dir_manip = type != NULL && !strcmp(type, "directory");
+	bool is_symlink_manip = type != NULL && !strcmp(type, "symlink");
 
-	host_fd = open(host, O_RDWR);
-	if (host_fd < 0) {
-		error("error: open");
-		return -1;
+	if (!is_dir_manip) {
+		host_fd = open(host, O_RDWR);
+		if (host_fd < 0) {
+			error("error: open");
+			return -1;
+		}
 	}
 
 	container_open_flags = O_RDWR;
 	if (is_put)
 		container_open_flags |= O_CREAT;
 
+	if (is_put && append)
+		container_open_flags |= O_APPEND;
+
 	if (pid > 0) {
 		attach_userns(pid);
 
@@ -210,37 +218,156 @@ int manip_file_in_ns(char *rootfs, int pid, char *host, char *container, bool is
 		}
 	}
 
-	if (is_put && stat(container, &st) < 0)
+	if (is_put && lstat(container, &st) < 0)
 		exists = 0;
 
-	if (is_put && exists && S_ISDIR(st.st_mode)) {
-		error("error: Path already exists as a directory");
+	if (is_put && !exists) {
+		if (mode == -1) {
+			mode = defaultMode;
+		}
+
+		if (uid == -1) {
+			uid = defaultUid;
+		}
+
+		if (gid == -1) {
+			gid = defaultGid;
+		}
+	}
+
+	if (is_put && is_dir_manip) {
+		if (exists && !S_ISDIR(st.st_mode)) {
+			error("error: Path already exists and isn't a directory");
+			goto close_host;
+		}
+
+		umask(0);
+		if (!exists && mkdir(container, mode) < 0) {
+			error("error: mkdir");
+			goto close_host;
+		}
+
+		if (mode != -1 && chmod(container, mode) < 0) {
+			error("error: chmod");
+			goto close_host;
+		}
+
+		if (chown(container, uid, gid) < 0) {
+			error("error: chown");
+			goto close_host;
+		}
+
+		ret = 0;
 		goto close_host;
 	}
 
-	umask(0);
-	container_fd = open(container, container_open_flags, 0);
-	if (container_fd < 0) {
-		error("error: open");
+	if (is_put && is_symlink_manip) {
+		char target[PATH_MAX];
+		ssize_t len;
+
+		len = read(host_fd, target, PATH_MAX - 1);
+		if (len < 0) {
+			error("error: read");
+			goto close_host;
+		}
+		target[len] = '\0';
+
+		if (exists && S_ISDIR(st.st_mode)) {
+			error("error: Path already exists as a directory");
+			goto close_host;
+		}
+
+		if (exists && unlink(container) < 0) {
+			error("error: unlink");
+			goto close_host;
+		}
+
+		if (symlink(target, container) < 0) {
+			error("error: symlink");
+			goto close_host;
+		}
+
+		if (lchown(container, uid, gid) < 0) {
+			error("error: chown");
+			goto close_host;
+		}
+
+		ret = 0;
 		goto close_host;
 	}
 
-	if (is_put) {
-		if (!exists) {
-			if (mode == -1) {
-				mode = defaultMode;
+	if (is_put && exists && S_ISDIR(st.st_mode)) {
+		error("error: Path already exists as a directory");
+		goto close_host;
+	}
+
+	if (!is_put) {
+		// Only return symlinks as such when asked not to follow them
+		if ((follow ? stat(container, &st) : lstat(container, &st)) < 0) {
+			error("error: stat");
+			goto close_host;
+		}
+
+		fprintf(stderr, "uid: %ld\n", (long)st.st_uid);
+		fprintf(stderr, "gid: %ld\n", (long)st.st_gid);
+		fprintf(stderr, "mode: %ld\n", (unsigned long)st.st_mode & (S_IRWXU | S_IRWXG | S_IRWXO));
+
+		if (S_ISLNK(st.st_mode)) {
+			char target[PATH_MAX];
+			ssize_t len;
+
+			len = readlink(container, target, PATH_MAX);
+			if (len < 0) {
+				error("error: readlink");
+				goto close_host;
 			}
 
-			if (uid == -1) {
-				uid = defaultUid;
+			if (write(host_fd, target, len) != len) {
+				error("error: write");
+				goto close_host;
+			}
+
+			fprintf(stderr, "type: symlink\n");
+			ret = 0;
+			goto close_host;
+		}
+
+		if (S_ISDIR(st.st_mode)) {
+			DIR *fdir;
+			struct dirent *de;
+
+			fdir = opendir(container);
+			if (!fdir) {
+				error("error: opendir");
+				goto close_host;
 			}
 
-			if (gid == -1) {
-				gid = defaultGid;
+			fprintf(stderr, "type: directory\n");
+
+			while ((de = readdir(fdir))) {
+				if (!strcmp(de->d_name, ".") || !strcmp(de->d_name, ".."))
+					continue;
+
+				fprintf(stderr, "entry: %s\n", de->d_name);
 			}
+
+			closedir(fdir);
+			ret = 0;
+			goto close_host;
 		}
 
-		if (copy(container_fd, host_fd) < 0) {
+		fprintf(stderr, "type: file\n");
+	}
+
+	umask(0);
+	container_fd = open(container, container_open_flags, 0);
+	if (container_fd < 0) {
+		error("error: open");
+		goto close_host;
+	}
+
+	if (is_put) {
+		if (copy(container_fd, host_fd, append) < 0) {
 			error("error: copy");
 			goto close_container;
 		}
@@ -257,22 +384,14 @@ int manip_file_in_ns(char *rootfs, int pid, char *host, char *container, bool is
 
 		ret = 0;
 	} else {
-		ret = copy(host_fd, container_fd);
-
-		if (fstat(container_fd, &st) < 0) {
-			error("error: stat");
-			goto close_container;
-		}
-
-		fprintf(stderr, "uid: %ld\n", (long)st.st_uid);
-		fprintf(stderr, "gid: %ld\n", (long)st.st_gid);
-		fprintf(stderr, "mode: %ld\n", (unsigned long)st.st_mode & (S_IRWXU | S_IRWXG | S_IRWXO));
+		ret = copy(host_fd, container_fd, false);
 	}
 
 close_container:
 	close(container_fd);
 close_host:
-	close(host_fd);
+	if (host_fd >= 0)
+		close(host_fd);
 	return ret;
 }
 
@@ -428,7 +547,9 @@ void forkdofile(char *buf, char *cur, bool is_put, ssize_t size) {
 	uid_t defaultUid = 0;
 	gid_t defaultGid = 0;
 	mode_t defaultMode = 0;
-	char *command = cur, *rootfs = NULL, *source = NULL, *target = NULL;
+	char *command = cur, *rootfs = NULL, *source = NULL, *target = NULL, *type = NULL;
+	bool append = false;
+	bool follow = true;
 	pid_t pid;
 
 	ADVANCE_ARG_REQUIRED();
@@ -444,6 +565,9 @@ void forkdofile(char *buf, char *cur, bool is_put, ssize_t size) {
 	target = cur;
 
 	if (is_put) {
+		ADVANCE_ARG_REQUIRED();
+		type = cur;
+
 		ADVANCE_ARG_REQUIRED();
 		uid = atoi(cur);
 
@@ -461,9 +585,15 @@ void forkdofile(char *buf, char *cur, bool is_put, ssize_t size) {
 
 		ADVANCE_ARG_REQUIRED();
 		defaultMode = atoi(cur);
+
+		ADVANCE_ARG_REQUIRED();
+		append = strcmp(cur, "append") == 0;
+	} else {
+		ADVANCE_ARG_REQUIRED();
+		follow = strcmp(cur, "nofollow") != 0;
 	}
 
-	_exit(manip_file_in_ns(rootfs, pid, source, target, is_put, uid, gid, mode, defaultUid, defaultGid, defaultMode));
+	_exit(manip_file_in_ns(rootfs, pid, source, target, is_put, type, uid, gid, mode, defaultUid, defaultGid, defaultMode, append, follow));
 }
 
 void forkcheckfile(char *buf, char *cur, bool is_put, ssize_t size) {
@@ -539,8 +669,8 @@ void forkremovefile(char *buf, char *cur, bool is_put, ssize_t size) {
 		}
 	}
 
-	if (stat(path, &sb) < 0) {
-		error("error: stat");
+	if (lstat(path, &sb) < 0) {
+		error("error: lstat");
 		_exit(1);
 	}
 
@@ -571,6 +701,37 @@ void forkgetnet(char *buf, char *cur, ssize_t size) {
 	// The rest happens in Go
 }
 
+void forkproxy(char *buf, char *cur, ssize_t size) {
+	char *connect;
+	int pid;
+
+	ADVANCE_ARG_REQUIRED();
+	pid = atoi(cur);
+
+	// The listening socket was created on the host and is passed as fd 3
+	ADVANCE_ARG_REQUIRED();
+
+	ADVANCE_ARG_REQUIRED();
+	connect = cur;
+
+	if (dosetns(pid, "net") < 0) {
+		fprintf(stderr, "Failed setns to container network namespace: %s\n", strerror(errno));
+		_exit(1);
+	}
+
+	// Unix sockets are looked up in the container's filesystem
+	if (strncmp(connect, "unix:", 5) == 0) {
+		attach_userns(pid);
+
+		if (dosetns(pid, "mnt") < 0) {
+			fprintf(stderr, "Failed setns to container mount namespace: %s\n", strerror(errno));
+			_exit(1);
+		}
+	}
+
+	// The rest happens in Go
+}
+
 __attribute__((constructor)) void init(void) {
 	int cmdline;
 	char buf[CMDLINE_SIZE];
@@ -613,6 +774,8 @@ __attribute__((constructor)) void init(void) {
 		forkumount(buf, cur, size);
 	} else if (strcmp(cur, "forkgetnet") == 0) {
 		forkgetnet(buf, cur, size);
+	} else if (strcmp(cur, "forkproxy") == 0) {
+		forkproxy(buf, cur, size);
 	}
 }
 */
@@ -8,6 +8,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -150,11 +151,12 @@ func (c *migrationFields) controlChannel() <-chan MigrationControl {
 type migrationSourceWs struct {
 	migrationFields
 
-	allConnected chan bool
+	allConnected  chan bool
+	containerOnly bool
 }
 
-func NewMigrationSource(c container) (*migrationSourceWs, error) {
-	ret := migrationSourceWs{migrationFields{container: c}, make(chan bool, 1)}
+func NewMigrationSource(c container, containerOnly bool) (*migrationSourceWs, error) {
+	ret := migrationSourceWs{migrationFields{container: c}, make(chan bool, 1), containerOnly}
 
 	var err error
 	ret.controlSecret, err = shared.RandomCryptoString()
@@ -278,6 +280,7 @@ func snapshotToProtobuf(c container) *Snapshot {
 	isEphemeral := c.IsEphemeral()
 	arch := int32(c.Architecture())
 	stateful := c.IsStateful()
+	creationDate := c.CreationDate().UTC().Unix()
 
 	return &Snapshot{
 		Name:         &parts[len(parts)-1],
@@ -287,6 +290,7 @@ func snapshotToProtobuf(c container) *Snapshot {
 		LocalDevices: devices,
 		Architecture: &arch,
 		Stateful:     &stateful,
+		CreationDate: &creationDate,
 	}
 }
 
@@ -326,7 +330,7 @@ func (s *migrationSourceWs) Do(migrateOp *operation) error {
 		}
 	}
 
-	driver, fsErr := s.container.Storage().MigrationSource(s.container)
+	driver, fsErr := s.container.Storage().MigrationSource(s.container, s.containerOnly)
 	/* the protocol says we have to send a header no matter what, so let's
 	 * do that, but then immediately send an error.
 	 */
@@ -372,7 +376,13 @@ func (s *migrationSourceWs) Do(migrateOp *operation) error {
 		myType = MigrationFSType_RSYNC
 		header.Fs = &myType
 
-		driver, _ = rsyncMigrationSource(s.container)
+		driver, _ = rsyncMigrationSource(s.container, s.containerOnly)
+	}
+
+	// When refreshing an existing container, the sink tells us which
+	// snapshots it's missing.
+	if header.GetRefresh() {
+		driver.Refresh(header.SnapshotNames)
 	}
 
 	// All failure paths need to do a few things to correctly handle errors before returning.
@@ -549,8 +559,9 @@ type migrationSink struct {
 	// We are pulling the container from src in pull mode.
 	src migrationFields
 
-	url    string
-	dialer websocket.Dialer
+	url     string
+	dialer  websocket.Dialer
+	refresh bool
 }
 
 type MigrationSinkArgs struct {
@@ -558,13 +569,15 @@ type MigrationSinkArgs struct {
 	Dialer    websocket.Dialer
 	Container container
 	Secrets   map[string]string
+	Refresh   bool
 }
 
 func NewMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
 	sink := migrationSink{
-		src:    migrationFields{container: args.Container},
-		url:    args.Url,
-		dialer: args.Dialer,
+		src:     migrationFields{container: args.Container},
+		url:     args.Url,
+		dialer:  args.Dialer,
+		refresh: args.Refresh,
 	}
 
 	var ok bool
@@ -603,19 +616,120 @@ func (c *migrationSink) connectWithSecret(secret string) (*websocket.Conn, error
 	return conn, err
 }
 
+// deleteContainer removes the container being created after a failed
+// migration. A container being refreshed is left alone.
+func (c *migrationSink) deleteContainer() {
+	if c.refresh {
+		return
+	}
+
+	c.src.container.Delete()
+}
+
+// migrationRefreshSnapshots removes the snapshots of the container being
+// refreshed which don't match the ones of the source, returning the snapshots
+// which need to be transferred: all of the source's from the first one we
+// don't have, or have under the same name but with a different creation date
+// (the snapshot was re-created on the source). This keeps the snapshots of both sides in the same order, so
+// they can be sent as deltas from each other.
+func migrationRefreshSnapshots(c container, snapshots []*Snapshot) ([]*Snapshot, error) {
+	localSnapshots, err := c.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	local := map[string]container{}
+	for _, snap := range localSnapshots {
+		local[shared.ExtractSnapshotName(snap.Name())] = snap
+	}
+
+	common := 0
+	for _, snap := range snapshots {
+		localSnap, ok := local[snap.GetName()]
+		if !ok {
+			break
+		}
+
+		// Older sources don't send the creation date
+		if snap.CreationDate != nil && localSnap.CreationDate().Unix() != snap.GetCreationDate() {
+			break
+		}
+
+		delete(local, snap.GetName())
+		common++
+	}
+
+	for _, snap := range local {
+		err := snap.Delete()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshots[common:], nil
+}
+
+// migrationLocalConns returns both ends of a websocket connection, used to
+// run the storage drivers of a migration between containers of this host.
+func migrationLocalConns() (*websocket.Conn, *websocket.Conn, error) {
+	dir, err := ioutil.TempDir("", "lxd_migration_")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "socket")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer listener.Close()
+
+	accepted := make(chan *websocket.Conn, 1)
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logger.Errorf("Failed to set up local migration websocket: %s", err)
+			accepted <- nil
+			return
+		}
+
+		accepted <- conn
+	}))
+
+	dialer := websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}
+
+	src, _, err := dialer.Dial("ws://lxd/", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dst := <-accepted
+	if dst == nil {
+		src.Close()
+		return nil, nil, fmt.Errorf("Failed to set up local migration websocket")
+	}
+
+	return src, dst, nil
+}
+
 func (c *migrationSink) Do(migrateOp *operation) error {
 	var err error
 
 	c.src.controlConn, err = c.connectWithSecret(c.src.controlSecret)
 	if err != nil {
-		c.src.container.Delete()
+		c.deleteContainer()
 		return err
 	}
 	defer c.src.disconnect()
 
 	c.src.fsConn, err = c.connectWithSecret(c.src.fsSecret)
 	if err != nil {
-		c.src.container.Delete()
+		c.deleteContainer()
 		c.src.sendControl(err)
 		return err
 	}
@@ -623,7 +737,7 @@ func (c *migrationSink) Do(migrateOp *operation) error {
 	if c.src.live {
 		c.src.criuConn, err = c.connectWithSecret(c.src.criuSecret)
 		if err != nil {
-			c.src.container.Delete()
+			c.deleteContainer()
 			c.src.sendControl(err)
 			return err
 		}
@@ -631,7 +745,7 @@ func (c *migrationSink) Do(migrateOp *operation) error {
 
 	header := MigrationHeader{}
 	if err := c.src.recv(&header); err != nil {
-		c.src.container.Delete()
+		c.deleteContainer()
 		c.src.sendControl(err)
 		return err
 	}
@@ -656,9 +770,39 @@ func (c *migrationSink) Do(migrateOp *operation) error {
 		resp.Fs = &myType
 	}
 
+	snapshots := []*Snapshot{}
+
+	/* Legacy: we only sent the snapshot names, so we just copy the
+	 * container's config over, same as we used to do.
+	 */
+	if len(header.SnapshotNames) != len(header.Snapshots) {
+		for _, name := range header.SnapshotNames {
+			base := snapshotToProtobuf(c.src.container)
+			base.Name = proto.String(name)
+			base.CreationDate = nil
+			snapshots = append(snapshots, base)
+		}
+	} else {
+		snapshots = header.Snapshots
+	}
+
+	if c.refresh {
+		snapshots, err = migrationRefreshSnapshots(c.src.container, snapshots)
+		if err != nil {
+			c.src.sendControl(err)
+			return err
+		}
+
+		resp.Refresh = proto.Bool(true)
+		resp.SnapshotNames = []string{}
+		for _, snap := range snapshots {
+			resp.SnapshotNames = append(resp.SnapshotNames, snap.GetName())
+		}
+	}
+
 	err = c.src.send(&resp)
 	if err != nil {
-		c.src.container.Delete()
+		c.deleteContainer()
 		c.src.sendControl(err)
 		return err
 	}
@@ -686,22 +830,6 @@ func (c *migrationSink) Do(migrateOp *operation) error {
 		 */
 		fsTransfer := make(chan error)
 		go func() {
-			snapshots := []*Snapshot{}
-
-			/* Legacy: we only sent the snapshot names, so we just
-			 * copy the container's config over, same as we used to
-			 * do.
-			 */
-			if len(header.SnapshotNames) != len(header.Snapshots) {
-				for _, name := range header.SnapshotNames {
-					base := snapshotToProtobuf(c.src.container)
-					base.Name = &name
-					snapshots = append(snapshots, base)
-				}
-			} else {
-				snapshots = header.Snapshots
-			}
-
 			err := mySink(c.src.live, c.src.container, snapshots, c.src.fsConn, srcIdmap)
 			if err != nil {
 				fsTransfer <- err
@@ -759,19 +887,19 @@ func (c *migrationSink) Do(migrateOp *operation) error {
 		case err = <-restore:
 			c.src.sendControl(err)
 			if err != nil {
-				c.src.container.Delete()
+				c.deleteContainer()
 				return err
 			}
 			return nil
 		case msg, ok := <-source:
 			if !ok {
 				c.src.disconnect()
-				c.src.container.Delete()
+				c.deleteContainer()
 				return fmt.Errorf("Got error reading source")
 			}
 			if !*msg.Success {
 				c.src.disconnect()
-				c.src.container.Delete()
+				c.deleteContainer()
 				return fmt.Errorf(*msg.Message)
 			} else {
 				// The source can only tell us it failed (e.g. if
@@ -780,7 +908,7 @@ func (c *migrationSink) Do(migrateOp *operation) error {
 				logger.Debugf("Unknown message %v from source", msg)
 				err = c.src.container.TemplateApply("copy")
 				if err != nil {
-					c.src.container.Delete()
+					c.deleteContainer()
 					return err
 				}
 			}
@@ -198,6 +198,7 @@ type Snapshot struct {
 	LocalDevices     []*Device `protobuf:"bytes,5,rep,name=localDevices" json:"localDevices,omitempty"`
 	Architecture     *int32    `protobuf:"varint,6,req,name=architecture" json:"architecture,omitempty"`
 	Stateful         *bool     `protobuf:"varint,7,req,name=stateful" json:"stateful,omitempty"`
+	CreationDate     *int64    `protobuf:"varint,8,opt,name=creation_date,json=creationDate" json:"creation_date,omitempty"`
 	XXX_unrecognized []byte    `json:"-"`
 }
 
@@ -254,12 +255,20 @@ func (m *Snapshot) GetStateful() bool {
 	return false
 }
 
+func (m *Snapshot) GetCreationDate() int64 {
+	if m != nil && m.CreationDate != nil {
+		return *m.CreationDate
+	}
+	return 0
+}
+
 type MigrationHeader struct {
 	Fs               *MigrationFSType `protobuf:"varint,1,req,name=fs,enum=main.MigrationFSType" json:"fs,omitempty"`
 	Criu             *CRIUType        `protobuf:"varint,2,opt,name=criu,enum=main.CRIUType" json:"criu,omitempty"`
 	Idmap            []*IDMapType     `protobuf:"bytes,3,rep,name=idmap" json:"idmap,omitempty"`
 	SnapshotNames    []string         `protobuf:"bytes,4,rep,name=snapshotNames" json:"snapshotNames,omitempty"`
 	Snapshots        []*Snapshot      `protobuf:"bytes,5,rep,name=snapshots" json:"snapshots,omitempty"`
+	Refresh          *bool            `protobuf:"varint,6,opt,name=refresh" json:"refresh,omitempty"`
 	XXX_unrecognized []byte           `json:"-"`
 }
 
@@ -302,6 +311,13 @@ func (m *MigrationHeader) GetSnapshots() []*Snapshot {
 	return nil
 }
 
+func (m *MigrationHeader) GetRefresh() bool {
+	if m != nil && m.Refresh != nil {
+		return *m.Refresh
+	}
+	return false
+}
+
 type MigrationControl struct {
 	Success *bool `protobuf:"varint,1,req,name=success" json:"success,omitempty"`
 	// optional failure message if sending a failure
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Messages sent on one end of the local migration connections are received
+// on the other.
+func TestMigrationLocalConns(t *testing.T) {
+	src, dst, err := migrationLocalConns()
+	require.NoError(t, err)
+	defer src.Close()
+	defer dst.Close()
+
+	err = src.WriteMessage(websocket.BinaryMessage, []byte("rootfs"))
+	require.NoError(t, err)
+
+	_, data, err := dst.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "rootfs", string(data))
+
+	err = dst.WriteMessage(websocket.BinaryMessage, []byte("done"))
+	require.NoError(t, err)
+
+	_, data, err = src.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "done", string(data))
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/gorilla/mux"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/types"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/version"
@@ -16,7 +24,11 @@ import (
 
 // Helper functions
 func networkIsInUse(c container, name string) bool {
-	for _, d := range c.ExpandedDevices() {
+	return networkDevicesUse(c.ExpandedDevices(), name)
+}
+
+func networkDevicesUse(devices types.Devices, name string) bool {
+	for _, d := range devices {
 		if d["type"] != "nic" {
 			continue
 		}
@@ -50,13 +62,30 @@ func networksGet(d *Daemon, r *http.Request) Response {
 		return InternalError(err)
 	}
 
+	names := []string{}
+	for _, iface := range ifs {
+		names = append(names, iface.Name)
+	}
+
+	// Managed networks may not currently exist on the host
+	managed, err := db.Networks(d.db)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	for _, name := range managed {
+		if !shared.StringInSlice(name, names) {
+			names = append(names, name)
+		}
+	}
+
 	resultString := []string{}
 	resultMap := []api.Network{}
-	for _, iface := range ifs {
+	for _, name := range names {
 		if recursion == 0 {
-			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s", version.APIVersion, iface.Name))
+			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s", version.APIVersion, name))
 		} else {
-			net, err := doNetworkGet(d, iface.Name)
+			net, err := doNetworkGet(d, name)
 			if err != nil {
 				continue
 			}
@@ -72,29 +101,98 @@ func networksGet(d *Daemon, r *http.Request) Response {
 	return SyncResponse(true, resultMap)
 }
 
-var networksCmd = Command{name: "networks", get: networksGet}
+func networksPost(d *Daemon, r *http.Request) Response {
+	req := api.NetworksPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Sanity checks
+	err = networkValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Type != "" && req.Type != "bridge" {
+		return BadRequest(fmt.Errorf("Only 'bridge' type networks can be created"))
+	}
+
+	networks, err := db.Networks(d.db)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if shared.StringInSlice(req.Name, networks) || shared.PathExists(fmt.Sprintf("/sys/class/net/%s", req.Name)) {
+		return BadRequest(fmt.Errorf("The network already exists"))
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err = networkValidateConfig(req.Name, req.Config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Set some default values where needed
+	err = networkFillAuto(d.State(), req.Config)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Create the database entry
+	_, err = db.NetworkCreate(d.db, req.Name, req.Description, req.Config)
+	if err != nil {
+		return SmartError(fmt.Errorf("Error inserting %s into database: %s", req.Name, err))
+	}
+
+	// Start the network
+	n, err := networkLoadByName(d.State(), req.Name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	err = n.Start()
+	if err != nil {
+		n.Delete()
+		return SmartError(err)
+	}
+
+	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/networks/%s", version.APIVersion, req.Name))
+}
+
+var networksCmd = Command{name: "networks", get: networksGet, post: networksPost}
 
 func networkGet(d *Daemon, r *http.Request) Response {
 	name := mux.Vars(r)["name"]
 
 	n, err := doNetworkGet(d, name)
 	if err != nil {
-		return InternalError(err)
+		return SmartError(err)
 	}
 
 	return SyncResponse(true, &n)
 }
 
 func doNetworkGet(d *Daemon, name string) (api.Network, error) {
-	iface, err := net.InterfaceByName(name)
-	if err != nil {
-		return api.Network{}, err
+	// Get some information
+	osInfo, _ := net.InterfaceByName(name)
+	_, dbInfo, _ := db.NetworkGet(d.db, name)
+
+	// Sanity check
+	if osInfo == nil && dbInfo == nil {
+		return api.Network{}, os.ErrNotExist
 	}
 
 	// Prepare the response
 	n := api.Network{}
-	n.Name = iface.Name
+	n.Name = name
 	n.UsedBy = []string{}
+	n.Config = map[string]string{}
 
 	// Look for containers using the interface
 	cts, err := db.ContainersList(d.db, db.CTypeRegular)
@@ -114,9 +212,15 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 	}
 
 	// Set the device type as needed
-	if shared.IsLoopback(iface) {
+	if osInfo != nil && shared.IsLoopback(osInfo) {
 		n.Type = "loopback"
-	} else if shared.PathExists(fmt.Sprintf("/sys/class/net/%s/bridge", n.Name)) {
+	} else if dbInfo != nil || shared.PathExists(fmt.Sprintf("/sys/class/net/%s/bridge", n.Name)) {
+		if dbInfo != nil {
+			n.Managed = true
+			n.Description = dbInfo.Description
+			n.Config = dbInfo.Config
+		}
+
 		n.Type = "bridge"
 	} else if shared.PathExists(fmt.Sprintf("/proc/net/vlan/%s", n.Name)) {
 		n.Type = "vlan"
@@ -136,4 +240,661 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 	return n, nil
 }
 
-var networkCmd = Command{name: "networks/{name}", get: networkGet}
+func networkDelete(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	// Get the existing network
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return NotFound
+	}
+
+	// Sanity checks
+	if n.IsUsed() {
+		return BadRequest(fmt.Errorf("The network is currently in use"))
+	}
+
+	// Delete the network
+	err = n.Delete()
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return EmptySyncResponse
+}
+
+func networkPost(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+	req := api.NetworkPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Get the existing network
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return NotFound
+	}
+
+	// Sanity checks
+	err = networkValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	networks, err := db.Networks(d.db)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if shared.StringInSlice(req.Name, networks) || shared.PathExists(fmt.Sprintf("/sys/class/net/%s", req.Name)) {
+		return Conflict
+	}
+
+	if n.IsUsed() {
+		return BadRequest(fmt.Errorf("The network is currently in use"))
+	}
+
+	// Rename it
+	err = n.Rename(req.Name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/networks/%s", version.APIVersion, req.Name))
+}
+
+func networkPut(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	// Get the existing network
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return NotFound
+	}
+
+	req := api.NetworkPut{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err = networkValidateConfig(name, req.Config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = n.Update(req)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return EmptySyncResponse
+}
+
+var networkCmd = Command{name: "networks/{name}", get: networkGet, delete: networkDelete, post: networkPost, put: networkPut}
+
+// The network structs and functions
+func networkLoadByName(s *state.State, name string) (*network, error) {
+	id, dbInfo, err := db.NetworkGet(s.DB, name)
+	if err != nil {
+		return nil, err
+	}
+
+	n := network{state: s, id: id, name: name, description: dbInfo.Description, config: dbInfo.Config}
+
+	return &n, nil
+}
+
+type network struct {
+	// Properties
+	state       *state.State
+	id          int64
+	name        string
+	description string
+
+	// config
+	config map[string]string
+}
+
+func (n *network) IsRunning() bool {
+	return shared.PathExists(fmt.Sprintf("/sys/class/net/%s", n.name))
+}
+
+func (n *network) IsUsed() bool {
+	// Look for containers using the interface
+	cts, err := containersExpandedDevices(n.state)
+	if err != nil {
+		return true
+	}
+
+	for _, devices := range cts {
+		if networkDevicesUse(devices, n.name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *network) Delete() error {
+	// Bring the network down
+	if n.IsRunning() {
+		err := n.Stop()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the network runtime files
+	err := os.RemoveAll(networkPath(n.name))
+	if err != nil {
+		return err
+	}
+
+	// Remove the network from the database
+	return db.NetworkDelete(n.state.DB, n.name)
+}
+
+func (n *network) Rename(name string) error {
+	// Bring the network down
+	if n.IsRunning() {
+		err := n.Stop()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rename the runtime files directory
+	if shared.PathExists(networkPath(n.name)) {
+		err := os.Rename(networkPath(n.name), networkPath(name))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rename the database entry
+	err := db.NetworkRename(n.state.DB, n.name, name)
+	if err != nil {
+		return err
+	}
+	n.name = name
+
+	// Bring the network up
+	return n.Start()
+}
+
+func (n *network) Start() error {
+	// Create the runtime files directory
+	err := os.MkdirAll(networkPath(n.name), 0711)
+	if err != nil {
+		return err
+	}
+
+	// Create the bridge interface
+	if !n.IsRunning() {
+		_, err := shared.RunCommand("ip", "link", "add", "dev", n.name, "type", "bridge")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Get a list of tunables
+	if shared.PathExists(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s", n.name)) {
+		// Don't let the bridge configure itself through router advertisements
+		err = networkSysctl(fmt.Sprintf("ipv6/conf/%s/autoconf", n.name), "0")
+		if err != nil {
+			return err
+		}
+
+		err = networkSysctl(fmt.Sprintf("ipv6/conf/%s/accept_dad", n.name), "0")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Set the MTU
+	if n.config["bridge.mtu"] != "" {
+		_, err = shared.RunCommand("ip", "link", "set", "dev", n.name, "mtu", n.config["bridge.mtu"])
+		if err != nil {
+			return err
+		}
+	}
+
+	// Bring it up
+	_, err = shared.RunCommand("ip", "link", "set", "dev", n.name, "up")
+	if err != nil {
+		return err
+	}
+
+	// Add any listed existing external interface
+	if n.config["bridge.external_interfaces"] != "" {
+		for _, entry := range strings.Split(n.config["bridge.external_interfaces"], ",") {
+			entry = strings.TrimSpace(entry)
+			iface, err := net.InterfaceByName(entry)
+			if err != nil {
+				continue
+			}
+
+			addrs, err := iface.Addrs()
+			if err == nil && len(addrs) != 0 {
+				return fmt.Errorf("Only unconfigured network interfaces can be bridged")
+			}
+
+			_, err = shared.RunCommand("ip", "link", "set", "dev", entry, "master", n.name)
+			if err != nil {
+				return err
+			}
+
+			_, err = shared.RunCommand("ip", "link", "set", "dev", entry, "up")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Remove any existing IPv4 and IPv6 configuration
+	_, err = shared.RunCommand("ip", "-4", "addr", "flush", "dev", n.name, "scope", "global")
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("ip", "-6", "addr", "flush", "dev", n.name, "scope", "global")
+	if err != nil {
+		return err
+	}
+
+	// Flush all the existing firewall rules
+	for _, protocol := range []string{"ipv4", "ipv6"} {
+		for _, table := range []string{"filter", "nat", "mangle"} {
+			err = networkIptablesClear(protocol, n.name, table)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Kill any existing dnsmasq daemon for this network
+	err = networkKillDnsmasq(n.name)
+	if err != nil {
+		return err
+	}
+
+	// Configure the dnsmasq daemon
+	dnsmasqCmd := []string{"--strict-order", "--bind-interfaces",
+		fmt.Sprintf("--pid-file=%s", filepath.Join(networkPath(n.name), "dnsmasq.pid")),
+		"--except-interface=lo",
+		fmt.Sprintf("--interface=%s", n.name)}
+
+	// Configure IPv4
+	if !shared.StringInSlice(n.config["ipv4.address"], []string{"", "none"}) {
+		err = n.startIPv4(&dnsmasqCmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Configure IPv6
+	if !shared.StringInSlice(n.config["ipv6.address"], []string{"", "none"}) {
+		err = n.startIPv6(&dnsmasqCmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Start the dnsmasq daemon
+	if len(dnsmasqCmd) > 5 {
+		dnsmasqCmd = append(dnsmasqCmd, []string{
+			"--dhcp-no-override", "--dhcp-authoritative",
+			fmt.Sprintf("--dhcp-leasefile=%s", filepath.Join(networkPath(n.name), "dnsmasq.leases")),
+			"-u", networkDnsmasqUser()}...)
+
+		dnsDomain := n.config["dns.domain"]
+		if dnsDomain == "" {
+			dnsDomain = "lxd"
+		}
+
+		switch n.config["dns.mode"] {
+		case "none":
+			dnsmasqCmd = append(dnsmasqCmd, "--port=0")
+		case "dynamic":
+			dnsmasqCmd = append(dnsmasqCmd, "-s", dnsDomain, "-S", fmt.Sprintf("/%s/", dnsDomain))
+		default:
+			// Only resolve the names of the containers themselves
+			hosts, err := networkStaticHosts(n.state)
+			if err != nil {
+				return err
+			}
+
+			err = n.updateStatic(hosts[n.name], false)
+			if err != nil {
+				return err
+			}
+
+			dnsmasqCmd = append(dnsmasqCmd, "-s", dnsDomain, "-S", fmt.Sprintf("/%s/", dnsDomain), "--dhcp-ignore-names",
+				fmt.Sprintf("--dhcp-hostsdir=%s", filepath.Join(networkPath(n.name), "dnsmasq.hosts")))
+		}
+
+		if n.config["raw.dnsmasq"] != "" {
+			rawPath := filepath.Join(networkPath(n.name), "dnsmasq.raw")
+			err = ioutil.WriteFile(rawPath, []byte(n.config["raw.dnsmasq"]+"\n"), 0)
+			if err != nil {
+				return err
+			}
+
+			dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--conf-file=%s", rawPath))
+		} else {
+			dnsmasqCmd = append(dnsmasqCmd, "--conf-file=/dev/null")
+		}
+
+		output, err := shared.RunCommand("dnsmasq", dnsmasqCmd...)
+		if err != nil {
+			return fmt.Errorf("Failed to run: dnsmasq %s: %s", strings.Join(dnsmasqCmd, " "), strings.TrimSpace(output))
+		}
+	}
+
+	return nil
+}
+
+// updateStatic writes one DHCP host entry per container to the dnsmasq hosts
+// directory of the network. dnsmasq picks up new entries on its own but
+// needs to be reloaded for changed and removed ones to be forgotten.
+func (n *network) updateStatic(hosts map[string]string, reload bool) error {
+	hostsPath := filepath.Join(networkPath(n.name), "dnsmasq.hosts")
+
+	if shared.StringInSlice(n.config["dns.mode"], []string{"none", "dynamic"}) {
+		return os.RemoveAll(hostsPath)
+	}
+
+	err := os.MkdirAll(hostsPath, 0755)
+	if err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(hostsPath)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, entry := range entries {
+		_, ok := hosts[entry.Name()]
+		if ok {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(hostsPath, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		changed = true
+	}
+
+	for ct, host := range hosts {
+		path := filepath.Join(hostsPath, ct)
+
+		content, err := ioutil.ReadFile(path)
+		if err == nil && string(content) == host {
+			continue
+		}
+
+		if err == nil {
+			changed = true
+		}
+
+		err = ioutil.WriteFile(path, []byte(host), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !changed || !reload {
+		return nil
+	}
+
+	pid, err := networkDnsmasqPid(n.name)
+	if err != nil || pid == 0 {
+		return err
+	}
+
+	return syscall.Kill(pid, syscall.SIGHUP)
+}
+
+func (n *network) startIPv4(dnsmasqCmd *[]string) error {
+	ip, subnet, err := net.ParseCIDR(n.config["ipv4.address"])
+	if err != nil {
+		return err
+	}
+
+	// Allow forwarding
+	if n.config["ipv4.routing"] == "" || shared.IsTrue(n.config["ipv4.routing"]) {
+		err = networkSysctl("ipv4/ip_forward", "1")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add the address
+	_, err = shared.RunCommand("ip", "-4", "addr", "add", "dev", n.name, n.config["ipv4.address"])
+	if err != nil {
+		return err
+	}
+
+	// Allow DHCP and DNS traffic to dnsmasq
+	for _, rule := range [][]string{
+		{"-i", n.name, "-p", "udp", "--dport", "67", "-j", "ACCEPT"},
+		{"-i", n.name, "-p", "udp", "--dport", "53", "-j", "ACCEPT"},
+		{"-i", n.name, "-p", "tcp", "--dport", "53", "-j", "ACCEPT"},
+	} {
+		err = networkIptablesPrepend("ipv4", n.name, "filter", "INPUT", rule...)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Allow forwarding through the bridge
+	if n.config["ipv4.firewall"] == "" || shared.IsTrue(n.config["ipv4.firewall"]) {
+		err = networkIptablesPrepend("ipv4", n.name, "filter", "FORWARD", "-i", n.name, "-j", "ACCEPT")
+		if err != nil {
+			return err
+		}
+
+		err = networkIptablesPrepend("ipv4", n.name, "filter", "FORWARD", "-o", n.name, "-j", "ACCEPT")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Fix DHCP checksums for clients which refuse offloaded ones
+	networkIptablesPrepend("ipv4", n.name, "mangle", "POSTROUTING", "-o", n.name, "-p", "udp", "--dport", "68", "-j", "CHECKSUM", "--checksum-fill")
+
+	// Configure NAT
+	if shared.IsTrue(n.config["ipv4.nat"]) {
+		err = networkIptablesPrepend("ipv4", n.name, "nat", "POSTROUTING", "-s", subnet.String(), "!", "-d", subnet.String(), "-j", "MASQUERADE")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Configure DHCP
+	*dnsmasqCmd = append(*dnsmasqCmd, fmt.Sprintf("--listen-address=%s", ip.String()))
+	if n.config["ipv4.dhcp"] == "" || shared.IsTrue(n.config["ipv4.dhcp"]) {
+		if n.config["ipv4.dhcp.ranges"] != "" {
+			for _, dhcpRange := range strings.Split(n.config["ipv4.dhcp.ranges"], ",") {
+				dhcpRange = strings.TrimSpace(dhcpRange)
+				*dnsmasqCmd = append(*dnsmasqCmd, "--dhcp-range", strings.Replace(dhcpRange, "-", ",", -1))
+			}
+		} else {
+			first, last := networkDHCPv4Range(subnet)
+			*dnsmasqCmd = append(*dnsmasqCmd, "--dhcp-range", fmt.Sprintf("%s,%s", first, last))
+		}
+	}
+
+	return nil
+}
+
+func (n *network) startIPv6(dnsmasqCmd *[]string) error {
+	ip, subnet, err := net.ParseCIDR(n.config["ipv6.address"])
+	if err != nil {
+		return err
+	}
+
+	// Allow forwarding
+	if n.config["ipv6.routing"] == "" || shared.IsTrue(n.config["ipv6.routing"]) {
+		// Keep accepting router advertisements on the host interfaces
+		entries, err := ioutil.ReadDir("/proc/sys/net/ipv6/conf")
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			err = networkSysctl(fmt.Sprintf("ipv6/conf/%s/accept_ra", entry.Name()), "2")
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		err = networkSysctl("ipv6/conf/all/forwarding", "1")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add the address
+	_, err = shared.RunCommand("ip", "-6", "addr", "add", "dev", n.name, n.config["ipv6.address"])
+	if err != nil {
+		return err
+	}
+
+	// Allow DHCP and DNS traffic to dnsmasq
+	for _, rule := range [][]string{
+		{"-i", n.name, "-p", "udp", "--dport", "547", "-j", "ACCEPT"},
+		{"-i", n.name, "-p", "udp", "--dport", "53", "-j", "ACCEPT"},
+		{"-i", n.name, "-p", "tcp", "--dport", "53", "-j", "ACCEPT"},
+	} {
+		err = networkIptablesPrepend("ipv6", n.name, "filter", "INPUT", rule...)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Allow forwarding through the bridge
+	if n.config["ipv6.firewall"] == "" || shared.IsTrue(n.config["ipv6.firewall"]) {
+		err = networkIptablesPrepend("ipv6", n.name, "filter", "FORWARD", "-i", n.name, "-j", "ACCEPT")
+		if err != nil {
+			return err
+		}
+
+		err = networkIptablesPrepend("ipv6", n.name, "filter", "FORWARD", "-o", n.name, "-j", "ACCEPT")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Configure NAT
+	if shared.IsTrue(n.config["ipv6.nat"]) {
+		err = networkIptablesPrepend("ipv6", n.name, "nat", "POSTROUTING", "-s", subnet.String(), "!", "-d", subnet.String(), "-j", "MASQUERADE")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Configure router advertisements and stateless DHCP
+	*dnsmasqCmd = append(*dnsmasqCmd, fmt.Sprintf("--listen-address=%s", ip.String()), "--enable-ra")
+	if n.config["ipv6.dhcp"] == "" || shared.IsTrue(n.config["ipv6.dhcp"]) {
+		*dnsmasqCmd = append(*dnsmasqCmd, "--dhcp-range", fmt.Sprintf("::,constructor:%s,ra-stateless,ra-names", n.name))
+	} else {
+		*dnsmasqCmd = append(*dnsmasqCmd, "--dhcp-range", fmt.Sprintf("::,constructor:%s,ra-only", n.name))
+	}
+
+	return nil
+}
+
+func (n *network) Stop() error {
+	if !n.IsRunning() {
+		return fmt.Errorf("The network is already stopped")
+	}
+
+	// Destroy the bridge interface
+	_, err := shared.RunCommand("ip", "link", "del", "dev", n.name)
+	if err != nil {
+		return err
+	}
+
+	// Cleanup the firewall
+	for _, protocol := range []string{"ipv4", "ipv6"} {
+		for _, table := range []string{"filter", "nat", "mangle"} {
+			err = networkIptablesClear(protocol, n.name, table)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Kill any existing dnsmasq daemon for this network
+	return networkKillDnsmasq(n.name)
+}
+
+func (n *network) Update(newNetwork api.NetworkPut) error {
+	// Resolve any new "auto" address
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		if newNetwork.Config[key] != "auto" {
+			continue
+		}
+
+		if n.config[key] != "" && !shared.StringInSlice(n.config[key], []string{"auto", "none"}) {
+			newNetwork.Config[key] = n.config[key]
+			continue
+		}
+
+		var subnet string
+		var err error
+		if key == "ipv4.address" {
+			subnet, err = networkRandomSubnetV4(n.state)
+		} else {
+			subnet, err = networkRandomSubnetV6(n.state)
+		}
+		if err != nil {
+			return err
+		}
+
+		newNetwork.Config[key] = subnet
+	}
+
+	// Release the external interfaces which were removed
+	oldInterfaces := strings.Split(n.config["bridge.external_interfaces"], ",")
+	newInterfaces := strings.Split(newNetwork.Config["bridge.external_interfaces"], ",")
+	for _, entry := range oldInterfaces {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || shared.StringInSlice(entry, newInterfaces) {
+			continue
+		}
+
+		if shared.PathExists(fmt.Sprintf("/sys/class/net/%s/master", entry)) {
+			shared.RunCommand("ip", "link", "set", "dev", entry, "nomaster")
+		}
+	}
+
+	// Update the database
+	err := db.NetworkUpdate(n.state.DB, n.id, newNetwork.Description, newNetwork.Config)
+	if err != nil {
+		return err
+	}
+
+	n.description = newNetwork.Description
+	n.config = newNetwork.Config
+
+	// Apply the new configuration
+	if n.IsRunning() {
+		return n.Start()
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+)
+
+func networkValidateConfig(name string, config map[string]string) error {
+	isBool := func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		if !shared.StringInSlice(strings.ToLower(value), []string{"true", "false", "yes", "no", "1", "0", "on", "off"}) {
+			return fmt.Errorf("Invalid value for a boolean: %s", value)
+		}
+
+		return nil
+	}
+
+	isOneOf := func(value string, valid []string) error {
+		if value == "" {
+			return nil
+		}
+
+		if !shared.StringInSlice(value, valid) {
+			return fmt.Errorf("Invalid value: %s (not one of %s)", value, valid)
+		}
+
+		return nil
+	}
+
+	isAddress := func(value string, ipv4 bool) error {
+		if shared.StringInSlice(value, []string{"", "none", "auto"}) {
+			return nil
+		}
+
+		ip, _, err := net.ParseCIDR(value)
+		if err != nil {
+			return fmt.Errorf("Invalid network address: %s", value)
+		}
+
+		if (ip.To4() != nil) != ipv4 {
+			return fmt.Errorf("Wrong address family: %s", value)
+		}
+
+		return nil
+	}
+
+	for k, v := range config {
+		var err error
+
+		switch k {
+		case "bridge.external_interfaces":
+			for _, entry := range strings.Split(v, ",") {
+				entry = strings.TrimSpace(entry)
+				if entry == name {
+					return fmt.Errorf("A network can't include itself as an external interface")
+				}
+
+				err = networkValidName(entry)
+				if err != nil {
+					break
+				}
+			}
+		case "bridge.mtu":
+			if v != "" {
+				_, err = strconv.ParseUint(v, 10, 32)
+			}
+		case "ipv4.address":
+			err = isAddress(v, true)
+		case "ipv6.address":
+			err = isAddress(v, false)
+		case "ipv4.nat", "ipv4.dhcp", "ipv4.firewall", "ipv4.routing",
+			"ipv6.nat", "ipv6.dhcp", "ipv6.firewall", "ipv6.routing":
+			err = isBool(v)
+		case "ipv4.dhcp.ranges":
+			for _, entry := range strings.Split(v, ",") {
+				fields := strings.SplitN(strings.TrimSpace(entry), "-", 2)
+				if len(fields) != 2 || net.ParseIP(fields[0]).To4() == nil || net.ParseIP(fields[1]).To4() == nil {
+					err = fmt.Errorf("Invalid IPv4 range: %s", entry)
+					break
+				}
+			}
+		case "dns.domain", "raw.dnsmasq":
+		case "dns.mode":
+			err = isOneOf(v, []string{"managed", "dynamic", "none"})
+		default:
+			return fmt.Errorf("Invalid network configuration key: %s", k)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Invalid value for %s: %v", k, err)
+		}
+	}
+
+	return nil
+}
+
+// networkFillAuto fills in the default configuration of a new network and
+// resolves "auto" addresses into actual subnets.
+func networkFillAuto(s *state.State, config map[string]string) error {
+	if config["ipv4.address"] == "" {
+		config["ipv4.address"] = "auto"
+	}
+
+	if config["ipv4.address"] == "auto" {
+		subnet, err := networkRandomSubnetV4(s)
+		if err != nil {
+			return err
+		}
+
+		config["ipv4.address"] = subnet
+		if config["ipv4.nat"] == "" {
+			config["ipv4.nat"] = "true"
+		}
+	}
+
+	if config["ipv6.address"] == "" && shared.PathExists("/proc/sys/net/ipv6") {
+		config["ipv6.address"] = "auto"
+	}
+
+	if config["ipv6.address"] == "auto" {
+		subnet, err := networkRandomSubnetV6(s)
+		if err != nil {
+			return err
+		}
+
+		config["ipv6.address"] = subnet
+		if config["ipv6.nat"] == "" {
+			config["ipv6.nat"] = "true"
+		}
+	}
+
+	return nil
+}
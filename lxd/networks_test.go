@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The default DHCP range covers the subnet minus the network, gateway and
+// broadcast addresses.
+func TestNetworkDHCPv4Range(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.3.1/24")
+	assert.NoError(t, err)
+
+	first, last := networkDHCPv4Range(subnet)
+	assert.Equal(t, "10.0.3.2", first)
+	assert.Equal(t, "10.0.3.254", last)
+}
+
+// Quoted comments are kept as a single argument.
+func TestNetworkIptablesSplit(t *testing.T) {
+	line := `-A FORWARD -o lxdbr0 -m comment --comment "generated for LXD network lxdbr0" -j ACCEPT`
+	fields := networkIptablesSplit(line)
+
+	assert.Equal(t, []string{"-A", "FORWARD", "-o", "lxdbr0", "-m", "comment", "--comment", "generated for LXD network lxdbr0", "-j", "ACCEPT"}, fields)
+}
+
+func TestNetworkValidateConfig(t *testing.T) {
+	assert.NoError(t, networkValidateConfig("lxdbr0", map[string]string{"ipv4.address": "10.0.3.1/24", "ipv6.address": "auto", "ipv4.nat": "true"}))
+	assert.Error(t, networkValidateConfig("lxdbr0", map[string]string{"ipv4.address": "fd42::1/64"}))
+	assert.Error(t, networkValidateConfig("lxdbr0", map[string]string{"bridge.external_interfaces": "lxdbr0"}))
+	assert.Error(t, networkValidateConfig("lxdbr0", map[string]string{"ipv4.dhcp.ranges": "10.0.3.2"}))
+	assert.Error(t, networkValidateConfig("lxdbr0", map[string]string{"foo": "bar"}))
+}
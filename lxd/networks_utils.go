@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+
+	log "gopkg.in/inconshreveable/log15.v2"
+)
+
+func networkValidName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No name provided")
+	}
+
+	// Linux limits interface names to 15 characters
+	if len(name) > 15 {
+		return fmt.Errorf("Network names may not be longer than 15 characters")
+	}
+
+	if shared.StringInSlice(name, []string{".", ".."}) {
+		return fmt.Errorf("Invalid network name '%s'", name)
+	}
+
+	for _, r := range name {
+		if r == '/' || r == ':' || r == ' ' || r == '\t' || r == '\n' {
+			return fmt.Errorf("Network names may not contain slashes, colons or whitespaces")
+		}
+	}
+
+	return nil
+}
+
+// networkPath returns the directory holding the runtime files of a managed
+// network.
+func networkPath(name string) string {
+	return shared.VarPath("networks", name)
+}
+
+func networkSysctl(path string, value string) error {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/sys/net/%s", path))
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(string(content)) == value {
+		return nil
+	}
+
+	return ioutil.WriteFile(fmt.Sprintf("/proc/sys/net/%s", path), []byte(value), 0)
+}
+
+// networkGetRoutes returns the subnets currently routed on the host.
+func networkGetRoutes(family string) ([]*net.IPNet, error) {
+	output, err := shared.RunCommand("ip", family, "route", "show")
+	if err != nil {
+		return nil, err
+	}
+
+	subnets := []*net.IPNet{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		_, subnet, err := net.ParseCIDR(fields[0])
+		if err != nil {
+			continue
+		}
+
+		subnets = append(subnets, subnet)
+	}
+
+	return subnets, nil
+}
+
+func networkSubnetsOverlap(a *net.IPNet, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// networkManagedSubnets returns the subnets used by the other managed
+// networks.
+func networkManagedSubnets(s *state.State, key string, except string) ([]*net.IPNet, error) {
+	names, err := db.Networks(s.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	subnets := []*net.IPNet{}
+	for _, name := range names {
+		if name == except {
+			continue
+		}
+
+		_, network, err := db.NetworkGet(s.DB, name)
+		if err != nil {
+			return nil, err
+		}
+
+		_, subnet, err := net.ParseCIDR(network.Config[key])
+		if err != nil {
+			continue
+		}
+
+		subnets = append(subnets, subnet)
+	}
+
+	return subnets, nil
+}
+
+// networkRandomSubnetV4 picks an unused 10.x.y.0/24 subnet and returns the
+// address of the bridge within it.
+func networkRandomSubnetV4(s *state.State) (string, error) {
+	used, err := networkGetRoutes("-4")
+	if err != nil {
+		return "", err
+	}
+
+	managed, err := networkManagedSubnets(s, "ipv4.address", "")
+	if err != nil {
+		return "", err
+	}
+	used = append(used, managed...)
+
+	for i := 0; i < 100; i++ {
+		cidr := fmt.Sprintf("10.%d.%d.1/24", rand.Intn(255), rand.Intn(255))
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return "", err
+		}
+
+		conflict := false
+		for _, u := range used {
+			// Ignore the default route
+			if ones, _ := u.Mask.Size(); ones == 0 {
+				continue
+			}
+
+			if networkSubnetsOverlap(u, subnet) {
+				conflict = true
+				break
+			}
+		}
+
+		if !conflict {
+			return cidr, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unable to find a free IPv4 subnet")
+}
+
+// networkRandomSubnetV6 picks a random unique local /64 and returns the
+// address of the bridge within it.
+func networkRandomSubnetV6(s *state.State) (string, error) {
+	managed, err := networkManagedSubnets(s, "ipv6.address", "")
+	if err != nil {
+		return "", err
+	}
+
+	for i := 0; i < 100; i++ {
+		cidr := fmt.Sprintf("fd42:%x:%x:%x::1/64", rand.Intn(65535), rand.Intn(65535), rand.Intn(65535))
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return "", err
+		}
+
+		conflict := false
+		for _, u := range managed {
+			if networkSubnetsOverlap(u, subnet) {
+				conflict = true
+				break
+			}
+		}
+
+		if !conflict {
+			return cidr, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unable to find a free IPv6 subnet")
+}
+
+// networkDHCPv4Range returns the default DHCP range of an IPv4 subnet, all of
+// it but the network, gateway and broadcast addresses.
+func networkDHCPv4Range(subnet *net.IPNet) (string, string) {
+	first := make(net.IP, 4)
+	last := make(net.IP, 4)
+	network := subnet.IP.To4()
+	for i := range network {
+		first[i] = network[i]
+		last[i] = network[i] | ^subnet.Mask[i]
+	}
+
+	first[3] += 2
+	last[3]--
+
+	return first.String(), last.String()
+}
+
+// Firewall handling
+func networkIptablesComment(name string) string {
+	return fmt.Sprintf("generated for LXD network %s", name)
+}
+
+func networkIptablesCommand(protocol string) string {
+	if protocol == "ipv6" {
+		return "ip6tables"
+	}
+
+	return "iptables"
+}
+
+func networkIptablesPrepend(protocol string, name string, table string, chain string, rule ...string) error {
+	cmd := networkIptablesCommand(protocol)
+
+	args := []string{"-w", "-t", table, "-I", chain}
+	args = append(args, rule...)
+	args = append(args, "-m", "comment", "--comment", networkIptablesComment(name))
+
+	_, err := shared.RunCommand(cmd, args...)
+	if err != nil {
+		return fmt.Errorf("Failed to add %s rule for network %s: %v", cmd, name, err)
+	}
+
+	return nil
+}
+
+// networkIptablesSplit splits a rule as printed by iptables-save, keeping
+// quoted arguments together.
+func networkIptablesSplit(line string) []string {
+	fields := []string{}
+	current := ""
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if current != "" {
+				fields = append(fields, current)
+				current = ""
+			}
+		default:
+			current += string(r)
+		}
+	}
+
+	if current != "" {
+		fields = append(fields, current)
+	}
+
+	return fields
+}
+
+func networkIptablesClear(protocol string, name string, table string) error {
+	cmd := networkIptablesCommand(protocol)
+
+	// Nothing to clear if the tool isn't available
+	_, err := exec.LookPath(fmt.Sprintf("%s-save", cmd))
+	if err != nil {
+		return nil
+	}
+
+	output, err := shared.RunCommand(fmt.Sprintf("%s-save", cmd), "-t", table)
+	if err != nil {
+		return err
+	}
+
+	comment := fmt.Sprintf("\"%s\"", networkIptablesComment(name))
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "-A ") || !strings.Contains(line, comment) {
+			continue
+		}
+
+		fields := networkIptablesSplit(line)
+		fields[0] = "-D"
+
+		args := append([]string{"-w", "-t", table}, fields...)
+		_, err := shared.RunCommand(cmd, args...)
+		if err != nil {
+			return fmt.Errorf("Failed to remove %s rule for network %s: %v", cmd, name, err)
+		}
+	}
+
+	return nil
+}
+
+// dnsmasq handling
+func networkDnsmasqUser() string {
+	// Pick the most restricted user available on the system
+	for _, username := range []string{"lxd", "dnsmasq"} {
+		_, err := user.Lookup(username)
+		if err == nil {
+			return username
+		}
+	}
+
+	return "nobody"
+}
+
+// networkDnsmasqPid returns the PID of the dnsmasq daemon of the network,
+// or 0 if it isn't running.
+func networkDnsmasqPid(name string) (int, error) {
+	pidPath := filepath.Join(networkPath(name), "dnsmasq.pid")
+	if !shared.PathExists(pidPath) {
+		return 0, nil
+	}
+
+	content, err := ioutil.ReadFile(pidPath)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, nil
+	}
+
+	// Make sure the process is still our dnsmasq
+	cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || !strings.Contains(string(cmdline), "dnsmasq") {
+		return 0, nil
+	}
+
+	return pid, nil
+}
+
+func networkKillDnsmasq(name string) error {
+	pidPath := filepath.Join(networkPath(name), "dnsmasq.pid")
+	if !shared.PathExists(pidPath) {
+		return nil
+	}
+
+	pid, err := networkDnsmasqPid(name)
+	if err != nil {
+		return err
+	}
+
+	if pid > 0 {
+		err = syscall.Kill(pid, syscall.SIGKILL)
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.Remove(pidPath)
+}
+
+// networkStaticHosts returns the DHCP host entries of the containers
+// attached to each managed network, indexed by network and container name.
+// Containers are only listed once LXD has assigned a MAC address to their
+// interface.
+func networkStaticHosts(s *state.State) (map[string]map[string]string, error) {
+	cts, err := db.ContainersList(s.DB, db.CTypeRegular)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := map[string]map[string]string{}
+	for _, ct := range cts {
+		args, err := db.ContainerGet(s.DB, ct)
+		if err != nil {
+			return nil, err
+		}
+
+		c := containerLXC{
+			state:        s,
+			name:         args.Name,
+			profiles:     args.Profiles,
+			localConfig:  args.Config,
+			localDevices: args.Devices,
+		}
+
+		err = c.expandDevices()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, k := range c.expandedDevices.DeviceNames() {
+			m := c.expandedDevices[k]
+			if m["type"] != "nic" || m["nictype"] != "bridged" || m["parent"] == "" {
+				continue
+			}
+
+			hwaddr := m["hwaddr"]
+			if hwaddr == "" {
+				hwaddr = args.Config[fmt.Sprintf("volatile.%s.hwaddr", k)]
+			}
+
+			if hwaddr == "" {
+				continue
+			}
+
+			if hosts[m["parent"]] == nil {
+				hosts[m["parent"]] = map[string]string{}
+			}
+
+			hosts[m["parent"]][ct] = fmt.Sprintf("%s,%s\n", hwaddr, ct)
+		}
+	}
+
+	return hosts, nil
+}
+
+// networkUpdateStatic refreshes the DHCP host entries of all the managed
+// networks, so that dnsmasq keeps resolving the names of their containers.
+func networkUpdateStatic(s *state.State) error {
+	names, err := db.Networks(s.DB)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	hosts, err := networkStaticHosts(s)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		n, err := networkLoadByName(s, name)
+		if err != nil {
+			return err
+		}
+
+		err = n.updateStatic(hosts[name], true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// networkStartup brings up all the managed networks.
+func networkStartup(s *state.State) error {
+	names, err := db.Networks(s.DB)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		n, err := networkLoadByName(s, name)
+		if err != nil {
+			return err
+		}
+
+		err = n.Start()
+		if err != nil {
+			// Don't cause LXD to fail to start entirely on network bring up failure
+			logger.Error("Failed to bring up network", log.Ctx{"err": err, "name": name})
+		}
+	}
+
+	return nil
+}
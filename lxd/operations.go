@@ -11,6 +11,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pborman/uuid"
 
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -51,6 +52,10 @@ type operation struct {
 	readonly  bool
 	canceler  *cancel.Canceler
 
+	// Fingerprint of the certificate of the client which created the
+	// operation, if known
+	requestor string
+
 	// Those functions are called at various points in the operation lifecycle
 	onRun     func(*operation) error
 	onCancel  func(*operation) error
@@ -125,7 +130,7 @@ func (op *operation) Run() (chan error, error) {
 				logger.Debugf("Failure for %s operation: %s: %s", op.class.String(), op.id, err)
 
 				_, md, _ := op.Render()
-				eventSend("operation", md)
+				eventSendOperation(op, md)
 				return
 			}
 
@@ -138,7 +143,7 @@ func (op *operation) Run() (chan error, error) {
 			op.lock.Lock()
 			logger.Debugf("Success for %s operation: %s", op.class.String(), op.id)
 			_, md, _ := op.Render()
-			eventSend("operation", md)
+			eventSendOperation(op, md)
 			op.lock.Unlock()
 		}(op, chanRun)
 	}
@@ -146,7 +151,7 @@ func (op *operation) Run() (chan error, error) {
 
 	logger.Debugf("Started %s operation: %s", op.class.String(), op.id)
 	_, md, _ := op.Render()
-	eventSend("operation", md)
+	eventSendOperation(op, md)
 
 	return chanRun, nil
 }
@@ -178,7 +183,7 @@ func (op *operation) Cancel() (chan error, error) {
 
 				logger.Debugf("Failed to cancel %s operation: %s: %s", op.class.String(), op.id, err)
 				_, md, _ := op.Render()
-				eventSend("operation", md)
+				eventSendOperation(op, md)
 				return
 			}
 
@@ -190,13 +195,13 @@ func (op *operation) Cancel() (chan error, error) {
 
 			logger.Debugf("Cancelled %s operation: %s", op.class.String(), op.id)
 			_, md, _ := op.Render()
-			eventSend("operation", md)
+			eventSendOperation(op, md)
 		}(op, oldStatus, chanCancel)
 	}
 
 	logger.Debugf("Cancelling %s operation: %s", op.class.String(), op.id)
 	_, md, _ := op.Render()
-	eventSend("operation", md)
+	eventSendOperation(op, md)
 
 	if op.canceler != nil {
 		err := op.canceler.Cancel()
@@ -215,7 +220,7 @@ func (op *operation) Cancel() (chan error, error) {
 
 	logger.Debugf("Cancelled %s operation: %s", op.class.String(), op.id)
 	_, md, _ = op.Render()
-	eventSend("operation", md)
+	eventSendOperation(op, md)
 
 	return chanCancel, nil
 }
@@ -341,7 +346,7 @@ func (op *operation) UpdateResources(opResources map[string][]string) error {
 
 	logger.Debugf("Updated resources for %s operation: %s", op.class.String(), op.id)
 	_, md, _ := op.Render()
-	eventSend("operation", md)
+	eventSendOperation(op, md)
 
 	return nil
 }
@@ -367,7 +372,7 @@ func (op *operation) UpdateMetadata(opMetadata interface{}) error {
 
 	logger.Debugf("Updated metadata for %s operation: %s", op.class.String(), op.id)
 	_, md, _ := op.Render()
-	eventSend("operation", md)
+	eventSendOperation(op, md)
 
 	return nil
 }
@@ -422,11 +427,92 @@ func operationCreate(opClass operationClass, opResources map[string][]string, op
 
 	logger.Debugf("New %s operation: %s", op.class.String(), op.id)
 	_, md, _ := op.Render()
-	eventSend("operation", md)
+	eventSendOperation(&op, md)
 
 	return &op, nil
 }
 
+// The metadata keys holding the secrets needed to connect to a websocket
+// operation
+var operationSecretKeys = []string{"fds", "control", "fs", "criu"}
+
+// SetRequestor records the client behind the request as the creator of the
+// operation.
+func (op *operation) SetRequestor(d *Daemon, r *http.Request) {
+	cert := certificateFromRequest(d, r)
+	if cert != nil {
+		op.requestor = cert.Fingerprint
+	}
+}
+
+// operationVisible returns whether the client behind the request may see the
+// operation. Restricted certificates only see the operations of their
+// containers.
+func operationVisible(d *Daemon, r *http.Request, op *operation) bool {
+	return operationVisibleTo(certificateFromRequest(d, r), op)
+}
+
+// operationVisibleTo returns whether the given certificate, nil for local
+// users, may see the operation.
+func operationVisibleTo(cert *db.CertInfo, op *operation) bool {
+	if cert == nil || !certificateIsRestricted(cert) {
+		return true
+	}
+
+	containers := op.resources["containers"]
+	if len(containers) == 0 {
+		return false
+	}
+
+	for _, name := range containers {
+		if !certificateAllowsContainer(cert, name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// operationRender renders the operation for the client behind the request,
+// hiding the websocket secrets of operations it didn't create unless it has
+// full administrative access.
+func operationRender(d *Daemon, r *http.Request, op *operation) (*api.Operation, error) {
+	_, body, err := op.Render()
+	if err != nil {
+		return nil, err
+	}
+
+	return operationHideSecrets(certificateFromRequest(d, r), certificateIsFullAdmin(d, r), op, body), nil
+}
+
+// operationHideSecrets returns the rendered operation as seen by the given
+// certificate, nil for local users. The websocket secrets are left out of a
+// copy of it unless the certificate has full administrative access or
+// created the operation.
+func operationHideSecrets(cert *db.CertInfo, fullAdmin bool, op *operation, body *api.Operation) *api.Operation {
+	if op.class != operationClassWebsocket || fullAdmin {
+		return body
+	}
+
+	if cert != nil && cert.Fingerprint == op.requestor {
+		return body
+	}
+
+	metadata := map[string]interface{}{}
+	for k, v := range body.Metadata {
+		if shared.StringInSlice(k, operationSecretKeys) {
+			continue
+		}
+
+		metadata[k] = v
+	}
+
+	hidden := *body
+	hidden.Metadata = metadata
+
+	return &hidden
+}
+
 func operationGet(id string) (*operation, error) {
 	operationsLock.Lock()
 	op, ok := operations[id]
@@ -444,11 +530,11 @@ func operationAPIGet(d *Daemon, r *http.Request) Response {
 	id := mux.Vars(r)["id"]
 
 	op, err := operationGet(id)
-	if err != nil {
+	if err != nil || !operationVisible(d, r, op) {
 		return NotFound
 	}
 
-	_, body, err := op.Render()
+	body, err := operationRender(d, r, op)
 	if err != nil {
 		return SmartError(err)
 	}
@@ -460,7 +546,7 @@ func operationAPIDelete(d *Daemon, r *http.Request) Response {
 	id := mux.Vars(r)["id"]
 
 	op, err := operationGet(id)
-	if err != nil {
+	if err != nil || !operationVisible(d, r, op) {
 		return NotFound
 	}
 
@@ -486,6 +572,10 @@ func operationsAPIGet(d *Daemon, r *http.Request) Response {
 	operationsLock.Unlock()
 
 	for _, v := range ops {
+		if !operationVisible(d, r, v) {
+			continue
+		}
+
 		status := strings.ToLower(v.status.String())
 		_, ok := md[status]
 		if !ok {
@@ -501,7 +591,7 @@ func operationsAPIGet(d *Daemon, r *http.Request) Response {
 			continue
 		}
 
-		_, body, err := v.Render()
+		body, err := operationRender(d, r, v)
 		if err != nil {
 			continue
 		}
@@ -522,7 +612,7 @@ func operationAPIWaitGet(d *Daemon, r *http.Request) Response {
 
 	id := mux.Vars(r)["id"]
 	op, err := operationGet(id)
-	if err != nil {
+	if err != nil || !operationVisible(d, r, op) {
 		return NotFound
 	}
 
@@ -531,7 +621,7 @@ func operationAPIWaitGet(d *Daemon, r *http.Request) Response {
 		return InternalError(err)
 	}
 
-	_, body, err := op.Render()
+	body, err := operationRender(d, r, op)
 	if err != nil {
 		return SmartError(err)
 	}
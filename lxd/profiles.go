@@ -28,11 +28,17 @@ func profilesGet(d *Daemon, r *http.Request) Response {
 	}
 
 	recursion := util.IsRecursionRequest(r)
+	cert := certificateFromRequest(d, r)
 
 	resultString := make([]string, len(results))
 	resultMap := make([]*api.Profile, len(results))
 	i := 0
 	for _, name := range results {
+		// Restricted certificates only see their profiles
+		if !certificateAllowsRead(cert, "profiles", name) {
+			continue
+		}
+
 		if !recursion {
 			url := fmt.Sprintf("/%s/profiles/%s", version.APIVersion, name)
 			resultString[i] = url
@@ -48,10 +54,10 @@ func profilesGet(d *Daemon, r *http.Request) Response {
 	}
 
 	if !recursion {
-		return SyncResponse(true, resultString)
+		return SyncResponse(true, resultString[:i])
 	}
 
-	return SyncResponse(true, resultMap)
+	return SyncResponse(true, resultMap[:i])
 }
 
 func profilesPost(d *Daemon, r *http.Request) Response {
@@ -95,6 +101,8 @@ func profilesPost(d *Daemon, r *http.Request) Response {
 			fmt.Errorf("Error inserting %s into database: %s", req.Name, err))
 	}
 
+	eventSendLifecycle("profile-created", fmt.Sprintf("/%s/profiles/%s", version.APIVersion, req.Name), nil, r)
+
 	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/profiles/%s", version.APIVersion, req.Name))
 }
 
@@ -152,7 +160,7 @@ func profilePut(d *Daemon, r *http.Request) Response {
 		return BadRequest(err)
 	}
 
-	return doProfileUpdate(d, name, id, profile, req)
+	return doProfileUpdate(d, r, name, id, profile, req)
 }
 
 // The handler for the post operation.
@@ -188,6 +196,9 @@ func profilePost(d *Daemon, r *http.Request) Response {
 		return SmartError(err)
 	}
 
+	eventSendLifecycle("profile-renamed", fmt.Sprintf("/%s/profiles/%s", version.APIVersion, name),
+		map[string]interface{}{"new_name": req.Name}, r)
+
 	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/profiles/%s", version.APIVersion, req.Name))
 }
 
@@ -210,6 +221,8 @@ func profileDelete(d *Daemon, r *http.Request) Response {
 		return SmartError(err)
 	}
 
+	eventSendLifecycle("profile-deleted", fmt.Sprintf("/%s/profiles/%s", version.APIVersion, name), nil, r)
+
 	return EmptySyncResponse
 }
 
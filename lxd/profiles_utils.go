@@ -2,13 +2,15 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
 )
 
-func doProfileUpdate(d *Daemon, name string, id int64, profile *api.Profile, req api.ProfilePut) Response {
+func doProfileUpdate(d *Daemon, r *http.Request, name string, id int64, profile *api.Profile, req api.ProfilePut) Response {
 	// Sanity checks
 	err := containerValidConfig(d.os, req.Config, true, false)
 	if err != nil {
@@ -43,6 +45,8 @@ func doProfileUpdate(d *Daemon, name string, id int64, profile *api.Profile, req
 			return SmartError(err)
 		}
 
+		eventSendLifecycle("profile-updated", fmt.Sprintf("/%s/profiles/%s", version.APIVersion, name), nil, r)
+
 		return EmptySyncResponse
 	}
 
@@ -69,6 +73,8 @@ func doProfileUpdate(d *Daemon, name string, id int64, profile *api.Profile, req
 		return SmartError(err)
 	}
 
+	eventSendLifecycle("profile-updated", fmt.Sprintf("/%s/profiles/%s", version.APIVersion, name), nil, r)
+
 	// Update all the containers using the profile. Must be done after db.TxCommit due to DB lock.
 	failures := map[string]error{}
 	for _, c := range containers {
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// API endpoints
+func api10ResourcesGet(d *Daemon, r *http.Request) Response {
+	res := api.Resources{}
+
+	cpu, err := resourcesGetCPU()
+	if err != nil {
+		return SmartError(err)
+	}
+	res.CPU = *cpu
+
+	memory, err := resourcesGetMemory()
+	if err != nil {
+		return SmartError(err)
+	}
+	res.Memory = *memory
+
+	pool, err := d.Storage.StoragePoolResources()
+	if err != nil {
+		return SmartError(err)
+	}
+	res.StoragePool = *pool
+
+	return SyncResponse(true, res)
+}
+
+var api10ResourcesCmd = Command{name: "resources", get: api10ResourcesGet}
+
+func storagePoolResourcesGet(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	err := storagePoolCheckExists(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	st, err := storageForPool(d.State(), d.Storage, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	res, err := st.StoragePoolResources()
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return SyncResponse(true, res)
+}
+
+var storagePoolResourcesCmd = Command{name: "storage-pools/{name}/resources", get: storagePoolResourcesGet}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const resourcesTestCPUInfo = `processor	: 0
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) CPU E5-2620 v3 @ 2.40GHz
+cpu MHz		: 1200.000
+physical id	: 0
+core id		: 0
+
+processor	: 1
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) CPU E5-2620 v3 @ 2.40GHz
+cpu MHz		: 1300.000
+physical id	: 0
+core id		: 0
+
+processor	: 2
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) CPU E5-2620 v3 @ 2.40GHz
+cpu MHz		: 1200.000
+physical id	: 0
+core id		: 1
+
+processor	: 3
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) CPU E5-2620 v3 @ 2.40GHz
+cpu MHz		: 1200.000
+physical id	: 1
+core id		: 0
+`
+
+func TestResourcesParseCPUInfo(t *testing.T) {
+	cpu, err := resourcesParseCPUInfo(strings.NewReader(resourcesTestCPUInfo))
+	assert.NoError(t, err)
+
+	assert.Equal(t, uint64(4), cpu.Total)
+	assert.Len(t, cpu.Sockets, 2)
+	assert.Equal(t, uint64(2), cpu.Sockets[0].Cores)
+	assert.Equal(t, uint64(3), cpu.Sockets[0].Threads)
+	assert.Equal(t, uint64(1200), cpu.Sockets[0].Frequency)
+	assert.Equal(t, "GenuineIntel", cpu.Sockets[0].Vendor)
+	assert.Equal(t, uint64(1), cpu.Sockets[1].Cores)
+	assert.Equal(t, uint64(1), cpu.Sockets[1].Threads)
+}
+
+func TestResourcesParseMemInfo(t *testing.T) {
+	memory, err := resourcesParseMemInfo(strings.NewReader(`MemTotal:        8000000 kB
+MemFree:         1000000 kB
+MemAvailable:    6000000 kB
+Buffers:          500000 kB
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(8000000*1024), memory.Total)
+	assert.Equal(t, uint64(2000000*1024), memory.Used)
+
+	// Without MemAvailable, free memory includes buffers and cache
+	memory, err = resourcesParseMemInfo(strings.NewReader(`MemTotal:        8000000 kB
+MemFree:         1000000 kB
+Buffers:          500000 kB
+Cached:          1500000 kB
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5000000*1024), memory.Used)
+
+	_, err = resourcesParseMemInfo(strings.NewReader(""))
+	assert.Error(t, err)
+}
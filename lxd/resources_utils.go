@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// resourcesCPUFrequency returns the maximum frequency in MHz of the given
+// CPU thread as reported by cpufreq, or 0 if unavailable.
+func resourcesCPUFrequency(thread uint64) uint64 {
+	path := fmt.Sprintf("/sys/devices/system/cpu/cpu%d/cpufreq/cpuinfo_max_freq", thread)
+	if !shared.PathExists(path) {
+		return 0
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	freq, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	// cpufreq reports kHz
+	return freq / 1000
+}
+
+// resourcesParseCPUInfo builds the list of CPU sockets from the content of
+// /proc/cpuinfo, grouping the threads by physical id.
+func resourcesParseCPUInfo(r io.Reader) (*api.ResourcesCPU, error) {
+	type cpuThread struct {
+		id        uint64
+		socket    int
+		core      string
+		vendor    string
+		name      string
+		frequency uint64
+	}
+
+	threads := []*cpuThread{}
+	var thread *cpuThread
+
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		fields := strings.SplitN(scan.Text(), ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(fields[0])
+		value := strings.TrimSpace(fields[1])
+
+		// Each thread record starts with its processor id
+		if key == "processor" {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid processor id: %s", value)
+			}
+
+			thread = &cpuThread{id: id, core: value}
+			threads = append(threads, thread)
+			continue
+		}
+
+		if thread == nil {
+			continue
+		}
+
+		switch key {
+		case "physical id":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid physical id: %s", value)
+			}
+			thread.socket = id
+		case "core id":
+			thread.core = value
+		case "vendor_id":
+			thread.vendor = value
+		case "model name":
+			thread.name = value
+		case "cpu MHz":
+			mhz, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				thread.frequency = uint64(mhz)
+			}
+		}
+	}
+
+	err := scan.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	sockets := map[int]*api.ResourcesCPUSocket{}
+	cores := map[int]map[string]bool{}
+	for _, thread := range threads {
+		socket, ok := sockets[thread.socket]
+		if !ok {
+			socket = &api.ResourcesCPUSocket{
+				Vendor:    thread.vendor,
+				Name:      thread.name,
+				Frequency: thread.frequency,
+			}
+			sockets[thread.socket] = socket
+			cores[thread.socket] = map[string]bool{}
+		}
+
+		socket.Threads++
+		cores[thread.socket][thread.core] = true
+
+		freq := resourcesCPUFrequency(thread.id)
+		if freq > socket.FrequencyTurbo {
+			socket.FrequencyTurbo = freq
+		}
+	}
+
+	ids := []int{}
+	for id := range sockets {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	cpu := api.ResourcesCPU{Sockets: []api.ResourcesCPUSocket{}, Total: uint64(len(threads))}
+	for _, id := range ids {
+		socket := sockets[id]
+		socket.Cores = uint64(len(cores[id]))
+		cpu.Sockets = append(cpu.Sockets, *socket)
+	}
+
+	return &cpu, nil
+}
+
+// resourcesParseMemInfo computes the total and used memory from the content
+// of /proc/meminfo.
+func resourcesParseMemInfo(r io.Reader) (*api.ResourcesMemory, error) {
+	values := map[string]uint64{}
+
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		// Values are reported in kB
+		if len(fields) == 3 && fields[2] == "kB" {
+			value *= 1024
+		}
+
+		values[strings.TrimSuffix(fields[0], ":")] = value
+	}
+
+	err := scan.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return nil, fmt.Errorf("Couldn't find MemTotal")
+	}
+
+	// Older kernels don't report MemAvailable
+	available, ok := values["MemAvailable"]
+	if !ok {
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+
+	if available > total {
+		available = total
+	}
+
+	return &api.ResourcesMemory{Total: total, Used: total - available}, nil
+}
+
+func resourcesGetCPU() (*api.ResourcesCPU, error) {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return resourcesParseCPUInfo(f)
+}
+
+func resourcesGetMemory() (*api.ResourcesMemory, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return resourcesParseMemInfo(f)
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/osarch"
 )
 
-const DEFAULT_SECCOMP_POLICY = `2
-blacklist
-reject_force_umount  # comment this to allow umount -f;  not recommended
+const SECCOMP_HEADER = `2
+`
+
+const DEFAULT_SECCOMP_POLICY = `reject_force_umount  # comment this to allow umount -f;  not recommended
 [all]
 kexec_load errno 1
 open_by_handle_at errno 1
@@ -19,15 +23,115 @@ finit_module errno 1
 delete_module errno 1
 `
 
+// Blocks the x32 and 32-bit compat entry points on 64-bit x86, which are a
+// frequent source of kernel bugs.
+const COMPAT_BLOCKING_POLICY = `[%s]
+compat_sys_rt_sigaction errno 38
+stub_x32_rt_sigreturn errno 38
+compat_sys_ioctl errno 38
+compat_sys_readv errno 38
+compat_sys_writev errno 38
+compat_sys_recvfrom errno 38
+compat_sys_sendmsg errno 38
+compat_sys_recvmsg errno 38
+stub_x32_execve errno 38
+compat_sys_ptrace errno 38
+compat_sys_rt_sigpending errno 38
+compat_sys_rt_sigtimedwait errno 38
+compat_sys_rt_sigqueueinfo errno 38
+compat_sys_sigaltstack errno 38
+compat_sys_timer_create errno 38
+compat_sys_mq_notify errno 38
+compat_sys_kexec_load errno 38
+compat_sys_waitid errno 38
+compat_sys_set_robust_list errno 38
+compat_sys_get_robust_list errno 38
+compat_sys_vmsplice errno 38
+compat_sys_move_pages errno 38
+compat_sys_preadv64 errno 38
+compat_sys_pwritev64 errno 38
+compat_sys_rt_tgsigqueueinfo errno 38
+compat_sys_recvmmsg errno 38
+compat_sys_sendmmsg errno 38
+compat_sys_process_vm_readv errno 38
+compat_sys_process_vm_writev errno 38
+compat_sys_setsockopt errno 38
+compat_sys_getsockopt errno 38
+compat_sys_io_setup errno 38
+compat_sys_io_submit errno 38
+stub_x32_execveat errno 38
+`
+
 var seccompPath = shared.VarPath("security", "seccomp")
 
 func SeccompProfilePath(c container) string {
 	return path.Join(seccompPath, c.Name())
 }
 
-func getSeccompProfileContent(c container) string {
-	/* for now there are no seccomp knobs. */
-	return DEFAULT_SECCOMP_POLICY
+// seccompSyscalls turns a newline separated list of syscalls into policy
+// entries, using the given action for the entries which don't have one.
+func seccompSyscalls(list string, action string) string {
+	policy := ""
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if len(strings.Fields(line)) == 1 && action != "" {
+			line = fmt.Sprintf("%s %s", line, action)
+		}
+
+		policy += line + "\n"
+	}
+
+	return policy
+}
+
+// seccompProfileFromConfig generates the seccomp policy for a container with
+// the given expanded configuration and architecture.
+func seccompProfileFromConfig(config map[string]string, architecture string) (string, error) {
+	raw := config["raw.seccomp"]
+	if raw != "" {
+		return raw, nil
+	}
+
+	policy := SECCOMP_HEADER
+
+	whitelist := config["security.syscalls.whitelist"]
+	if whitelist != "" {
+		policy += "whitelist\n[all]\n"
+		policy += seccompSyscalls(whitelist, "")
+		return policy, nil
+	}
+
+	policy += "blacklist\n"
+	policy += DEFAULT_SECCOMP_POLICY
+
+	if shared.IsTrue(config["security.syscalls.blacklist_compat"]) {
+		if architecture != "x86_64" {
+			return "", fmt.Errorf("security.syscalls.blacklist_compat isn't supported on %s", architecture)
+		}
+
+		policy += fmt.Sprintf(COMPAT_BLOCKING_POLICY, architecture)
+	}
+
+	blacklist := config["security.syscalls.blacklist"]
+	if blacklist != "" {
+		policy += "[all]\n"
+		policy += seccompSyscalls(blacklist, "errno 1")
+	}
+
+	return policy, nil
+}
+
+func getSeccompProfileContent(c container) (string, error) {
+	architecture, err := osarch.ArchitectureName(c.Architecture())
+	if err != nil {
+		return "", err
+	}
+
+	return seccompProfileFromConfig(c.ExpandedConfig(), architecture)
 }
 
 func SeccompCreateProfile(c container) error {
@@ -37,7 +141,11 @@ func SeccompCreateProfile(c container) error {
 	 * the mtime on the file for any compiler purpose, so let's just write
 	 * out the profile.
 	 */
-	profile := getSeccompProfileContent(c)
+	profile, err := getSeccompProfileContent(c)
+	if err != nil {
+		return err
+	}
+
 	if err := os.MkdirAll(seccompPath, 0700); err != nil {
 		return err
 	}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeccompProfileFromConfig(t *testing.T) {
+	policy, err := seccompProfileFromConfig(map[string]string{}, "x86_64")
+	assert.NoError(t, err)
+	assert.Equal(t, SECCOMP_HEADER+"blacklist\n"+DEFAULT_SECCOMP_POLICY, policy)
+
+	policy, err = seccompProfileFromConfig(map[string]string{"security.syscalls.blacklist": "ptrace\nkeyctl kill\n"}, "x86_64")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(policy, "[all]\nptrace errno 1\nkeyctl kill\n"))
+
+	policy, err = seccompProfileFromConfig(map[string]string{"security.syscalls.blacklist_compat": "true"}, "x86_64")
+	assert.NoError(t, err)
+	assert.Contains(t, policy, "[x86_64]\ncompat_sys_rt_sigaction errno 38\n")
+
+	_, err = seccompProfileFromConfig(map[string]string{"security.syscalls.blacklist_compat": "true"}, "aarch64")
+	assert.Error(t, err)
+
+	policy, err = seccompProfileFromConfig(map[string]string{"security.syscalls.whitelist": "read\nwrite"}, "x86_64")
+	assert.NoError(t, err)
+	assert.Equal(t, SECCOMP_HEADER+"whitelist\n[all]\nread\nwrite\n", policy)
+
+	policy, err = seccompProfileFromConfig(map[string]string{"raw.seccomp": "2\nwhitelist\n", "security.syscalls.blacklist": "ptrace"}, "x86_64")
+	assert.NoError(t, err)
+	assert.Equal(t, "2\nwhitelist\n", policy)
+}
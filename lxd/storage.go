@@ -7,7 +7,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -90,10 +92,32 @@ func storageTypeToString(sType storageType) string {
 	return "dir"
 }
 
+func storageStringToType(sName string) (storageType, error) {
+	switch sName {
+	case "btrfs":
+		return storageTypeBtrfs, nil
+	case "zfs":
+		return storageTypeZfs, nil
+	case "lvm":
+		return storageTypeLvm, nil
+	case "dir":
+		return storageTypeDir, nil
+	case "mock":
+		return storageTypeMock, nil
+	}
+
+	return -1, fmt.Errorf("Invalid storage type: %s", sName)
+}
+
 type MigrationStorageSourceDriver interface {
 	/* snapshots for this container, if any */
 	Snapshots() []container
 
+	/* only send the given snapshots, the target having the older ones
+	 * already (refresh of an existing container)
+	 */
+	Refresh(snapshotNames []string)
+
 	/* send any bits of the container/snapshots that are possible while the
 	 * container is still running.
 	 */
@@ -148,6 +172,17 @@ type storage interface {
 	ImageCreate(fingerprint string) error
 	ImageDelete(fingerprint string) error
 
+	// Custom storage volumes, mounted under the path returned by
+	// StoragePoolVolumeMount.
+	StoragePoolVolumeCreate(name string, config map[string]string) error
+	StoragePoolVolumeDelete(name string) error
+	StoragePoolVolumeMount(name string) (string, error)
+	StoragePoolVolumeUmount(name string) error
+	StoragePoolVolumeRename(name string, newName string) error
+
+	// Space and inodes available to the storage pool.
+	StoragePoolResources() (*api.ResourcesStoragePool, error)
+
 	MigrationType() MigrationFSType
 	/* does this storage backend preserve inodes when it is moved across
 	 * LXD hosts?
@@ -171,8 +206,16 @@ type storage interface {
 	// We leave sending containers which are snapshots of other containers
 	// already present on the target instance as an exercise for the
 	// enterprising developer.
-	MigrationSource(container container) (MigrationStorageSourceDriver, error)
+	MigrationSource(container container, containerOnly bool) (MigrationStorageSourceDriver, error)
 	MigrationSink(live bool, container container, objects []*Snapshot, conn *websocket.Conn, srcIdmap *idmap.IdmapSet) error
+
+	// Optimized backups, using the native send/receive format of the
+	// backend. ContainerBackupDump writes the given snapshots (oldest
+	// first) and then the container itself as files in the target
+	// directory, which ContainerBackupLoad reads back into an empty
+	// container, creating the listed snapshots along the way.
+	ContainerBackupDump(container container, snapshots []container, target string) error
+	ContainerBackupLoad(container container, snapshots []db.ContainerArgs, source string) error
 }
 
 func newStorage(d *Daemon, sType storageType) (storage, error) {
@@ -185,7 +228,12 @@ func newStorageWithConfig(s *state.State, st storage, sType storageType, config
 		return st, nil
 	}
 
-	shared := storageShared{s: s}
+	shared := storageShared{s: s, poolName: storagePoolDefaultName}
+	poolName, ok := config["poolName"].(string)
+	if ok && poolName != "" {
+		shared.poolName = poolName
+	}
+
 	var w storage
 
 	switch sType {
@@ -265,14 +313,42 @@ func storageForFilename(s *state.State, storage storage, filename string) (stora
 
 func storageForImage(s *state.State, storage storage, imgInfo *api.Image) (storage, error) {
 	imageFilename := shared.VarPath("images", imgInfo.Fingerprint)
+
+	// The image may have been unpacked into a named storage pool
+	if !s.OS.MockMode {
+		poolName, err := storagePoolForFilename(s, imageFilename)
+		if err != nil {
+			return nil, err
+		}
+
+		if poolName != "" {
+			return storageForPool(s, storage, poolName)
+		}
+	}
+
 	return storageForFilename(s, storage, imageFilename)
 }
 
+// storageForContainer returns the storage backend of the pool the container's
+// root disk lives on, falling back to detecting it from the container's path
+// for containers on the default pool.
+func storageForContainer(s *state.State, storage storage, name string, devices types.Devices) (storage, error) {
+	poolName := storagePoolForDevices(devices)
+	if poolName != "" {
+		return storageForPool(s, storage, poolName)
+	}
+
+	return storageForFilename(s, storage, shared.VarPath("containers", strings.Split(name, "/")[0]))
+}
+
 type storageShared struct {
 	sType        storageType
 	sTypeName    string
 	sTypeVersion string
 
+	// Name of the storage pool served by this backend
+	poolName string
+
 	s *state.State
 
 	storage storage
@@ -300,6 +376,34 @@ func (ss *storageShared) GetStorageTypeVersion() string {
 	return ss.sTypeVersion
 }
 
+// getStoragePoolVolumeMountPoint returns the path at which the given custom
+// storage volume is made available on the host.
+func getStoragePoolVolumeMountPoint(pool string, name string) string {
+	return shared.VarPath("storage-pools", pool, "custom", name)
+}
+
+func (ss *storageShared) getStoragePoolVolumeMountPoint(name string) string {
+	return getStoragePoolVolumeMountPoint(ss.poolName, name)
+}
+
+// getPathResources reports the space and inodes of the filesystem holding
+// the given path.
+func (ss *storageShared) getPathResources(path string) (*api.ResourcesStoragePool, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ResourcesStoragePool{}
+	res.Space.Total = stat.Blocks * uint64(stat.Bsize)
+	res.Space.Used = (stat.Blocks - stat.Bfree) * uint64(stat.Bsize)
+	res.Inodes.Total = stat.Files
+	res.Inodes.Used = stat.Files - stat.Ffree
+
+	return &res, nil
+}
+
 func (ss *storageShared) shiftRootfs(c container) error {
 	dpath := c.Path()
 	rpath := c.RootfsPath()
@@ -544,6 +648,35 @@ func (lw *storageLogWrapper) ImageDelete(fingerprint string) error {
 
 }
 
+func (lw *storageLogWrapper) StoragePoolVolumeCreate(name string, config map[string]string) error {
+	lw.log.Debug("StoragePoolVolumeCreate", log.Ctx{"volume": name, "config": config})
+	return lw.w.StoragePoolVolumeCreate(name, config)
+}
+
+func (lw *storageLogWrapper) StoragePoolVolumeDelete(name string) error {
+	lw.log.Debug("StoragePoolVolumeDelete", log.Ctx{"volume": name})
+	return lw.w.StoragePoolVolumeDelete(name)
+}
+
+func (lw *storageLogWrapper) StoragePoolVolumeMount(name string) (string, error) {
+	lw.log.Debug("StoragePoolVolumeMount", log.Ctx{"volume": name})
+	return lw.w.StoragePoolVolumeMount(name)
+}
+
+func (lw *storageLogWrapper) StoragePoolVolumeUmount(name string) error {
+	lw.log.Debug("StoragePoolVolumeUmount", log.Ctx{"volume": name})
+	return lw.w.StoragePoolVolumeUmount(name)
+}
+
+func (lw *storageLogWrapper) StoragePoolVolumeRename(name string, newName string) error {
+	lw.log.Debug("StoragePoolVolumeRename", log.Ctx{"volume": name, "newName": newName})
+	return lw.w.StoragePoolVolumeRename(name, newName)
+}
+
+func (lw *storageLogWrapper) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	return lw.w.StoragePoolResources()
+}
+
 func (lw *storageLogWrapper) MigrationType() MigrationFSType {
 	return lw.w.MigrationType()
 }
@@ -552,9 +685,19 @@ func (lw *storageLogWrapper) PreservesInodes() bool {
 	return lw.w.PreservesInodes()
 }
 
-func (lw *storageLogWrapper) MigrationSource(container container) (MigrationStorageSourceDriver, error) {
+func (lw *storageLogWrapper) MigrationSource(container container, containerOnly bool) (MigrationStorageSourceDriver, error) {
 	lw.log.Debug("MigrationSource", log.Ctx{"container": container.Name()})
-	return lw.w.MigrationSource(container)
+	return lw.w.MigrationSource(container, containerOnly)
+}
+
+func (lw *storageLogWrapper) ContainerBackupDump(container container, snapshots []container, target string) error {
+	lw.log.Debug("ContainerBackupDump", log.Ctx{"container": container.Name(), "target": target})
+	return lw.w.ContainerBackupDump(container, snapshots, target)
+}
+
+func (lw *storageLogWrapper) ContainerBackupLoad(container container, snapshots []db.ContainerArgs, source string) error {
+	lw.log.Debug("ContainerBackupLoad", log.Ctx{"container": container.Name(), "source": source})
+	return lw.w.ContainerBackupLoad(container, snapshots, source)
 }
 
 func (lw *storageLogWrapper) MigrationSink(live bool, container container, objects []*Snapshot, conn *websocket.Conn, srcIdmap *idmap.IdmapSet) error {
@@ -607,15 +750,24 @@ func ShiftIfNecessary(container container, srcIdmap *idmap.IdmapSet) error {
 type rsyncStorageSourceDriver struct {
 	container container
 	snapshots []container
+	refresh   []string
 }
 
-func (s rsyncStorageSourceDriver) Snapshots() []container {
+func (s *rsyncStorageSourceDriver) Snapshots() []container {
 	return s.snapshots
 }
 
-func (s rsyncStorageSourceDriver) SendWhileRunning(conn *websocket.Conn) error {
+func (s *rsyncStorageSourceDriver) Refresh(snapshotNames []string) {
+	s.refresh = snapshotNames
+}
+
+func (s *rsyncStorageSourceDriver) SendWhileRunning(conn *websocket.Conn) error {
 	ctName, _, _ := containerGetParentAndSnapshotName(s.container.Name())
 	for _, send := range s.snapshots {
+		if !migrationSendSnapshot(send, s.refresh) {
+			continue
+		}
+
 		if err := send.StorageStart(); err != nil {
 			return err
 		}
@@ -630,24 +782,38 @@ func (s rsyncStorageSourceDriver) SendWhileRunning(conn *websocket.Conn) error {
 	return RsyncSend(ctName, shared.AddSlash(s.container.Path()), conn)
 }
 
-func (s rsyncStorageSourceDriver) SendAfterCheckpoint(conn *websocket.Conn) error {
+func (s *rsyncStorageSourceDriver) SendAfterCheckpoint(conn *websocket.Conn) error {
 	ctName, _, _ := containerGetParentAndSnapshotName(s.container.Name())
 
 	/* resync anything that changed between our first send and the checkpoint */
 	return RsyncSend(ctName, shared.AddSlash(s.container.Path()), conn)
 }
 
-func (s rsyncStorageSourceDriver) Cleanup() {
+func (s *rsyncStorageSourceDriver) Cleanup() {
 	/* no-op */
 }
 
-func rsyncMigrationSource(container container) (MigrationStorageSourceDriver, error) {
+func rsyncMigrationSource(container container, containerOnly bool) (MigrationStorageSourceDriver, error) {
+	if containerOnly {
+		return &rsyncStorageSourceDriver{container: container}, nil
+	}
+
 	snapshots, err := container.Snapshots()
 	if err != nil {
 		return nil, err
 	}
 
-	return rsyncStorageSourceDriver{container, snapshots}, nil
+	return &rsyncStorageSourceDriver{container: container, snapshots: snapshots}, nil
+}
+
+// migrationSendSnapshot returns whether a snapshot is to be sent, which is
+// always the case unless the transfer is a refresh of the given snapshots.
+func migrationSendSnapshot(snap container, refresh []string) bool {
+	if refresh == nil {
+		return true
+	}
+
+	return shared.StringInSlice(shared.ExtractSnapshotName(snap.Name()), refresh)
 }
 
 func snapshotProtobufToContainerArgs(containerName string, snap *Snapshot) db.ContainerArgs {
@@ -668,7 +834,7 @@ func snapshotProtobufToContainerArgs(containerName string, snap *Snapshot) db.Co
 	}
 
 	name := containerName + shared.SnapshotDelimiter + snap.GetName()
-	return db.ContainerArgs{
+	args := db.ContainerArgs{
 		Name:         name,
 		Ctype:        db.CTypeSnapshot,
 		Config:       config,
@@ -678,6 +844,14 @@ func snapshotProtobufToContainerArgs(containerName string, snap *Snapshot) db.Co
 		Architecture: int(snap.GetArchitecture()),
 		Stateful:     snap.GetStateful(),
 	}
+
+	// Keep the creation date of the source, used to match the snapshots
+	// when refreshing the container
+	if snap.CreationDate != nil {
+		args.CreationDate = time.Unix(snap.GetCreationDate(), 0).UTC()
+	}
+
+	return args
 }
 
 func rsyncMigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *idmap.IdmapSet) error {
@@ -753,6 +927,12 @@ func rsyncMigrationSink(live bool, container container, snapshots []*Snapshot, c
 func SetupStorageDriver(d *Daemon) error {
 	var err error
 
+	defer func() {
+		if d.Storage != nil {
+			storagePoolSetDefault(d.Storage)
+		}
+	}()
+
 	lvmVgName := daemonConfig["storage.lvm_vg_name"].Get()
 	zfsPoolName := daemonConfig["storage.zfs_pool_name"].Get()
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -15,7 +16,9 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/pborman/uuid"
 
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/logger"
 
@@ -435,6 +438,52 @@ func (s *storageBtrfs) ImageDelete(fingerprint string) error {
 	return nil
 }
 
+func (s *storageBtrfs) StoragePoolVolumeCreate(name string, config map[string]string) error {
+	volumePath := s.getStoragePoolVolumeMountPoint(name)
+	err := os.MkdirAll(filepath.Dir(volumePath), 0711)
+	if err != nil {
+		return err
+	}
+
+	err = s.subvolCreate(volumePath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(volumePath, 0755)
+	if err != nil {
+		s.subvolsDelete(volumePath)
+		return err
+	}
+
+	return nil
+}
+
+func (s *storageBtrfs) StoragePoolVolumeDelete(name string) error {
+	return s.subvolsDelete(s.getStoragePoolVolumeMountPoint(name))
+}
+
+func (s *storageBtrfs) StoragePoolVolumeMount(name string) (string, error) {
+	volumePath := s.getStoragePoolVolumeMountPoint(name)
+	if !shared.PathExists(volumePath) {
+		return "", fmt.Errorf("Storage volume '%s' doesn't exist", name)
+	}
+
+	return volumePath, nil
+}
+
+func (s *storageBtrfs) StoragePoolVolumeUmount(name string) error {
+	return nil
+}
+
+func (s *storageBtrfs) StoragePoolVolumeRename(name string, newName string) error {
+	return os.Rename(s.getStoragePoolVolumeMountPoint(name), s.getStoragePoolVolumeMountPoint(newName))
+}
+
+func (s *storageBtrfs) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	return s.getPathResources(shared.VarPath())
+}
+
 func (s *storageBtrfs) subvolCreate(subvol string) error {
 	parentDestPath := filepath.Dir(subvol)
 	if !shared.PathExists(parentDestPath) {
@@ -754,12 +803,17 @@ type btrfsMigrationSourceDriver struct {
 	btrfs              *storageBtrfs
 	runningSnapName    string
 	stoppedSnapName    string
+	refresh            []string
 }
 
 func (s *btrfsMigrationSourceDriver) Snapshots() []container {
 	return s.snapshots
 }
 
+func (s *btrfsMigrationSourceDriver) Refresh(snapshotNames []string) {
+	s.refresh = snapshotNames
+}
+
 func (s *btrfsMigrationSourceDriver) send(conn *websocket.Conn, btrfsPath string, btrfsParent string) error {
 	args := []string{"send"}
 	if btrfsParent != "" {
@@ -819,6 +873,10 @@ func (s *btrfsMigrationSourceDriver) SendWhileRunning(conn *websocket.Conn) erro
 	}
 
 	for i, snap := range s.snapshots {
+		if !migrationSendSnapshot(snap, s.refresh) {
+			continue
+		}
+
 		prev := ""
 		if i > 0 {
 			prev = s.snapshots[i-1].Path()
@@ -896,18 +954,22 @@ func (s *storageBtrfs) PreservesInodes() bool {
 	}
 }
 
-func (s *storageBtrfs) MigrationSource(c container) (MigrationStorageSourceDriver, error) {
+func (s *storageBtrfs) MigrationSource(c container, containerOnly bool) (MigrationStorageSourceDriver, error) {
 	if runningInUserns {
-		return rsyncMigrationSource(c)
+		return rsyncMigrationSource(c, containerOnly)
 	}
 
 	/* List all the snapshots in order of reverse creation. The idea here
 	 * is that we send the oldest to newest snapshot, hopefully saving on
 	 * xfer costs. Then, after all that, we send the container itself.
 	 */
-	snapshots, err := c.Snapshots()
-	if err != nil {
-		return nil, err
+	snapshots := []container{}
+	if !containerOnly {
+		var err error
+		snapshots, err = c.Snapshots()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	driver := &btrfsMigrationSourceDriver{
@@ -1031,3 +1093,157 @@ func (s *storageBtrfs) MigrationSink(live bool, container container, snapshots [
 
 	return nil
 }
+
+// sendToFile writes the btrfs send stream of a subvolume, relative to the
+// given parent if any, to the target file.
+func (s *storageBtrfs) sendToFile(btrfsPath string, btrfsParent string, target string) error {
+	args := []string{"send"}
+	if btrfsParent != "" {
+		args = append(args, "-p", btrfsParent)
+	}
+	args = append(args, btrfsPath)
+
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("btrfs", args...)
+	cmd.Stdout = f
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		logger.Error("problem with btrfs send", log.Ctx{"output": stderr.String()})
+		return err
+	}
+
+	return nil
+}
+
+// receiveFromFile receives a btrfs send stream from the source file into
+// the given directory.
+func (s *storageBtrfs) receiveFromFile(btrfsPath string, source string) error {
+	output, err := shared.RunCommand("btrfs", "receive", "-e", "-f", source, btrfsPath)
+	if err != nil {
+		logger.Error("problem with btrfs receive", log.Ctx{"output": output})
+		return err
+	}
+
+	return nil
+}
+
+func (s *storageBtrfs) ContainerBackupDump(container container, snapshots []container, target string) error {
+	if runningInUserns {
+		return fmt.Errorf("Optimized backups aren't supported by the btrfs storage backend when running in a user namespace")
+	}
+
+	if len(snapshots) > 0 {
+		err := os.MkdirAll(filepath.Join(target, "snapshots"), 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	prev := ""
+	for _, snap := range snapshots {
+		_, snapName, _ := containerGetParentAndSnapshotName(snap.Name())
+
+		err := s.sendToFile(snap.Path(), prev, filepath.Join(target, "snapshots", fmt.Sprintf("%s.bin", snapName)))
+		if err != nil {
+			return err
+		}
+
+		prev = snap.Path()
+	}
+
+	/* We can't send running fses, so let's snapshot the fs and send
+	 * the snapshot.
+	 */
+	tmpPath := containerPath(fmt.Sprintf("%s/.backup-%s", container.Name(), uuid.NewRandom().String()), true)
+	err := os.MkdirAll(tmpPath, 0700)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	btrfsPath := fmt.Sprintf("%s/.root", tmpPath)
+	err = s.subvolsSnapshot(container.Path(), btrfsPath, true)
+	if err != nil {
+		return err
+	}
+	defer s.subvolsDelete(btrfsPath)
+
+	return s.sendToFile(btrfsPath, prev, filepath.Join(target, "container.bin"))
+}
+
+func (s *storageBtrfs) ContainerBackupLoad(container container, snapshots []db.ContainerArgs, source string) error {
+	if runningInUserns {
+		return fmt.Errorf("Optimized backups aren't supported by the btrfs storage backend when running in a user namespace")
+	}
+
+	cName := container.Name()
+
+	snapshotsPath := shared.VarPath(fmt.Sprintf("snapshots/%s", cName))
+	if !shared.PathExists(snapshotsPath) {
+		err := os.MkdirAll(snapshotsPath, 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, args := range snapshots {
+		sc, err := containerCreateEmptySnapshot(container.StateObject(), container.Storage(), args)
+		if err != nil {
+			return err
+		}
+
+		// Remove the pre-created subvolume, btrfs receive creates it
+		err = s.subvolsDelete(sc.Path())
+		if err != nil {
+			return err
+		}
+
+		_, snapName, _ := containerGetParentAndSnapshotName(args.Name)
+		err = s.receiveFromFile(snapshotsPath, filepath.Join(source, "snapshots", fmt.Sprintf("%s.bin", snapName)))
+		if err != nil {
+			return err
+		}
+	}
+
+	/* The container is received as a read-only ".root" subvolume, turn
+	 * it into a writable one in place of the pre-created container.
+	 */
+	err := s.receiveFromFile(snapshotsPath, filepath.Join(source, "container.bin"))
+	if err != nil {
+		return err
+	}
+
+	cPath := containerPath(fmt.Sprintf("%s/.root", cName), true)
+	err = s.subvolsDelete(container.Path())
+	if err != nil {
+		return err
+	}
+
+	err = s.subvolsSnapshot(cPath, container.Path(), false)
+	if err != nil {
+		return err
+	}
+
+	err = s.subvolsDelete(cPath)
+	if err != nil {
+		return err
+	}
+
+	// Cleanup
+	if ok, _ := shared.PathIsEmpty(snapshotsPath); ok {
+		err := os.Remove(snapshotsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
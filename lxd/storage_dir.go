@@ -8,7 +8,9 @@ import (
 
 	"github.com/gorilla/websocket"
 
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 
 	log "gopkg.in/inconshreveable/log15.v2"
@@ -301,6 +303,41 @@ func (s *storageDir) ImageDelete(fingerprint string) error {
 	return nil
 }
 
+func (s *storageDir) StoragePoolVolumeCreate(name string, config map[string]string) error {
+	volumePath := s.getStoragePoolVolumeMountPoint(name)
+	err := os.MkdirAll(filepath.Dir(volumePath), 0711)
+	if err != nil {
+		return err
+	}
+
+	return os.Mkdir(volumePath, 0755)
+}
+
+func (s *storageDir) StoragePoolVolumeDelete(name string) error {
+	return os.RemoveAll(s.getStoragePoolVolumeMountPoint(name))
+}
+
+func (s *storageDir) StoragePoolVolumeMount(name string) (string, error) {
+	volumePath := s.getStoragePoolVolumeMountPoint(name)
+	if !shared.PathExists(volumePath) {
+		return "", fmt.Errorf("Storage volume '%s' doesn't exist", name)
+	}
+
+	return volumePath, nil
+}
+
+func (s *storageDir) StoragePoolVolumeUmount(name string) error {
+	return nil
+}
+
+func (s *storageDir) StoragePoolVolumeRename(name string, newName string) error {
+	return os.Rename(s.getStoragePoolVolumeMountPoint(name), s.getStoragePoolVolumeMountPoint(newName))
+}
+
+func (s *storageDir) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	return s.getPathResources(shared.VarPath())
+}
+
 func (s *storageDir) MigrationType() MigrationFSType {
 	return MigrationFSType_RSYNC
 }
@@ -309,10 +346,18 @@ func (s *storageDir) PreservesInodes() bool {
 	return false
 }
 
-func (s *storageDir) MigrationSource(container container) (MigrationStorageSourceDriver, error) {
-	return rsyncMigrationSource(container)
+func (s *storageDir) MigrationSource(container container, containerOnly bool) (MigrationStorageSourceDriver, error) {
+	return rsyncMigrationSource(container, containerOnly)
 }
 
 func (s *storageDir) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *idmap.IdmapSet) error {
 	return rsyncMigrationSink(live, container, snapshots, conn, srcIdmap)
 }
+
+func (s *storageDir) ContainerBackupDump(container container, snapshots []container, target string) error {
+	return fmt.Errorf("Optimized backups aren't supported by the %s storage backend", s.sTypeName)
+}
+
+func (s *storageDir) ContainerBackupLoad(container container, snapshots []db.ContainerArgs, source string) error {
+	return fmt.Errorf("Optimized backups aren't supported by the %s storage backend", s.sTypeName)
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/logger"
 
@@ -125,6 +126,25 @@ func doStorageLVMValidateThinPoolName(db *sql.DB, key string, value string) erro
 	return nil
 }
 
+// storageLVMValidatePoolSource checks that the given volume group (and
+// optional thin pool) can be used as the source of a new storage pool.
+func storageLVMValidatePoolSource(vgName string, thinPoolName string) error {
+	err := storageLVMCheckVolumeGroup(vgName)
+	if err != nil {
+		return err
+	}
+
+	if thinPoolName != "" {
+		// A missing thin pool gets created on first use
+		_, err := storageLVMThinpoolExists(vgName, thinPoolName)
+		if err != nil {
+			return fmt.Errorf("Error checking for thin pool '%s' in '%s': %v", thinPoolName, vgName, err)
+		}
+	}
+
+	return nil
+}
+
 func storageLVMValidateVolumeGroupName(d *Daemon, key string, value string) error {
 	users, err := storageLVMGetThinPoolUsers(d.db)
 	if err != nil {
@@ -163,11 +183,20 @@ func containerNameToLVName(containerName string) string {
 }
 
 type storageLvm struct {
-	vgName string
+	vgName       string
+	thinPoolName string
+	fsType       string
+	volumeSize   string
 
 	storageShared
 }
 
+// storageLVMVolumeToLVName returns the name of the LV backing a custom
+// storage volume.
+func storageLVMVolumeToLVName(name string) string {
+	return fmt.Sprintf("custom_%s", containerNameToLVName(name))
+}
+
 func (s *storageLvm) Init(config map[string]interface{}) (storage, error) {
 	s.sType = storageTypeLvm
 	s.sTypeName = storageTypeToString(s.sType)
@@ -207,6 +236,21 @@ func (s *storageLvm) Init(config map[string]interface{}) (storage, error) {
 		s.vgName = config["vgName"].(string)
 	}
 
+	s.thinPoolName = daemonConfig["storage.lvm_thinpool_name"].Get()
+	if config["thinPoolName"] != nil {
+		s.thinPoolName = config["thinPoolName"].(string)
+	}
+
+	s.fsType = daemonConfig["storage.lvm_fstype"].Get()
+	if config["fsType"] != nil {
+		s.fsType = config["fsType"].(string)
+	}
+
+	s.volumeSize = daemonConfig["storage.lvm_volume_size"].Get()
+	if config["volumeSize"] != nil {
+		s.volumeSize = config["volumeSize"].(string)
+	}
+
 	return s, nil
 }
 
@@ -254,7 +298,7 @@ func (s *storageLvm) lvmVersionIsAtLeast(versionString string) (bool, error) {
 
 func (s *storageLvm) ContainerCreate(container container) error {
 	containerName := containerNameToLVName(container.Name())
-	lvpath, err := s.createThinLV(containerName)
+	lvpath, err := s.createThinLV(containerName, s.volumeSize)
 	if err != nil {
 		return err
 	}
@@ -287,10 +331,8 @@ func (s *storageLvm) ContainerCreate(container container) error {
 func (s *storageLvm) ContainerCreateFromImage(
 	container container, imageFingerprint string) error {
 
-	imageLVFilename := shared.VarPath(
-		"images", fmt.Sprintf("%s.lv", imageFingerprint))
-
-	if !shared.PathExists(imageLVFilename) {
+	// Each pool holds its own copy of the image
+	if !s.lvExists(imageFingerprint) {
 		if err := s.ImageCreate(imageFingerprint); err != nil {
 			return err
 		}
@@ -320,7 +362,7 @@ func (s *storageLvm) ContainerCreateFromImage(
 	}
 
 	// Generate a new xfs's UUID
-	fstype := daemonConfig["storage.lvm_fstype"].Get()
+	fstype := s.fsType
 	if fstype == "xfs" {
 		err := xfsGenerateNewUUID(lvpath)
 		if err != nil {
@@ -435,7 +477,7 @@ func (s *storageLvm) ContainerCopy(container container, sourceContainer containe
 func (s *storageLvm) ContainerStart(name string, path string) error {
 	lvName := containerNameToLVName(name)
 	lvpath := fmt.Sprintf("/dev/%s/%s", s.vgName, lvName)
-	fstype := daemonConfig["storage.lvm_fstype"].Get()
+	fstype := s.fsType
 
 	err := tryMount(lvpath, path, fstype, 0, "discard")
 	if err != nil {
@@ -673,7 +715,7 @@ func (s *storageLvm) ContainerSnapshotStart(container container) error {
 	}
 
 	// Generate a new xfs's UUID
-	fstype := daemonConfig["storage.lvm_fstype"].Get()
+	fstype := s.fsType
 	if fstype == "xfs" {
 		err := xfsGenerateNewUUID(lvpath)
 		if err != nil {
@@ -714,16 +756,19 @@ func (s *storageLvm) ContainerSnapshotCreateEmpty(snapshotContainer container) e
 func (s *storageLvm) ImageCreate(fingerprint string) error {
 	finalName := shared.VarPath("images", fingerprint)
 
-	lvpath, err := s.createThinLV(fingerprint)
+	lvpath, err := s.createThinLV(fingerprint, s.volumeSize)
 	if err != nil {
 		s.log.Error("LVMCreateThinLV", log.Ctx{"err": err})
 		return fmt.Errorf("Error Creating LVM LV for new image: %v", err)
 	}
 
-	dst := shared.VarPath("images", fmt.Sprintf("%s.lv", fingerprint))
-	err = os.Symlink(lvpath, dst)
-	if err != nil {
-		return err
+	// Only the default pool links its image volumes from the images directory
+	if s.poolName == storagePoolDefaultName {
+		dst := shared.VarPath("images", fmt.Sprintf("%s.lv", fingerprint))
+		err = os.Symlink(lvpath, dst)
+		if err != nil {
+			return err
+		}
 	}
 
 	tempLVMountPoint, err := ioutil.TempDir(shared.VarPath("images"), "tmp_lv_mnt")
@@ -736,7 +781,7 @@ func (s *storageLvm) ImageCreate(fingerprint string) error {
 		}
 	}()
 
-	fstype := daemonConfig["storage.lvm_fstype"].Get()
+	fstype := s.fsType
 	err = tryMount(lvpath, tempLVMountPoint, fstype, 0, "discard")
 	if err != nil {
 		logger.Infof("Error mounting image LV for unpacking: %v", err)
@@ -767,14 +812,20 @@ func (s *storageLvm) ImageCreate(fingerprint string) error {
 }
 
 func (s *storageLvm) ImageDelete(fingerprint string) error {
-	err := s.removeLV(fingerprint)
-	if err != nil {
-		return err
+	if s.lvExists(fingerprint) {
+		err := s.removeLV(fingerprint)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.poolName != storagePoolDefaultName {
+		return nil
 	}
 
 	lvsymlink := fmt.Sprintf(
 		"%s.lv", shared.VarPath("images", fingerprint))
-	err = os.Remove(lvsymlink)
+	err := os.Remove(lvsymlink)
 	if err != nil {
 		return fmt.Errorf(
 			"Failed to remove symlink to deleted image LV: '%s': %v", lvsymlink, err)
@@ -783,8 +834,164 @@ func (s *storageLvm) ImageDelete(fingerprint string) error {
 	return nil
 }
 
+func (s *storageLvm) StoragePoolVolumeCreate(name string, config map[string]string) error {
+	lvSize := s.volumeSize
+	if config["size"] != "" {
+		lvSize = config["size"]
+	}
+
+	_, err := s.createThinLV(storageLVMVolumeToLVName(name), lvSize)
+	if err != nil {
+		return err
+	}
+
+	volumePath, err := s.StoragePoolVolumeMount(name)
+	if err != nil {
+		s.removeLV(storageLVMVolumeToLVName(name))
+		return err
+	}
+
+	err = os.Chmod(volumePath, 0755)
+	if err != nil {
+		s.StoragePoolVolumeDelete(name)
+		return err
+	}
+
+	return nil
+}
+
+func (s *storageLvm) StoragePoolVolumeDelete(name string) error {
+	err := s.StoragePoolVolumeUmount(name)
+	if err != nil {
+		return err
+	}
+
+	err = s.removeLV(storageLVMVolumeToLVName(name))
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(s.getStoragePoolVolumeMountPoint(name))
+}
+
+func (s *storageLvm) StoragePoolVolumeMount(name string) (string, error) {
+	volumePath := s.getStoragePoolVolumeMountPoint(name)
+	if shared.IsMountPoint(volumePath) {
+		return volumePath, nil
+	}
+
+	err := os.MkdirAll(volumePath, 0711)
+	if err != nil {
+		return "", err
+	}
+
+	lvpath := fmt.Sprintf("/dev/%s/%s", s.vgName, storageLVMVolumeToLVName(name))
+	err = tryMount(lvpath, volumePath, s.fsType, 0, "discard")
+	if err != nil {
+		return "", fmt.Errorf("Error mounting storage volume '%s': %v", name, err)
+	}
+
+	return volumePath, nil
+}
+
+func (s *storageLvm) StoragePoolVolumeUmount(name string) error {
+	volumePath := s.getStoragePoolVolumeMountPoint(name)
+	if !shared.IsMountPoint(volumePath) {
+		return nil
+	}
+
+	return tryUnmount(volumePath, 0)
+}
+
+func (s *storageLvm) StoragePoolVolumeRename(name string, newName string) error {
+	err := s.StoragePoolVolumeUmount(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.renameLV(storageLVMVolumeToLVName(name), storageLVMVolumeToLVName(newName))
+	if err != nil {
+		s.StoragePoolVolumeMount(name)
+		return err
+	}
+
+	err = os.RemoveAll(s.getStoragePoolVolumeMountPoint(name))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.StoragePoolVolumeMount(newName)
+	return err
+}
+
+func (s *storageLvm) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	res := api.ResourcesStoragePool{}
+
+	// Containers live in the thin pool, once it has been created
+	poolExists, err := storageLVMThinpoolExists(s.vgName, s.thinPoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	if poolExists {
+		output, err := shared.RunCommand(
+			"lvs", "--noheadings", "--nosuffix", "--units", "b",
+			"-o", "lv_size,data_percent", fmt.Sprintf("%s/%s", s.vgName, s.thinPoolName))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get LVM thin pool usage: %s", output)
+		}
+
+		values := strings.Fields(output)
+		if len(values) != 2 {
+			return nil, fmt.Errorf("Unexpected LVM output: %s", output)
+		}
+
+		size, err := strconv.ParseUint(values[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		percent, err := strconv.ParseFloat(values[1], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Space.Total = size
+		res.Space.Used = uint64(float64(size) * percent / 100)
+
+		return &res, nil
+	}
+
+	output, err := shared.RunCommand(
+		"vgs", "--noheadings", "--nosuffix", "--units", "b",
+		"-o", "vg_size,vg_free", s.vgName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get LVM volume group usage: %s", output)
+	}
+
+	values := strings.Fields(output)
+	if len(values) != 2 {
+		return nil, fmt.Errorf("Unexpected LVM output: %s", output)
+	}
+
+	size, err := strconv.ParseUint(values[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	free, err := strconv.ParseUint(values[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Space.Total = size
+	res.Space.Used = size - free
+
+	return &res, nil
+}
+
 func (s *storageLvm) createDefaultThinPool() (string, error) {
-	thinPoolName := daemonConfig["storage.lvm_thinpool_name"].Get()
+	thinPoolName := s.thinPoolName
 	isRecent, err := s.lvmVersionIsAtLeast("2.02.99")
 	if err != nil {
 		return "", fmt.Errorf("Error checking LVM version: %v", err)
@@ -844,12 +1051,11 @@ func (s *storageLvm) createDefaultThinPool() (string, error) {
 	return thinPoolName, nil
 }
 
-func (s *storageLvm) createThinLV(lvname string) (string, error) {
+func (s *storageLvm) createThinLV(lvname string, lvSize string) (string, error) {
 	var err error
 
-	vgname := daemonConfig["storage.lvm_vg_name"].Get()
-	poolname := daemonConfig["storage.lvm_thinpool_name"].Get()
-	exists, err := storageLVMThinpoolExists(vgname, poolname)
+	poolname := s.thinPoolName
+	exists, err := storageLVMThinpoolExists(s.vgName, poolname)
 	if err != nil {
 		return "", err
 	}
@@ -860,15 +1066,16 @@ func (s *storageLvm) createThinLV(lvname string) (string, error) {
 			return "", fmt.Errorf("Error creating LVM thin pool: %v", err)
 		}
 
-		err = doStorageLVMValidateThinPoolName(s.s.DB, "", poolname)
-		if err != nil {
-			s.log.Error("Setting thin pool name", log.Ctx{"err": err})
-			return "", fmt.Errorf("Error setting LVM thin pool config: %v", err)
+		// Named storage pools carry their own thin pool name
+		if s.vgName == daemonConfig["storage.lvm_vg_name"].Get() {
+			err = doStorageLVMValidateThinPoolName(s.s.DB, "", poolname)
+			if err != nil {
+				s.log.Error("Setting thin pool name", log.Ctx{"err": err})
+				return "", fmt.Errorf("Error setting LVM thin pool config: %v", err)
+			}
 		}
 	}
 
-	lvSize := daemonConfig["storage.lvm_volume_size"].Get()
-
 	output, err := shared.TryRunCommand(
 		"lvcreate",
 		"--thin",
@@ -882,7 +1089,7 @@ func (s *storageLvm) createThinLV(lvname string) (string, error) {
 
 	lvpath := fmt.Sprintf("/dev/%s/%s", s.vgName, lvname)
 
-	fstype := daemonConfig["storage.lvm_fstype"].Get()
+	fstype := s.fsType
 	switch fstype {
 	case "xfs":
 		output, err = shared.TryRunCommand(
@@ -904,6 +1111,12 @@ func (s *storageLvm) createThinLV(lvname string) (string, error) {
 	return lvpath, nil
 }
 
+// lvExists returns whether the volume group of the pool holds the given LV.
+func (s *storageLvm) lvExists(lvname string) bool {
+	_, err := shared.RunCommand("lvs", "--noheadings", fmt.Sprintf("%s/%s", s.vgName, lvname))
+	return err == nil
+}
+
 func (s *storageLvm) removeLV(lvname string) error {
 	var err error
 	var output string
@@ -976,10 +1189,18 @@ func (s *storageLvm) PreservesInodes() bool {
 	return false
 }
 
-func (s *storageLvm) MigrationSource(container container) (MigrationStorageSourceDriver, error) {
-	return rsyncMigrationSource(container)
+func (s *storageLvm) MigrationSource(container container, containerOnly bool) (MigrationStorageSourceDriver, error) {
+	return rsyncMigrationSource(container, containerOnly)
 }
 
 func (s *storageLvm) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *idmap.IdmapSet) error {
 	return rsyncMigrationSink(live, container, snapshots, conn, srcIdmap)
 }
+
+func (s *storageLvm) ContainerBackupDump(container container, snapshots []container, target string) error {
+	return fmt.Errorf("Optimized backups aren't supported by the %s storage backend", s.sTypeName)
+}
+
+func (s *storageLvm) ContainerBackupLoad(container container, snapshots []db.ContainerArgs, source string) error {
+	return fmt.Errorf("Optimized backups aren't supported by the %s storage backend", s.sTypeName)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+
+	log "gopkg.in/inconshreveable/log15.v2"
+)
+
+// API endpoints
+func storagePoolsGet(d *Daemon, r *http.Request) Response {
+	results, err := db.StoragePools(d.db)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	results = append([]string{storagePoolDefaultName}, results...)
+
+	recursion := util.IsRecursionRequest(r)
+
+	resultString := []string{}
+	resultMap := []*api.StoragePool{}
+	for _, name := range results {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s", version.APIVersion, name))
+		} else {
+			pool, err := doStoragePoolGet(d, name)
+			if err != nil {
+				logger.Error("Failed to get storage pool", log.Ctx{"pool": name, "err": err})
+				continue
+			}
+			resultMap = append(resultMap, pool)
+		}
+	}
+
+	if !recursion {
+		return SyncResponse(true, resultString)
+	}
+
+	return SyncResponse(true, resultMap)
+}
+
+func storagePoolsPost(d *Daemon, r *http.Request) Response {
+	req := api.StoragePoolsPost{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	// Sanity checks
+	err := storagePoolValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Name == storagePoolDefaultName {
+		return Conflict
+	}
+
+	_, pool, _ := db.StoragePoolGet(d.db, req.Name)
+	if pool != nil {
+		return Conflict
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err = storagePoolValidateConfig(req.Driver, req.Config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = storagePoolCheckSource(d, req.Driver, req.Config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Create the database entry
+	_, err = db.StoragePoolCreate(d.db, req.Name, req.Driver, req.Description, req.Config)
+	if err != nil {
+		return SmartError(fmt.Errorf("Error inserting %s into database: %s", req.Name, err))
+	}
+
+	// Make sure the driver can actually use the pool
+	_, err = storageForPool(d.State(), d.Storage, req.Name)
+	if err != nil {
+		db.StoragePoolDelete(d.db, req.Name)
+		storagePoolInvalidate(req.Name)
+		return BadRequest(err)
+	}
+
+	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/storage-pools/%s", version.APIVersion, req.Name))
+}
+
+var storagePoolsCmd = Command{name: "storage-pools", get: storagePoolsGet, post: storagePoolsPost}
+
+func doStoragePoolGet(d *Daemon, name string) (*api.StoragePool, error) {
+	var pool *api.StoragePool
+	if name == storagePoolDefaultName {
+		pool = storagePoolDefault(d)
+	} else {
+		var err error
+		_, pool, err = db.StoragePoolGet(d.db, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	usedBy, err := storagePoolUsedBy(d, name)
+	if err != nil {
+		return nil, err
+	}
+	pool.UsedBy = usedBy
+
+	return pool, nil
+}
+
+func storagePoolGet(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	pool, err := doStoragePoolGet(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return SyncResponse(true, pool)
+}
+
+func storagePoolPut(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	if name == storagePoolDefaultName {
+		return BadRequest(fmt.Errorf("The default storage pool is configured through the storage.* server configuration keys"))
+	}
+
+	id, pool, err := db.StoragePoolGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	req := api.StoragePoolPut{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err = storagePoolValidateConfig(pool.Driver, req.Config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// The backing store can't be switched from under existing containers
+	if !reflect.DeepEqual(pool.Config, req.Config) {
+		usedBy, err := storagePoolUsedBy(d, name)
+		if err != nil {
+			return SmartError(err)
+		}
+
+		if len(usedBy) > 0 {
+			return BadRequest(fmt.Errorf("Storage pool is currently in use"))
+		}
+	}
+
+	err = db.StoragePoolUpdate(d.db, id, req.Description, req.Config)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	storagePoolInvalidate(name)
+
+	return EmptySyncResponse
+}
+
+func storagePoolDelete(d *Daemon, r *http.Request) Response {
+	name := mux.Vars(r)["name"]
+
+	if name == storagePoolDefaultName {
+		return BadRequest(fmt.Errorf("The default storage pool can't be deleted"))
+	}
+
+	_, _, err := db.StoragePoolGet(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	usedBy, err := storagePoolUsedBy(d, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if len(usedBy) > 0 {
+		return BadRequest(fmt.Errorf("Storage pool is currently in use"))
+	}
+
+	err = db.StoragePoolDelete(d.db, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	storagePoolInvalidate(name)
+
+	return EmptySyncResponse
+}
+
+var storagePoolCmd = Command{name: "storage-pools/{name}", get: storagePoolGet, put: storagePoolPut, delete: storagePoolDelete}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/types"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// The name under which the server-wide storage backend, configured through
+// the storage.* daemon configuration keys, is exposed as a storage pool.
+const storagePoolDefaultName = "default"
+
+// Configuration keys supported by each storage pool driver
+var storagePoolConfigKeys = map[string][]string{
+	"btrfs": {},
+	"dir":   {},
+	"lvm":   {"lvm.vg_name", "lvm.thinpool_name", "volume.block.filesystem", "volume.size"},
+	"zfs":   {"zfs.pool_name"},
+}
+
+// Cache of initialized storage backends for the named storage pools
+var storagePoolsLock sync.Mutex
+var storagePools = map[string]storage{}
+
+func storagePoolValidName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No name provided")
+	}
+
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("Storage pool names may not contain slashes")
+	}
+
+	if shared.StringInSlice(name, []string{".", ".."}) {
+		return fmt.Errorf("Invalid storage pool name '%s'", name)
+	}
+
+	return nil
+}
+
+func storagePoolValidateConfig(driver string, config map[string]string) error {
+	validKeys, ok := storagePoolConfigKeys[driver]
+	if !ok {
+		return fmt.Errorf("Invalid storage pool driver: %s", driver)
+	}
+
+	for k, v := range config {
+		if !shared.StringInSlice(k, validKeys) {
+			return fmt.Errorf("Invalid storage pool configuration key for %s: %s", driver, k)
+		}
+
+		switch k {
+		case "volume.block.filesystem":
+			if v != "" && !shared.StringInSlice(v, []string{"ext4", "xfs"}) {
+				return fmt.Errorf("Invalid value: %s (not one of %s)", v, []string{"ext4", "xfs"})
+			}
+		case "volume.size":
+			if v != "" {
+				_, err := shared.ParseByteSizeString(v)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	switch driver {
+	case "lvm":
+		if config["lvm.vg_name"] == "" {
+			return fmt.Errorf("The \"lvm.vg_name\" property is required for LVM storage pools")
+		}
+	case "zfs":
+		if config["zfs.pool_name"] == "" {
+			return fmt.Errorf("The \"zfs.pool_name\" property is required for ZFS storage pools")
+		}
+	}
+
+	return nil
+}
+
+// storagePoolDriverConfig converts the configuration of a storage pool into
+// the configuration map understood by the Init function of its driver.
+func storagePoolDriverConfig(driver string, config map[string]string) map[string]interface{} {
+	driverConfig := map[string]interface{}{}
+
+	switch driver {
+	case "lvm":
+		driverConfig["vgName"] = config["lvm.vg_name"]
+		driverConfig["thinPoolName"] = "LXDPool"
+		if config["lvm.thinpool_name"] != "" {
+			driverConfig["thinPoolName"] = config["lvm.thinpool_name"]
+		}
+
+		driverConfig["fsType"] = "ext4"
+		if config["volume.block.filesystem"] != "" {
+			driverConfig["fsType"] = config["volume.block.filesystem"]
+		}
+
+		driverConfig["volumeSize"] = "10GiB"
+		if config["volume.size"] != "" {
+			driverConfig["volumeSize"] = config["volume.size"]
+		}
+	case "zfs":
+		driverConfig["zfsPool"] = config["zfs.pool_name"]
+	}
+
+	return driverConfig
+}
+
+// storagePoolCheckSource makes sure the backing store referenced by a new
+// storage pool exists and isn't already in use by another storage pool.
+func storagePoolCheckSource(d *Daemon, driver string, config map[string]string) error {
+	sourceKey := map[string]string{"lvm": "lvm.vg_name", "zfs": "zfs.pool_name"}[driver]
+	if sourceKey != "" {
+		pools := []*api.StoragePool{storagePoolDefault(d)}
+
+		names, err := db.StoragePools(d.db)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			_, pool, err := db.StoragePoolGet(d.db, name)
+			if err != nil {
+				return err
+			}
+
+			pools = append(pools, pool)
+		}
+
+		for _, pool := range pools {
+			if pool.Driver == driver && pool.Config[sourceKey] == config[sourceKey] {
+				return fmt.Errorf("The %s source '%s' is already used by storage pool '%s'", driver, config[sourceKey], pool.Name)
+			}
+		}
+	}
+
+	switch driver {
+	case "btrfs":
+		if d.os.BackingFS != "btrfs" {
+			return fmt.Errorf("Btrfs storage pools require %s to be on a btrfs filesystem", shared.VarPath())
+		}
+	case "lvm":
+		return storageLVMValidatePoolSource(config["lvm.vg_name"], config["lvm.thinpool_name"])
+	case "zfs":
+		return storageZFSValidatePoolSource(config["zfs.pool_name"])
+	}
+
+	return nil
+}
+
+// storagePoolDefault renders the server-wide storage backend as a storage
+// pool.
+func storagePoolDefault(d *Daemon) *api.StoragePool {
+	pool := api.StoragePool{
+		Name:   storagePoolDefaultName,
+		Driver: d.Storage.GetStorageTypeName(),
+	}
+	pool.Description = "Default LXD storage pool"
+	pool.Config = map[string]string{}
+
+	switch d.Storage.GetStorageType() {
+	case storageTypeLvm:
+		pool.Config["lvm.vg_name"] = daemonConfig["storage.lvm_vg_name"].Get()
+		pool.Config["lvm.thinpool_name"] = daemonConfig["storage.lvm_thinpool_name"].Get()
+		pool.Config["volume.block.filesystem"] = daemonConfig["storage.lvm_fstype"].Get()
+		pool.Config["volume.size"] = daemonConfig["storage.lvm_volume_size"].Get()
+	case storageTypeZfs:
+		pool.Config["zfs.pool_name"] = daemonConfig["storage.zfs_pool_name"].Get()
+	}
+
+	return &pool
+}
+
+// storagePoolForDevices returns the name of the storage pool referenced by
+// the root disk device, or an empty string for the default pool.
+func storagePoolForDevices(devices types.Devices) string {
+	_, rootDiskDevice, err := containerGetRootDiskDevice(devices)
+	if err != nil {
+		return ""
+	}
+
+	if rootDiskDevice["pool"] == storagePoolDefaultName {
+		return ""
+	}
+
+	return rootDiskDevice["pool"]
+}
+
+// storagePoolForFilename returns the name of the named storage pool holding
+// the ZFS dataset or LVM volume backing the given path, or an empty string
+// if it belongs to the default pool.
+func storagePoolForFilename(s *state.State, filename string) (string, error) {
+	names, err := db.StoragePools(s.DB)
+	if err != nil {
+		return "", err
+	}
+
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	dataset := ""
+	if shared.PathExists(filename + ".zfs") {
+		output, err := shared.RunCommand("zfs", "list", "-H", "-o", "name", filename+".zfs")
+		if err == nil {
+			dataset = strings.TrimSpace(output)
+		}
+	}
+
+	vgName := ""
+	if shared.PathExists(filename + ".lv") {
+		lvPath, err := os.Readlink(filename + ".lv")
+		if err == nil {
+			vgName = filepath.Base(filepath.Dir(lvPath))
+		}
+	}
+
+	if dataset == "" && vgName == "" {
+		return "", nil
+	}
+
+	for _, name := range names {
+		_, pool, err := db.StoragePoolGet(s.DB, name)
+		if err != nil {
+			return "", err
+		}
+
+		switch pool.Driver {
+		case "lvm":
+			if vgName != "" && pool.Config["lvm.vg_name"] == vgName {
+				return name, nil
+			}
+		case "zfs":
+			if dataset != "" && strings.HasPrefix(dataset, pool.Config["zfs.pool_name"]+"/") {
+				return name, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// storageForPool returns the storage backend of the given storage pool. The
+// default pool resolves to the provided backend, if any.
+func storageForPool(s *state.State, st storage, name string) (storage, error) {
+	if name == "" {
+		name = storagePoolDefaultName
+	}
+
+	if st != nil && (s.OS.MockMode || name == storagePoolDefaultName) {
+		return st, nil
+	}
+
+	storagePoolsLock.Lock()
+	defer storagePoolsLock.Unlock()
+
+	if s.OS.MockMode {
+		name = storagePoolDefaultName
+	}
+
+	pool, ok := storagePools[name]
+	if ok {
+		return pool, nil
+	}
+
+	if name == storagePoolDefaultName {
+		return nil, fmt.Errorf("The default storage pool isn't initialized")
+	}
+
+	_, dbPool, err := db.StoragePoolGet(s.DB, name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load storage pool '%s': %v", name, err)
+	}
+
+	sType, err := storageStringToType(dbPool.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	driverConfig := storagePoolDriverConfig(dbPool.Driver, dbPool.Config)
+	driverConfig["poolName"] = name
+
+	pool, err = newStorageWithConfig(s, nil, sType, driverConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to initialize storage pool '%s': %v", name, err)
+	}
+
+	storagePools[name] = pool
+
+	return pool, nil
+}
+
+// storagePoolSetDefault records the backend serving the default storage
+// pool.
+func storagePoolSetDefault(st storage) {
+	storagePoolsLock.Lock()
+	storagePools[storagePoolDefaultName] = st
+	storagePoolsLock.Unlock()
+}
+
+// storagePoolInvalidate drops the cached storage backend of a storage pool
+// whose configuration changed.
+func storagePoolInvalidate(name string) {
+	storagePoolsLock.Lock()
+	delete(storagePools, name)
+	storagePoolsLock.Unlock()
+}
+
+// containersExpandedDevices returns the expanded devices of all the
+// containers, indexed by container name.
+func containersExpandedDevices(s *state.State) (map[string]types.Devices, error) {
+	cts, err := db.ContainersList(s.DB, db.CTypeRegular)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := map[string]types.Devices{}
+	for _, ct := range cts {
+		args, err := db.ContainerGet(s.DB, ct)
+		if err != nil {
+			return nil, err
+		}
+
+		c := containerLXC{
+			state:        s,
+			name:         args.Name,
+			profiles:     args.Profiles,
+			localConfig:  args.Config,
+			localDevices: args.Devices,
+		}
+
+		err = c.expandDevices()
+		if err != nil {
+			return nil, err
+		}
+
+		devices[ct] = c.expandedDevices
+	}
+
+	return devices, nil
+}
+
+// storagePoolUsedBy returns the URLs of the containers and profiles whose
+// root disk lives on the given storage pool, as well as those of the custom
+// storage volumes it holds.
+func storagePoolUsedBy(d *Daemon, name string) ([]string, error) {
+	usedBy := []string{}
+
+	isPool := func(devices types.Devices) bool {
+		_, rootDiskDevice, err := containerGetRootDiskDevice(devices)
+		if err != nil {
+			return false
+		}
+
+		if rootDiskDevice["pool"] == "" {
+			return name == storagePoolDefaultName
+		}
+
+		return rootDiskDevice["pool"] == name
+	}
+
+	cts, err := containersExpandedDevices(d.State())
+	if err != nil {
+		return nil, err
+	}
+
+	for ct, devices := range cts {
+		if isPool(devices) {
+			usedBy = append(usedBy, fmt.Sprintf("/%s/containers/%s", version.APIVersion, ct))
+		}
+	}
+
+	profiles, err := db.Profiles(d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profileName := range profiles {
+		_, profile, err := db.ProfileGet(d.db, profileName)
+		if err != nil {
+			return nil, err
+		}
+
+		_, rootDiskDevice, err := containerGetRootDiskDevice(profile.Devices)
+		if err != nil {
+			continue
+		}
+
+		if isPool(types.Devices{"root": rootDiskDevice}) {
+			usedBy = append(usedBy, fmt.Sprintf("/%s/profiles/%s", version.APIVersion, profileName))
+		}
+	}
+
+	volumes, err := db.StoragePoolVolumes(d.db, name, db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, volume := range volumes {
+		usedBy = append(usedBy, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s", version.APIVersion, name, storagePoolVolumeTypeNameCustom, volume))
+	}
+
+	sort.Strings(usedBy)
+
+	return usedBy, nil
+}
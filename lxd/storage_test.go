@@ -5,6 +5,8 @@ import (
 
 	"github.com/gorilla/websocket"
 
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 
 	log "gopkg.in/inconshreveable/log15.v2"
@@ -129,6 +131,30 @@ func (s *storageMock) ImageDelete(fingerprint string) error {
 	return nil
 }
 
+func (s *storageMock) StoragePoolVolumeCreate(name string, config map[string]string) error {
+	return nil
+}
+
+func (s *storageMock) StoragePoolVolumeDelete(name string) error {
+	return nil
+}
+
+func (s *storageMock) StoragePoolVolumeMount(name string) (string, error) {
+	return s.getStoragePoolVolumeMountPoint(name), nil
+}
+
+func (s *storageMock) StoragePoolVolumeUmount(name string) error {
+	return nil
+}
+
+func (s *storageMock) StoragePoolVolumeRename(name string, newName string) error {
+	return nil
+}
+
+func (s *storageMock) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	return &api.ResourcesStoragePool{}, nil
+}
+
 func (s *storageMock) MigrationType() MigrationFSType {
 	return MigrationFSType_RSYNC
 }
@@ -137,9 +163,17 @@ func (s *storageMock) PreservesInodes() bool {
 	return false
 }
 
-func (s *storageMock) MigrationSource(container container) (MigrationStorageSourceDriver, error) {
+func (s *storageMock) MigrationSource(container container, containerOnly bool) (MigrationStorageSourceDriver, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 func (s *storageMock) MigrationSink(live bool, container container, snapshots []*Snapshot, conn *websocket.Conn, srcIdmap *idmap.IdmapSet) error {
 	return nil
 }
+
+func (s *storageMock) ContainerBackupDump(container container, snapshots []container, target string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (s *storageMock) ContainerBackupLoad(container container, snapshots []db.ContainerArgs, source string) error {
+	return fmt.Errorf("not implemented")
+}
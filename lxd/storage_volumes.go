@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+
+	log "gopkg.in/inconshreveable/log15.v2"
+)
+
+// storagePoolCheckExists makes sure the given storage pool is defined.
+func storagePoolCheckExists(d *Daemon, pool string) error {
+	if pool == storagePoolDefaultName {
+		return nil
+	}
+
+	_, _, err := db.StoragePoolGet(d.db, pool)
+	return err
+}
+
+// API endpoints
+func storagePoolVolumesGet(d *Daemon, r *http.Request) Response {
+	pool := mux.Vars(r)["name"]
+
+	// Only custom volumes are managed through the API
+	volumeTypeName := mux.Vars(r)["type"]
+	if volumeTypeName == "" {
+		volumeTypeName = storagePoolVolumeTypeNameCustom
+	}
+
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = storagePoolCheckExists(d, pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	volumes, err := db.StoragePoolVolumes(d.db, pool, volumeType)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	recursion := util.IsRecursionRequest(r)
+
+	resultString := []string{}
+	resultMap := []*api.StorageVolume{}
+	for _, name := range volumes {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s", version.APIVersion, pool, volumeTypeName, name))
+		} else {
+			volume, err := doStoragePoolVolumeGet(d, pool, name, volumeTypeName)
+			if err != nil {
+				logger.Error("Failed to get storage volume", log.Ctx{"pool": pool, "volume": name, "err": err})
+				continue
+			}
+			resultMap = append(resultMap, volume)
+		}
+	}
+
+	if !recursion {
+		return SyncResponse(true, resultString)
+	}
+
+	return SyncResponse(true, resultMap)
+}
+
+func storagePoolVolumesPost(d *Daemon, r *http.Request) Response {
+	pool := mux.Vars(r)["name"]
+
+	req := api.StorageVolumesPost{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	// The type may come from the URL or from the request
+	volumeTypeName := mux.Vars(r)["type"]
+	if volumeTypeName == "" {
+		volumeTypeName = req.Type
+	}
+
+	if volumeTypeName == "" {
+		volumeTypeName = storagePoolVolumeTypeNameCustom
+	}
+
+	if req.Type != "" && req.Type != volumeTypeName {
+		return BadRequest(fmt.Errorf("Storage volume type mismatch: %s != %s", req.Type, volumeTypeName))
+	}
+
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// Sanity checks
+	err = storagePoolVolumeValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	err = storagePoolCheckExists(d, pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	driver, err := storagePoolDriver(d, pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	for k := range req.Config {
+		if strings.HasPrefix(k, "volatile.") {
+			return BadRequest(fmt.Errorf("Volatile keys can't be set on new storage volumes"))
+		}
+	}
+
+	err = storagePoolVolumeValidateConfig(driver, req.Config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	_, volume, _ := db.StoragePoolVolumeGet(d.db, pool, req.Name, volumeType)
+	if volume != nil {
+		return Conflict
+	}
+
+	st, err := storageForPool(d.State(), d.Storage, pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	// Create the database entry
+	volumeID, err := db.StoragePoolVolumeCreate(d.db, pool, req.Name, volumeType, req.Description, req.Config)
+	if err != nil {
+		return SmartError(fmt.Errorf("Error inserting %s into database: %s", req.Name, err))
+	}
+
+	// Create the volume itself
+	err = st.StoragePoolVolumeCreate(req.Name, req.Config)
+	if err != nil {
+		db.StoragePoolVolumeDelete(d.db, volumeID)
+		return SmartError(err)
+	}
+
+	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s", version.APIVersion, pool, volumeTypeName, req.Name))
+}
+
+var storagePoolVolumesCmd = Command{name: "storage-pools/{name}/volumes", get: storagePoolVolumesGet, post: storagePoolVolumesPost}
+var storagePoolVolumesTypeCmd = Command{name: "storage-pools/{name}/volumes/{type}", get: storagePoolVolumesGet, post: storagePoolVolumesPost}
+
+func doStoragePoolVolumeGet(d *Daemon, pool string, name string, volumeTypeName string) (*api.StorageVolume, error) {
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, volume, err := db.StoragePoolVolumeGet(d.db, pool, name, volumeType)
+	if err != nil {
+		return nil, err
+	}
+	volume.Type = volumeTypeName
+
+	usedBy, err := storagePoolVolumeUsedBy(d, pool, name)
+	if err != nil {
+		return nil, err
+	}
+	volume.UsedBy = usedBy
+
+	return volume, nil
+}
+
+func storagePoolVolumeTypeGet(d *Daemon, r *http.Request) Response {
+	pool := mux.Vars(r)["pool"]
+	volumeTypeName := mux.Vars(r)["type"]
+	name := mux.Vars(r)["name"]
+
+	_, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	volume, err := doStoragePoolVolumeGet(d, pool, name, volumeTypeName)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return SyncResponse(true, volume)
+}
+
+func storagePoolVolumeTypePut(d *Daemon, r *http.Request) Response {
+	pool := mux.Vars(r)["pool"]
+	volumeTypeName := mux.Vars(r)["type"]
+	name := mux.Vars(r)["name"]
+
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	volumeID, volume, err := db.StoragePoolVolumeGet(d.db, pool, name, volumeType)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	req := api.StorageVolumePut{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	driver, err := storagePoolDriver(d, pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	err = storagePoolVolumeValidateConfig(driver, req.Config)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	// The volume was sized at creation time and volatile keys are
+	// managed by LXD
+	for k, v := range volume.Config {
+		if strings.HasPrefix(k, "volatile.") {
+			req.Config[k] = v
+		}
+	}
+
+	if req.Config["size"] != volume.Config["size"] {
+		return BadRequest(fmt.Errorf("The size of a storage volume can't be changed"))
+	}
+
+	err = db.StoragePoolVolumeUpdate(d.db, volumeID, req.Description, req.Config)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return EmptySyncResponse
+}
+
+func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request) Response {
+	pool := mux.Vars(r)["pool"]
+	volumeTypeName := mux.Vars(r)["type"]
+	name := mux.Vars(r)["name"]
+
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	volumeID, _, err := db.StoragePoolVolumeGet(d.db, pool, name, volumeType)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	usedBy, err := storagePoolVolumeUsedBy(d, pool, name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	if len(usedBy) > 0 {
+		return BadRequest(fmt.Errorf("Storage volume is currently in use"))
+	}
+
+	st, err := storageForPool(d.State(), d.Storage, pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	err = st.StoragePoolVolumeDelete(name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	err = db.StoragePoolVolumeDelete(d.db, volumeID)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return EmptySyncResponse
+}
+
+func storagePoolVolumeTypePost(d *Daemon, r *http.Request) Response {
+	pool := mux.Vars(r)["pool"]
+	volumeTypeName := mux.Vars(r)["type"]
+	name := mux.Vars(r)["name"]
+
+	volumeType, err := storagePoolVolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	req := api.StorageVolumePost{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return BadRequest(err)
+	}
+
+	// Sanity checks
+	err = storagePoolVolumeValidName(req.Name)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	volumeID, _, err := db.StoragePoolVolumeGet(d.db, pool, name, volumeType)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	_, volume, _ := db.StoragePoolVolumeGet(d.db, pool, req.Name, volumeType)
+	if volume != nil {
+		return Conflict
+	}
+
+	// The volume can't be renamed from under a running container
+	cts, err := containersExpandedDevices(d.State())
+	if err != nil {
+		return SmartError(err)
+	}
+
+	for ct, devices := range cts {
+		for _, device := range devices {
+			if !isStoragePoolVolumeDevice(device, pool, name) {
+				continue
+			}
+
+			c, err := containerLoadByName(d.State(), d.Storage, ct)
+			if err != nil {
+				return SmartError(err)
+			}
+
+			if c.IsRunning() {
+				return BadRequest(fmt.Errorf("Storage volume is in use by running container '%s'", ct))
+			}
+		}
+	}
+
+	st, err := storageForPool(d.State(), d.Storage, pool)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	err = st.StoragePoolVolumeRename(name, req.Name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	err = db.StoragePoolVolumeRename(d.db, volumeID, req.Name)
+	if err != nil {
+		st.StoragePoolVolumeRename(req.Name, name)
+		return SmartError(err)
+	}
+
+	err = storagePoolVolumeUpdateUsers(d, pool, name, req.Name)
+	if err != nil {
+		return SmartError(err)
+	}
+
+	return SyncResponseLocation(true, nil, fmt.Sprintf("/%s/storage-pools/%s/volumes/%s/%s", version.APIVersion, pool, volumeTypeName, req.Name))
+}
+
+var storagePoolVolumeTypeCmd = Command{name: "storage-pools/{pool}/volumes/{type}/{name}", get: storagePoolVolumeTypeGet, put: storagePoolVolumeTypePut, post: storagePoolVolumeTypePost, delete: storagePoolVolumeTypeDelete}
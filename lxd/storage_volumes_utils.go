@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/types"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/idmap"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Only custom storage volumes can be managed through the API
+const storagePoolVolumeTypeNameCustom = "custom"
+
+// Configuration keys supported by custom storage volumes on each driver
+var storagePoolVolumeConfigKeys = map[string][]string{
+	"btrfs": {},
+	"dir":   {},
+	"lvm":   {"size"},
+	"zfs":   {"size"},
+}
+
+func storagePoolVolumeTypeNameToType(volumeTypeName string) (db.StoragePoolVolumeType, error) {
+	switch volumeTypeName {
+	case storagePoolVolumeTypeNameCustom:
+		return db.StoragePoolVolumeTypeCustom, nil
+	}
+
+	return -1, fmt.Errorf("Invalid storage volume type: %s", volumeTypeName)
+}
+
+func storagePoolVolumeValidName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No name provided")
+	}
+
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("Storage volume names may not contain slashes")
+	}
+
+	if shared.StringInSlice(name, []string{".", ".."}) {
+		return fmt.Errorf("Invalid storage volume name '%s'", name)
+	}
+
+	return nil
+}
+
+func storagePoolVolumeValidateConfig(driver string, config map[string]string) error {
+	validKeys, ok := storagePoolVolumeConfigKeys[driver]
+	if !ok {
+		return fmt.Errorf("Invalid storage pool driver: %s", driver)
+	}
+
+	for k, v := range config {
+		if strings.HasPrefix(k, "volatile.") {
+			continue
+		}
+
+		if !shared.StringInSlice(k, validKeys) {
+			return fmt.Errorf("Invalid storage volume configuration key for %s: %s", driver, k)
+		}
+
+		if k == "size" && v != "" {
+			_, err := shared.ParseByteSizeString(v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// storagePoolDriver returns the name of the driver of the given storage pool.
+func storagePoolDriver(d *Daemon, pool string) (string, error) {
+	if pool == storagePoolDefaultName {
+		return d.Storage.GetStorageTypeName(), nil
+	}
+
+	_, dbPool, err := db.StoragePoolGet(d.db, pool)
+	if err != nil {
+		return "", err
+	}
+
+	return dbPool.Driver, nil
+}
+
+// isStoragePoolVolumeDevice returns whether the device attaches the given
+// custom storage volume.
+func isStoragePoolVolumeDevice(device types.Device, pool string, name string) bool {
+	return device["type"] == "disk" && device["path"] != "/" && device["pool"] == pool && device["source"] == name
+}
+
+// storagePoolVolumeUsedBy returns the URLs of the containers and profiles
+// attaching the given custom storage volume.
+func storagePoolVolumeUsedBy(d *Daemon, pool string, name string) ([]string, error) {
+	usedBy := []string{}
+
+	cts, err := containersExpandedDevices(d.State())
+	if err != nil {
+		return nil, err
+	}
+
+	for ct, devices := range cts {
+		for _, device := range devices {
+			if isStoragePoolVolumeDevice(device, pool, name) {
+				usedBy = append(usedBy, fmt.Sprintf("/%s/containers/%s", version.APIVersion, ct))
+				break
+			}
+		}
+	}
+
+	profiles, err := db.Profiles(d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profileName := range profiles {
+		_, profile, err := db.ProfileGet(d.db, profileName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, device := range profile.Devices {
+			if isStoragePoolVolumeDevice(device, pool, name) {
+				usedBy = append(usedBy, fmt.Sprintf("/%s/profiles/%s", version.APIVersion, profileName))
+				break
+			}
+		}
+	}
+
+	return usedBy, nil
+}
+
+// storagePoolVolumeUpdateUsers points the disk devices of the containers and
+// profiles attaching a custom storage volume to its new name.
+func storagePoolVolumeUpdateUsers(d *Daemon, pool string, oldName string, newName string) error {
+	profiles, err := db.Profiles(d.db)
+	if err != nil {
+		return err
+	}
+
+	for _, profileName := range profiles {
+		id, profile, err := db.ProfileGet(d.db, profileName)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for _, device := range profile.Devices {
+			if isStoragePoolVolumeDevice(device, pool, oldName) {
+				device["source"] = newName
+				changed = true
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		tx, err := db.Begin(d.db)
+		if err != nil {
+			return err
+		}
+
+		err = db.ProfileConfigClear(tx, id)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		err = db.ProfileConfigAdd(tx, id, profile.Config)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		err = db.DevicesAdd(tx, "profile", id, types.Devices(profile.Devices))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		err = db.TxCommit(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	cts, err := db.ContainersList(d.db, db.CTypeRegular)
+	if err != nil {
+		return err
+	}
+
+	for _, ct := range cts {
+		c, err := containerLoadByName(d.State(), d.Storage, ct)
+		if err != nil {
+			return err
+		}
+
+		devices := c.LocalDevices()
+		changed := false
+		for _, device := range devices {
+			if isStoragePoolVolumeDevice(device, pool, oldName) {
+				device["source"] = newName
+				changed = true
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		err = c.Update(db.ContainerArgs{
+			Architecture: c.Architecture(),
+			Ephemeral:    c.IsEphemeral(),
+			Config:       c.LocalConfig(),
+			Devices:      devices,
+			Profiles:     c.Profiles()}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storagePoolVolumeAttach mounts a custom storage volume for use by the given
+// container and makes sure its content is shifted to the container's idmap.
+// It returns the path of the volume on the host.
+func storagePoolVolumeAttach(s *state.State, pool string, name string, c container) (string, error) {
+	volumeID, volume, err := db.StoragePoolVolumeGet(s.DB, pool, name, db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return "", fmt.Errorf("Failed to load storage volume '%s' on pool '%s': %v", name, pool, err)
+	}
+
+	st, err := storageForPool(s, nil, pool)
+	if err != nil {
+		return "", err
+	}
+
+	volumePath, err := st.StoragePoolVolumeMount(name)
+	if err != nil {
+		return "", err
+	}
+
+	nextIdmap, err := c.IdmapSet()
+	if err != nil {
+		return "", err
+	}
+
+	nextJSONIdmap, err := storagePoolVolumeIdmapToJSON(nextIdmap)
+	if err != nil {
+		return "", err
+	}
+
+	lastJSONIdmap := volume.Config["volatile.idmap.last"]
+	if lastJSONIdmap == "" {
+		lastJSONIdmap = "[]"
+	}
+
+	if lastJSONIdmap == nextJSONIdmap {
+		return volumePath, nil
+	}
+
+	// Shifting the volume would break any other running container using it
+	cts, err := containersExpandedDevices(s)
+	if err != nil {
+		return "", err
+	}
+
+	for ct, devices := range cts {
+		if ct == c.Name() {
+			continue
+		}
+
+		for _, device := range devices {
+			if !isStoragePoolVolumeDevice(device, pool, name) {
+				continue
+			}
+
+			other, err := containerLoadByName(s, c.Storage(), ct)
+			if err != nil {
+				return "", err
+			}
+
+			if other.IsRunning() {
+				return "", fmt.Errorf("Storage volume '%s' is in use by running container '%s' with a different idmap", name, ct)
+			}
+		}
+	}
+
+	if lastJSONIdmap != "[]" {
+		lastIdmap := new(idmap.IdmapSet)
+		err = json.Unmarshal([]byte(lastJSONIdmap), &lastIdmap.Idmap)
+		if err != nil {
+			return "", err
+		}
+
+		err = lastIdmap.UnshiftRootfs(volumePath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if nextIdmap != nil {
+		err = nextIdmap.ShiftRootfs(volumePath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	volume.Config["volatile.idmap.last"] = nextJSONIdmap
+	err = db.StoragePoolVolumeUpdate(s.DB, volumeID, volume.Description, volume.Config)
+	if err != nil {
+		return "", err
+	}
+
+	return volumePath, nil
+}
+
+func storagePoolVolumeIdmapToJSON(set *idmap.IdmapSet) (string, error) {
+	if set == nil {
+		return "[]", nil
+	}
+
+	idmapBytes, err := json.Marshal(set.Idmap)
+	if err != nil {
+		return "", err
+	}
+
+	return string(idmapBytes), nil
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -12,8 +14,10 @@ import (
 
 	"github.com/gorilla/websocket"
 
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/logger"
 
@@ -140,12 +144,11 @@ func (s *storageZfs) ContainerCreate(container container) error {
 
 func (s *storageZfs) ContainerCreateFromImage(container container, fingerprint string) error {
 	cPath := container.Path()
-	imagePath := shared.VarPath("images", fingerprint)
-	subvol := fmt.Sprintf("%s.zfs", imagePath)
 	fs := fmt.Sprintf("containers/%s", container.Name())
 	fsImage := fmt.Sprintf("images/%s", fingerprint)
 
-	if !shared.PathExists(subvol) {
+	// Each pool holds its own copy of the image
+	if !s.zfsExists(fsImage) {
 		err := s.ImageCreate(fingerprint)
 		if err != nil {
 			return err
@@ -594,9 +597,20 @@ func (s *storageZfs) ContainerSnapshotCreateEmpty(snapshotContainer container) e
 	return nil
 }
 
+// imageMountPoint returns the path the dataset of the given image is mounted
+// on. Only the default pool uses the images directory, named storage pools
+// mount their copy of the image under their own directory.
+func (s *storageZfs) imageMountPoint(fingerprint string) string {
+	if s.poolName == storagePoolDefaultName {
+		return shared.VarPath("images", fmt.Sprintf("%s.zfs", fingerprint))
+	}
+
+	return shared.VarPath("storage-pools", s.poolName, "images", fingerprint)
+}
+
 func (s *storageZfs) ImageCreate(fingerprint string) error {
 	imagePath := shared.VarPath("images", fingerprint)
-	subvol := fmt.Sprintf("%s.zfs", imagePath)
+	subvol := s.imageMountPoint(fingerprint)
 	fs := fmt.Sprintf("images/%s", fingerprint)
 
 	if s.zfsExists(fmt.Sprintf("deleted/%s", fs)) {
@@ -630,6 +644,18 @@ func (s *storageZfs) ImageCreate(fingerprint string) error {
 		return err
 	}
 
+	if s.poolName != storagePoolDefaultName {
+		err = os.MkdirAll(filepath.Dir(subvol), 0700)
+		if err != nil {
+			return cleanup(err)
+		}
+
+		err = s.zfsSet(fs, "mountpoint", subvol)
+		if err != nil {
+			return cleanup(err)
+		}
+	}
+
 	err = unpackImage(imagePath, subvol, s.storage.GetStorageType())
 	if err != nil {
 		return cleanup(err)
@@ -675,8 +701,9 @@ func (s *storageZfs) ImageDelete(fingerprint string) error {
 		}
 	}
 
-	if shared.PathExists(shared.VarPath(fs + ".zfs")) {
-		err := os.Remove(shared.VarPath(fs + ".zfs"))
+	subvol := s.imageMountPoint(fingerprint)
+	if shared.PathExists(subvol) {
+		err := os.Remove(subvol)
 		if err != nil {
 			return err
 		}
@@ -686,6 +713,130 @@ func (s *storageZfs) ImageDelete(fingerprint string) error {
 }
 
 // Helper functions
+func (s *storageZfs) StoragePoolVolumeCreate(name string, config map[string]string) error {
+	volumePath := s.getStoragePoolVolumeMountPoint(name)
+	err := os.MkdirAll(filepath.Dir(volumePath), 0711)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"create", "-p", "-o", fmt.Sprintf("mountpoint=%s", volumePath)}
+	if config["size"] != "" {
+		size, err := shared.ParseByteSizeString(config["size"])
+		if err != nil {
+			return err
+		}
+
+		args = append(args, "-o", fmt.Sprintf("quota=%d", size))
+	}
+	args = append(args, fmt.Sprintf("%s/custom/%s", s.zfsPool, name))
+
+	output, err := shared.RunCommand("zfs", args...)
+	if err != nil {
+		s.log.Error("zfs create failed", log.Ctx{"output": string(output)})
+		return fmt.Errorf("Failed to create ZFS filesystem: %s", output)
+	}
+
+	_, err = s.StoragePoolVolumeMount(name)
+	if err != nil {
+		s.zfsDestroy(fmt.Sprintf("custom/%s", name))
+		return err
+	}
+
+	err = os.Chmod(volumePath, 0755)
+	if err != nil {
+		s.StoragePoolVolumeDelete(name)
+		return err
+	}
+
+	return nil
+}
+
+func (s *storageZfs) StoragePoolVolumeDelete(name string) error {
+	err := s.zfsDestroy(fmt.Sprintf("custom/%s", name))
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(s.getStoragePoolVolumeMountPoint(name))
+}
+
+func (s *storageZfs) StoragePoolVolumeMount(name string) (string, error) {
+	volumePath := s.getStoragePoolVolumeMountPoint(name)
+	if !shared.IsMountPoint(volumePath) {
+		err := s.zfsMount(fmt.Sprintf("custom/%s", name))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return volumePath, nil
+}
+
+func (s *storageZfs) StoragePoolVolumeUmount(name string) error {
+	if !shared.IsMountPoint(s.getStoragePoolVolumeMountPoint(name)) {
+		return nil
+	}
+
+	return s.zfsUnmount(fmt.Sprintf("custom/%s", name))
+}
+
+func (s *storageZfs) StoragePoolVolumeRename(name string, newName string) error {
+	err := s.StoragePoolVolumeUmount(name)
+	if err != nil {
+		return err
+	}
+
+	err = s.zfsRename(fmt.Sprintf("custom/%s", name), fmt.Sprintf("custom/%s", newName))
+	if err != nil {
+		s.StoragePoolVolumeMount(name)
+		return err
+	}
+
+	err = s.zfsSet(fmt.Sprintf("custom/%s", newName), "mountpoint", s.getStoragePoolVolumeMountPoint(newName))
+	if err != nil {
+		return err
+	}
+
+	err = os.RemoveAll(s.getStoragePoolVolumeMountPoint(name))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.StoragePoolVolumeMount(newName)
+	return err
+}
+
+func (s *storageZfs) StoragePoolResources() (*api.ResourcesStoragePool, error) {
+	output, err := shared.RunCommand(
+		"zfs", "get", "-H", "-p", "-o", "value", "used,available", s.zfsPool)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get ZFS pool usage: %s", output)
+	}
+
+	values := strings.Fields(output)
+	if len(values) != 2 {
+		return nil, fmt.Errorf("Unexpected ZFS output: %s", output)
+	}
+
+	used, err := strconv.ParseUint(values[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := strconv.ParseUint(values[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	// ZFS allocates inodes dynamically so only the space is reported
+	res := api.ResourcesStoragePool{}
+	res.Space.Used = used
+	res.Space.Total = used + available
+
+	return &res, nil
+}
+
 func (s *storageZfs) zfsCheckPool(pool string) error {
 	output, err := shared.RunCommand(
 		"zfs", "get", "type", "-H", "-o", "value", pool)
@@ -1217,6 +1368,39 @@ func storageZFSValidatePoolName(d *Daemon, key string, value string) error {
 	return nil
 }
 
+// storageZFSValidatePoolSource checks that the given zpool or dataset can be
+// used as the source of a new storage pool.
+func storageZFSValidatePoolSource(value string) error {
+	s := storageZfs{}
+
+	err := s.initShared()
+	if err != nil {
+		return fmt.Errorf("Unable to initialize the ZFS backend: %v", err)
+	}
+
+	out, err := exec.LookPath("zfs")
+	if err != nil || len(out) == 0 {
+		return fmt.Errorf("The 'zfs' tool isn't available")
+	}
+
+	err = s.zfsCheckPool(value)
+	if err != nil {
+		return fmt.Errorf("Invalid ZFS pool: %v", err)
+	}
+
+	s.zfsPool = value
+	subvols, err := s.zfsListSubvolumes("")
+	if err != nil {
+		return err
+	}
+
+	if len(subvols) > 0 {
+		return fmt.Errorf("Provided ZFS pool (or dataset) isn't empty")
+	}
+
+	return nil
+}
+
 type zfsMigrationSourceDriver struct {
 	container        container
 	snapshots        []container
@@ -1224,12 +1408,17 @@ type zfsMigrationSourceDriver struct {
 	zfs              *storageZfs
 	runningSnapName  string
 	stoppedSnapName  string
+	refresh          []string
 }
 
 func (s *zfsMigrationSourceDriver) Snapshots() []container {
 	return s.snapshots
 }
 
+func (s *zfsMigrationSourceDriver) Refresh(snapshotNames []string) {
+	s.refresh = snapshotNames
+}
+
 func (s *zfsMigrationSourceDriver) send(conn *websocket.Conn, zfsName string, zfsParent string) error {
 	fields := strings.SplitN(s.container.Name(), shared.SnapshotDelimiter, 2)
 	args := []string{"send", fmt.Sprintf("%s/containers/%s@%s", s.zfs.zfsPool, fields[0], zfsName)}
@@ -1285,6 +1474,10 @@ func (s *zfsMigrationSourceDriver) SendWhileRunning(conn *websocket.Conn) error
 
 		lastSnap = snap
 
+		if !migrationSendSnapshot(s.snapshots[i], s.refresh) {
+			continue
+		}
+
 		if err := s.send(conn, snap, prev); err != nil {
 			return err
 		}
@@ -1333,7 +1526,7 @@ func (s *storageZfs) PreservesInodes() bool {
 	return true
 }
 
-func (s *storageZfs) MigrationSource(ct container) (MigrationStorageSourceDriver, error) {
+func (s *storageZfs) MigrationSource(ct container, containerOnly bool) (MigrationStorageSourceDriver, error) {
 	/* If the container is a snapshot, let's just send that; we don't need
 	 * to send anything else, because that's all the user asked for.
 	 */
@@ -1348,6 +1541,10 @@ func (s *storageZfs) MigrationSource(ct container) (MigrationStorageSourceDriver
 		zfs:              s,
 	}
 
+	if containerOnly {
+		return &driver, nil
+	}
+
 	/* List all the snapshots in order of reverse creation. The idea here
 	 * is that we send the oldest to newest snapshot, hopefully saving on
 	 * xfer costs. Then, after all that, we send the container itself.
@@ -1450,16 +1647,27 @@ func (s *storageZfs) MigrationSink(live bool, container container, snapshots []*
 	}
 
 	defer func() {
-		/* clean up our migration-send snapshots that we got from recv. */
+		/* clean up the snapshots we got from recv which aren't backing
+		 * a snapshot of the container (e.g. the migration-send ones). */
 		zfsSnapshots, err := s.zfsListSnapshots(fmt.Sprintf("containers/%s", container.Name()))
 		if err != nil {
 			logger.Error("failed listing snapshots post migration", log.Ctx{"err": err})
 			return
 		}
 
+		ctSnapshots, err := container.Snapshots()
+		if err != nil {
+			logger.Error("failed listing snapshots post migration", log.Ctx{"err": err})
+			return
+		}
+
+		keep := []string{}
+		for _, snap := range ctSnapshots {
+			keep = append(keep, fmt.Sprintf("snapshot-%s", shared.ExtractSnapshotName(snap.Name())))
+		}
+
 		for _, snap := range zfsSnapshots {
-			// If we received a bunch of snapshots, remove the migration-send-* ones, if not, wipe any snapshot we got
-			if snapshots != nil && len(snapshots) > 0 && !strings.HasPrefix(snap, "migration-send") {
+			if shared.StringInSlice(snap, keep) {
 				continue
 			}
 
@@ -1487,3 +1695,142 @@ func (s *storageZfs) MigrationSink(live bool, container container, snapshots []*
 	s.zfsMount(zfsName)
 	return nil
 }
+
+// zfsSendToFile writes the zfs send stream of a snapshot of the given
+// dataset, incremental from the parent snapshot if any, to the target file.
+func (s *storageZfs) zfsSendToFile(path string, name string, parent string, target string) error {
+	args := []string{"send", fmt.Sprintf("%s/%s@%s", s.zfsPool, path, name)}
+	if parent != "" {
+		args = append(args, "-i", fmt.Sprintf("%s/%s@%s", s.zfsPool, path, parent))
+	}
+
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("zfs", args...)
+	cmd.Stdout = f
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		logger.Error("problem with zfs send", log.Ctx{"output": stderr.String()})
+		return err
+	}
+
+	return nil
+}
+
+// zfsReceiveFromFile receives a zfs send stream from the source file into
+// the given dataset or snapshot.
+func (s *storageZfs) zfsReceiveFromFile(path string, source string) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("zfs", "receive", "-F", "-u", fmt.Sprintf("%s/%s", s.zfsPool, path))
+	cmd.Stdin = f
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		logger.Error("problem with zfs recv", log.Ctx{"output": stderr.String()})
+		return err
+	}
+
+	return nil
+}
+
+func (s *storageZfs) ContainerBackupDump(container container, snapshots []container, target string) error {
+	zfsName := fmt.Sprintf("containers/%s", container.Name())
+
+	if len(snapshots) > 0 {
+		err := os.MkdirAll(filepath.Join(target, "snapshots"), 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	prev := ""
+	for _, snap := range snapshots {
+		_, snapName, _ := containerGetParentAndSnapshotName(snap.Name())
+		zfsSnapName := fmt.Sprintf("snapshot-%s", snapName)
+
+		err := s.zfsSendToFile(zfsName, zfsSnapName, prev, filepath.Join(target, "snapshots", fmt.Sprintf("%s.bin", snapName)))
+		if err != nil {
+			return err
+		}
+
+		prev = zfsSnapName
+	}
+
+	// Send the current state of the container through a temporary snapshot
+	tmpSnapName := fmt.Sprintf("backup-%s", uuid.NewRandom().String())
+	err := s.zfsSnapshotCreate(zfsName, tmpSnapName)
+	if err != nil {
+		return err
+	}
+	defer s.zfsSnapshotDestroy(zfsName, tmpSnapName)
+
+	return s.zfsSendToFile(zfsName, tmpSnapName, prev, filepath.Join(target, "container.bin"))
+}
+
+func (s *storageZfs) ContainerBackupLoad(container container, snapshots []db.ContainerArgs, source string) error {
+	/* As with migration, always unmount the (empty) fs before receiving
+	 * anything into it.
+	 */
+	zfsName := fmt.Sprintf("containers/%s", container.Name())
+	err := s.zfsUnmount(zfsName)
+	if err != nil {
+		return err
+	}
+
+	for _, args := range snapshots {
+		_, err := containerCreateEmptySnapshot(container.StateObject(), container.Storage(), args)
+		if err != nil {
+			return err
+		}
+
+		_, snapName, _ := containerGetParentAndSnapshotName(args.Name)
+		err = s.zfsReceiveFromFile(fmt.Sprintf("%s@snapshot-%s", zfsName, snapName), filepath.Join(source, "snapshots", fmt.Sprintf("%s.bin", snapName)))
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(shared.VarPath(fmt.Sprintf("snapshots/%s", container.Name())), 0700)
+		if err != nil {
+			return err
+		}
+
+		err = os.Symlink("on-zfs", shared.VarPath(fmt.Sprintf("snapshots/%s/%s.zfs", container.Name(), snapName)))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.zfsReceiveFromFile(zfsName, filepath.Join(source, "container.bin"))
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary snapshot the container was sent through
+	zfsSnapshots, err := s.zfsListSnapshots(zfsName)
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range zfsSnapshots {
+		if strings.HasPrefix(snap, "backup-") {
+			s.zfsSnapshotDestroy(zfsName, snap)
+		}
+	}
+
+	s.zfsMount(zfsName)
+	return nil
+}
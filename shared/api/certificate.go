@@ -14,6 +14,11 @@ type CertificatesPost struct {
 type CertificatePut struct {
 	Name string `json:"name" yaml:"name"`
 	Type string `json:"type" yaml:"type"`
+
+	// API extension: certificate_roles
+	Role       string   `json:"role" yaml:"role"`
+	Containers []string `json:"containers" yaml:"containers"`
+	Profiles   []string `json:"profiles" yaml:"profiles"`
 }
 
 // Certificate represents a LXD certificate
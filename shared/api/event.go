@@ -0,0 +1,18 @@
+package api
+
+// EventLifecycle represents a lifecycle type event entry
+// API extension: event_lifecycle
+type EventLifecycle struct {
+	Action    string                   `json:"action" yaml:"action"`
+	Source    string                   `json:"source" yaml:"source"`
+	Context   map[string]interface{}   `json:"context,omitempty" yaml:"context,omitempty"`
+	Requestor *EventLifecycleRequestor `json:"requestor,omitempty" yaml:"requestor,omitempty"`
+}
+
+// EventLifecycleRequestor represents the client which caused a lifecycle event
+// API extension: event_lifecycle
+type EventLifecycleRequestor struct {
+	Username string `json:"username" yaml:"username"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+	Address  string `json:"address" yaml:"address"`
+}
@@ -233,6 +233,11 @@ func (s *SimpleStreamsManifest) ToLXD() ([]api.Image, map[string][][]string) {
 				{metaPath, metaHash, "meta", fmt.Sprintf("%d", metaSize)},
 				{rootfsPath, rootfsHash, "root", fmt.Sprintf("%d", rootfsSize)}}
 
+			// Deltas can only be applied on top of a squashfs rootfs
+			if rootSquash.FileType == "" {
+				deltas = nil
+			}
+
 			// Add the deltas
 			for _, delta := range deltas {
 				srcImage, ok := product.Versions[delta.DeltaBase]
@@ -17,6 +17,16 @@ test_filemanip() {
   err=$(my_curl -o /dev/null -w "%{http_code}" -X GET "https://${LXD_ADDR}/1.0/containers/filemanip/files?path=/tmp/foo")
   [ "${err}" -eq "404" ]
 
+  # deleting symlinks removes the link rather than its target
+  lxc exec filemanip -- mkdir /tmp/dir
+  lxc exec filemanip -- ln -s /tmp/dir /tmp/dirlink
+  lxc exec filemanip -- ln -s /tmp/missing /tmp/dangling
+  my_curl -X DELETE "https://${LXD_ADDR}/1.0/containers/filemanip/files?path=/tmp/dirlink" | grep -q '"status_code":200'
+  my_curl -X DELETE "https://${LXD_ADDR}/1.0/containers/filemanip/files?path=/tmp/dangling" | grep -q '"status_code":200'
+  lxc exec filemanip -- test ! -L /tmp/dirlink
+  lxc exec filemanip -- test ! -L /tmp/dangling
+  lxc exec filemanip -- test -d /tmp/dir
+
   lxc delete filemanip -f
 
   if [ "$(storage_backend "$LXD_DIR")" != "lvm" ]; then